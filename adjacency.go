@@ -0,0 +1,52 @@
+package birdland
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Adjacency gives the neighbours of the nodes on one side of the user-item
+// bipartite graph, for instance the users who interacted with each item. It
+// lets the adjacency lists live outside of the Bird, in an on-disk or remote
+// store. Neighbors is called concurrently by the walks and must be safe for
+// concurrent use; the returned slice must not be modified afterwards.
+type Adjacency interface {
+	Neighbors(node int) []int
+}
+
+// AdjacencyLists is the Adjacency of adjacency lists held in memory, such as
+// ItemsToUsers.
+type AdjacencyLists [][]int
+
+// Neighbors returns the list of node.
+func (a AdjacencyLists) Neighbors(node int) []int { return a[node] }
+
+// SetItemsToUsers makes the walks read the users who interacted with each
+// item from a, which must list the same users as UsersToItems. ItemsToUsers,
+// or its lazy counterpart, is then released, which saves the memory it takes
+// when the lists are stored elsewhere. A nil a builds ItemsToUsers back from
+// UsersToItems, lazily if Cfg.LazyItemsToUsers is set.
+//
+// The users of each item are read in the order given by a. A Bird whose lists
+// are stored externally cannot be updated incrementally, and referrer weights
+// cannot be set on it.
+//
+// The collections of the users stay in UsersToItems: they are read along with
+// the samplers' tables, which must be in memory anyway. OpenMapped serves both
+// from a file without loading them.
+func (b *Bird) SetItemsToUsers(a Adjacency) error {
+	if b.referrerWeights != nil {
+		return errors.New("the item-user lists cannot be replaced once referrer weights are set")
+	}
+
+	if a == nil {
+		b.externalItemsToUsers = nil
+		b.indexItemsToUsers()
+		return nil
+	}
+
+	b.ItemsToUsers = nil
+	b.lazyItemsToUsers = nil
+	b.externalItemsToUsers = a
+
+	return nil
+}
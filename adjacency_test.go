@@ -0,0 +1,70 @@
+package birdland
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// countingAdjacency records the number of calls to Neighbors.
+type countingAdjacency struct {
+	AdjacencyLists
+	calls int64
+}
+
+func (a *countingAdjacency) Neighbors(node int) []int {
+	atomic.AddInt64(&a.calls, 1)
+	return a.AdjacencyLists.Neighbors(node)
+}
+
+func TestBirdSetItemsToUsers(t *testing.T) {
+	itemWeights := []float64{1, 2, 3, 4}
+	usersToItems := [][]int{[]int{0, 1, 2}, []int{1, 3}, []int{0, 2, 3}}
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 3, Weight: 2}}
+
+	bird, err := NewBird(NewBirdCfg(), itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("SetItemsToUsers: Bird initialization should not have raised an error but did: %v", err)
+	}
+	bird.ReSeed(42)
+	expected, _, err := bird.Process(query)
+	if err != nil {
+		t.Fatalf("SetItemsToUsers: Process should not have raised an error but did: %v", err)
+	}
+
+	store := &countingAdjacency{AdjacencyLists: AdjacencyLists(bird.ItemsToUsers)}
+	if err := bird.SetItemsToUsers(store); err != nil {
+		t.Fatalf("SetItemsToUsers: should not have raised an error but did: %v", err)
+	}
+	if bird.ItemsToUsers != nil {
+		t.Errorf("SetItemsToUsers: ItemsToUsers should have been released")
+	}
+	bird.ReSeed(42)
+	items, _, err := bird.Process(query)
+	if err != nil {
+		t.Fatalf("SetItemsToUsers: Process should not have raised an error but did: %v", err)
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("SetItemsToUsers: the walks should not depend on where the lists are stored, expected %v got %v", expected, items)
+	}
+	if atomic.LoadInt64(&store.calls) == 0 {
+		t.Errorf("SetItemsToUsers: the walks did not read the external store")
+	}
+	if degrees := bird.ItemDegrees(); !reflect.DeepEqual(degrees, []int{2, 2, 2, 2}) {
+		t.Errorf("SetItemsToUsers: expected the degrees to be read from the store, got %v", degrees)
+	}
+
+	if err := bird.AddInteraction(0, 3); err == nil {
+		t.Errorf("SetItemsToUsers: updating a Bird whose lists are stored externally should have raised an error")
+	}
+	if err := bird.SetReferrerWeights([][]float64{{1, 1, 1}, {1, 1}, {1, 1, 1}}); err == nil {
+		t.Errorf("SetItemsToUsers: referrer weights should have been rejected")
+	}
+
+	if err := bird.SetItemsToUsers(nil); err != nil {
+		t.Fatalf("SetItemsToUsers: restoring the in-memory lists should not have raised an error but did: %v", err)
+	}
+	if !reflect.DeepEqual(bird.ItemsToUsers, [][]int(store.AdjacencyLists)) {
+		t.Errorf("SetItemsToUsers: expected ItemsToUsers to be rebuilt as %v, got %v", store.AdjacencyLists, bird.ItemsToUsers)
+	}
+}
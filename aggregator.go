@@ -0,0 +1,418 @@
+package birdland
+
+import (
+	"fmt"
+	"math"
+)
+
+// Visit is a single step of a random walk: the walk reached Item through the
+// collection of Referrer. Depth is the number of steps taken since the query,
+// starting at 1, and Walk the index of the walk among the Cfg.Draws walks.
+type Visit struct {
+	Item     int
+	Referrer int
+	Depth    int
+	Walk     int
+}
+
+// ScoreAggregator turns the visits of the random walks into the scores of
+// the visited items. The visits are ordered as in Process, by depth first.
+// An aggregator may be shared by several Birds and called concurrently, so
+// it should not keep state between calls.
+type ScoreAggregator interface {
+	Aggregate(visits []Visit) map[int]float64
+}
+
+// ScoreAggregatorFunc lets an ordinary function be used as a ScoreAggregator.
+type ScoreAggregatorFunc func(visits []Visit) map[int]float64
+
+// Aggregate calls f(visits).
+func (f ScoreAggregatorFunc) Aggregate(visits []Visit) map[int]float64 { return f(visits) }
+
+// CountAggregator scores each item by its number of visits. It is the
+// aggregator used when Cfg.Aggregator is nil.
+type CountAggregator struct{}
+
+// Aggregate counts the visits of each item.
+func (CountAggregator) Aggregate(visits []Visit) map[int]float64 {
+	scores := make(map[int]float64)
+	for _, v := range visits {
+		scores[v.Item]++
+	}
+
+	return scores
+}
+
+// DepthDecayAggregator scores each visit Decay^(Depth-1), so that with a Decay
+// below 1 the items reached close to the query count more than those reached
+// at the end of the walks. A Decay of 1 is equivalent to CountAggregator.
+type DepthDecayAggregator struct {
+	Decay float64
+}
+
+// Aggregate sums the decayed visits of each item.
+func (a DepthDecayAggregator) Aggregate(visits []Visit) map[int]float64 {
+	scores := make(map[int]float64)
+	for _, v := range visits {
+		scores[v.Item] += math.Pow(a.Decay, float64(v.Depth-1))
+	}
+
+	return scores
+}
+
+// ReferrerQualityAggregator scores each visit by the quality of its referrer,
+// so that the visits referred by users who interacted with a large part of
+// the catalog, such as bots, count less than those referred by users with
+// focused tastes. Quality returns the weight of the visits referred by a
+// user, for instance a trust score; Bird.DegreeReferrerQuality gives the
+// inverse of the logarithm of the size of their collection.
+type ReferrerQualityAggregator struct {
+	Quality func(user int) float64
+}
+
+// Aggregate sums the quality of the referrers of the visits of each item.
+func (a ReferrerQualityAggregator) Aggregate(visits []Visit) map[int]float64 {
+	scores := make(map[int]float64)
+	for _, v := range visits {
+		scores[v.Item] += a.Quality(v.Referrer)
+	}
+
+	return scores
+}
+
+// DegreeReferrerQuality returns a referrer quality for
+// ReferrerQualityAggregator of 1/log(1+d), d being the number of items in the
+// user's collection.
+func (b *Bird) DegreeReferrerQuality() func(user int) float64 {
+	return func(user int) float64 {
+		return 1 / math.Log1p(float64(len(b.UsersToItems[user])))
+	}
+}
+
+// DepthCounts holds the number of visits of each item at each depth of the
+// walks: counts[item][d] is the number of visits of item at depth d+1. All
+// the slices have the same length, the largest depth of the visits.
+type DepthCounts map[int][]int
+
+// CountByDepth counts the visits of each item at each depth.
+func CountByDepth(visits []Visit) DepthCounts {
+	depth := 0
+	for _, v := range visits {
+		if v.Depth > depth {
+			depth = v.Depth
+		}
+	}
+
+	counts := make(DepthCounts)
+	for _, v := range visits {
+		c, ok := counts[v.Item]
+		if !ok {
+			c = make([]int, depth)
+			counts[v.Item] = c
+		}
+		c[v.Depth-1]++
+	}
+
+	return counts
+}
+
+// Combine scores each item by the sum of its visits at each depth d+1 times
+// weights[d], which lets the weight of each depth be tuned without performing
+// the walks again. It returns the items in descending order of score, ties
+// being resolved in favor of the smallest item. There must be a weight for
+// every depth.
+func (c DepthCounts) Combine(weights []float64) ([]ScoredItem, error) {
+	scores := make(map[int]float64, len(c))
+	for item, counts := range c {
+		if len(counts) > len(weights) {
+			return nil, fmt.Errorf("expected a weight for each of the %d depths, got %d", len(counts), len(weights))
+		}
+		var score float64
+		for d, n := range counts {
+			score += weights[d] * float64(n)
+		}
+		scores[item] = score
+	}
+
+	return rankItems(scores, len(scores)), nil
+}
+
+// DepthWeightsAggregator scores each visit at depth d+1 Weights[d], and the
+// visits deeper than len(Weights) 0. It gives the scores of
+// DepthCounts.Combine as a ScoreAggregator, once suitable weights are found.
+type DepthWeightsAggregator struct {
+	Weights []float64
+}
+
+// Aggregate sums the weighted visits of each item.
+func (a DepthWeightsAggregator) Aggregate(visits []Visit) map[int]float64 {
+	scores := make(map[int]float64)
+	for _, v := range visits {
+		var w float64
+		if v.Depth <= len(a.Weights) {
+			w = a.Weights[v.Depth-1]
+		}
+		scores[v.Item] += w
+	}
+
+	return scores
+}
+
+// ProcessDepthCounts performs the same random walks as ProcessScores and
+// returns the number of visits of each item at each depth.
+func (b *Bird) ProcessDepthCounts(query []QueryItem) (DepthCounts, error) {
+	visits, _, err := b.walkVisits(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return CountByDepth(visits), nil
+}
+
+// PopularityAggregator divides the score given by Aggregator, or the number of
+// visits if it is nil, by the popularity of the item raised to the power
+// Beta, in [0, 1], so that the items everyone interacted with stop crowding
+// out the others, as the inverse document frequency does in TF-IDF. Degree
+// returns the popularity of an item, usually its number of users; items with
+// a degree below 1 are not penalized. A Beta of 0 leaves the scores
+// unchanged.
+type PopularityAggregator struct {
+	Aggregator ScoreAggregator
+	Degree     func(item int) int
+	Beta       float64
+}
+
+// Aggregate penalizes the scores of the underlying aggregator.
+func (a PopularityAggregator) Aggregate(visits []Visit) map[int]float64 {
+	aggregator := a.Aggregator
+	if aggregator == nil {
+		aggregator = CountAggregator{}
+	}
+
+	scores := aggregator.Aggregate(visits)
+	for item, score := range scores {
+		if degree := a.Degree(item); degree > 1 {
+			scores[item] = score / math.Pow(float64(degree), a.Beta)
+		}
+	}
+
+	return scores
+}
+
+// ScoreOptions changes how RecommendItemsScored scores the visited items.
+type ScoreOptions struct {
+	// PopularityPenalty is the Beta of a PopularityAggregator applied on top
+	// of Cfg.Aggregator, with the number of users of each item as its
+	// popularity. It must be in [0, 1]; 0 means no penalty.
+	PopularityPenalty float64
+
+	// Normalization makes the scores comparable across configurations;
+	// the default, ScoresRaw, leaves them unchanged.
+	Normalization ScoreNormalization
+	// Temperature is the temperature of ScoresSoftmax, which must be
+	// positive. It is ignored by the other normalizations.
+	Temperature float64
+
+	// Seen excludes the items a user has already seen from the
+	// recommendations, nil excludes none. The items are excluded before
+	// the n best ones are kept, so that n items are still returned when
+	// the walks visited enough unseen ones.
+	Seen SeenSet
+
+	// Fallback completes the recommendations with popular items when the
+	// walks visit fewer than n items, or when no item of the query has
+	// been interacted with, in which case the walks are not performed.
+	// These items are flagged as Fallback, have a zero score and come
+	// last. The default, FallbackNone, leaves the recommendations as they
+	// are.
+	Fallback FallbackPolicy
+}
+
+// RecommendItemsScored returns the n items with the highest scores for query,
+// as ranked by ProcessScores and changed by opts. When opts.Seen leaves fewer
+// than n items, those left are returned along with ErrNotEnoughItems, unless
+// opts.Fallback makes up the missing items.
+func (b *Bird) RecommendItemsScored(query []QueryItem, n int, opts ScoreOptions) ([]ScoredItem, error) {
+	if n < 0 {
+		return nil, &InvalidInputError{Err: fmt.Errorf("the number of items must not be negative, got %d", n)}
+	}
+	aggregator, err := b.scoreAggregator(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Fallback != FallbackNone && b.coldQuery(query) {
+		return b.recommendFallback(nil, nil, n, opts)
+	}
+	ranked, err := b.processScores(query, aggregator)
+	if err != nil {
+		return nil, err
+	}
+
+	scored, err := opts.normalize(ranked, n)
+	return b.recommendFallback(scored, err, n, opts)
+}
+
+// scoreAggregator checks opts and returns the aggregator that scores the
+// items as opts requires.
+func (b *Bird) scoreAggregator(opts ScoreOptions) (ScoreAggregator, error) {
+	if !(opts.PopularityPenalty >= 0 && opts.PopularityPenalty <= 1) {
+		return nil, fmt.Errorf("the popularity penalty must be in [0, 1], got %v", opts.PopularityPenalty)
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	aggregator := b.Cfg.Aggregator
+	if opts.PopularityPenalty > 0 {
+		aggregator = PopularityAggregator{
+			Aggregator: aggregator,
+			Degree:     func(item int) int { return len(b.itemUsers(item)) },
+			Beta:       opts.PopularityPenalty,
+		}
+	}
+
+	return aggregator, nil
+}
+
+// ProcessScores performs the same random walks as ProcessCounts and returns
+// the visited items in descending order of the score given by
+// Cfg.Aggregator, or of their number of visits if it is nil.
+//
+// Each item also gets the standard error of its score, which tells whether
+// the difference between two scores is meaningful or due to too few draws.
+// The walks are treated as independent samples: if an item is visited x_w
+// times by walk w, its number of visits n = Σ x_w has a variance estimated by
+// D/(D-1) (Σ x_w² - n²/D), D being the number of walks, which is less than
+// Cfg.Draws when some would start from items no one has interacted with. The
+// standard error of the score is that of n, scaled by the ratio of the score
+// to n. It is exact for the number of visits and for scores proportional to
+// it, and an approximation for other aggregators such as
+// DepthDecayAggregator.
+func (b *Bird) ProcessScores(query []QueryItem) ([]ScoredItem, error) {
+	return b.processScores(query, b.Cfg.Aggregator)
+}
+
+// processScores is ProcessScores with the scores given by aggregator, or the
+// number of visits if it is nil.
+func (b *Bird) processScores(query []QueryItem, aggregator ScoreAggregator) ([]ScoredItem, error) {
+	// The query is checked before the avoided items are split off, so that
+	// the error reports the index of the item in the whole query.
+	if err := b.checkQueryRange(query); err != nil {
+		return nil, wrap(err, "cannot sample items")
+	}
+	query, avoided := splitAvoided(query)
+	visits, walks, err := b.walkVisits(query)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked, err := b.scoreVisits(visits, walks, aggregator)
+	if err != nil {
+		return nil, err
+	}
+
+	return ranked, b.avoid(ranked, avoided)
+}
+
+// scoreVisits returns the visited items in descending order of the score
+// given by aggregator, or of their number of visits if it is nil, along with
+// their standard errors, as described by ProcessScores. walks is the number
+// of walks the visits come from.
+func (b *Bird) scoreVisits(visits []Visit, walks int, aggregator ScoreAggregator) ([]ScoredItem, error) {
+	if aggregator == nil {
+		aggregator = CountAggregator{}
+	}
+	scores := aggregator.Aggregate(visits)
+	for item, score := range scores {
+		if math.IsNaN(score) {
+			return nil, fmt.Errorf("the aggregator gave item %d a NaN score", item)
+		}
+	}
+
+	ranked := rankItems(scores, len(scores))
+	stdErrs := visitStdErrs(visits, walks)
+	for i, s := range ranked {
+		if e, ok := stdErrs[s.Item]; ok {
+			ranked[i].StdErr = e.stdErr * math.Abs(s.Score) / e.visits
+		}
+	}
+
+	return ranked, nil
+}
+
+// walkVisits performs the random walks of ProcessCounts and returns their
+// visits along with the number of walks, which is less than Cfg.Draws when
+// some walks would start from items no one has interacted with.
+func (b *Bird) walkVisits(query []QueryItem) ([]Visit, int, error) {
+	visits, starts, err := b.walkVisitsFrom(query)
+	return visits, len(starts), err
+}
+
+// walkVisitsFrom is walkVisits that returns the item each walk started from
+// instead of the number of walks.
+func (b *Bird) walkVisitsFrom(query []QueryItem) ([]Visit, []int, error) {
+	if len(query) == 0 {
+		return nil, nil, &EmptyQueryError{}
+	}
+
+	starts, s, err := b.startWalks(query)
+	if err != nil {
+		return nil, nil, wrap(err, "cannot sample items")
+	}
+
+	depth := b.walkDepth(len(starts))
+	visits := make([]Visit, 0, len(starts)*depth)
+	err = b.newWalker(b.RandSource, s).run(starts, depth, func(d int, items, referrers []int) bool {
+		for i, item := range items {
+			if b.Cfg.MaxVisits > 0 && len(visits) == b.Cfg.MaxVisits {
+				return false
+			}
+			if item == deadEnd {
+				continue
+			}
+			visits = append(visits, Visit{Item: item, Referrer: referrers[i], Depth: d + 1, Walk: i})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, nil, wrap(err, "cannot step through items")
+	}
+
+	return visits, starts, nil
+}
+
+// visitStdErr is the number of visits of an item and its standard error.
+type visitStdErr struct {
+	visits, stdErr float64
+}
+
+// visitStdErrs estimates the standard error of the number of visits of each
+// item, the walks being independent samples.
+func visitStdErrs(visits []Visit, walks int) map[int]visitStdErr {
+	type walkItem struct {
+		walk, item int
+	}
+	perWalk := make(map[walkItem]int) // visits of each item by each walk
+	for _, v := range visits {
+		perWalk[walkItem{v.Walk, v.Item}]++
+	}
+
+	sums := make(map[int]float64)
+	sumSquares := make(map[int]float64)
+	for k, x := range perWalk {
+		sums[k.item] += float64(x)
+		sumSquares[k.item] += float64(x) * float64(x)
+	}
+
+	d := float64(walks)
+	stdErrs := make(map[int]visitStdErr, len(sums))
+	for item, n := range sums {
+		var variance float64
+		if walks > 1 {
+			variance = d / (d - 1) * (sumSquares[item] - n*n/d)
+		}
+		stdErrs[item] = visitStdErr{visits: n, stdErr: math.Sqrt(math.Max(variance, 0))}
+	}
+
+	return stdErrs
+}
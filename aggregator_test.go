@@ -0,0 +1,262 @@
+package birdland
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestDepthDecayAggregator(t *testing.T) {
+	visits := []Visit{
+		{Item: 0, Depth: 1}, {Item: 1, Depth: 1},
+		{Item: 0, Depth: 2}, {Item: 1, Depth: 2},
+		{Item: 0, Depth: 3},
+	}
+
+	scores := DepthDecayAggregator{Decay: 0.5}.Aggregate(visits)
+	if math.Abs(scores[0]-1.75) > 1e-12 || math.Abs(scores[1]-1.5) > 1e-12 {
+		t.Errorf("DepthDecayAggregator: expected scores 1.75 and 1.5, got %v", scores)
+	}
+
+	counts := CountAggregator{}.Aggregate(visits)
+	if counts[0] != 3 || counts[1] != 2 {
+		t.Errorf("CountAggregator: expected counts 3 and 2, got %v", counts)
+	}
+}
+
+func TestReferrerQualityAggregator(t *testing.T) {
+	// User 0 interacted with the 100 items, the 5 other users with items 0
+	// and 1 only, so that the walks from item 0 reach items 2 to 99 through
+	// user 0 alone.
+	itemWeights := make([]float64, 100)
+	usersToItems := make([][]int, 6)
+	for item := range itemWeights {
+		itemWeights[item] = 1
+		usersToItems[0] = append(usersToItems[0], item)
+	}
+	for u := 1; u < len(usersToItems); u++ {
+		usersToItems[u] = []int{0, 1}
+	}
+	cfg := NewBirdCfg()
+	cfg.Draws = 10000
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("ReferrerQualityAggregator: Bird initialization should not have raised an error but did: %v", err)
+	}
+	var visits []Visit
+	cfg.Aggregator = ScoreAggregatorFunc(func(v []Visit) map[int]float64 {
+		visits = v
+		return map[int]float64{}
+	})
+	if _, err := bird.ProcessScores([]QueryItem{{Item: 0, Weight: 1}}); err != nil {
+		t.Fatalf("ReferrerQualityAggregator: ProcessScores should not have raised an error but did: %v", err)
+	}
+
+	megaShare := func(scores map[int]float64) float64 {
+		var mega, total float64
+		for item, score := range scores {
+			if item >= 2 {
+				mega += score
+			}
+			total += score
+		}
+		return mega / total
+	}
+	counted := megaShare(CountAggregator{}.Aggregate(visits))
+	weighted := megaShare(ReferrerQualityAggregator{Quality: bird.DegreeReferrerQuality()}.Aggregate(visits))
+	if weighted > counted/2 {
+		t.Errorf("ReferrerQualityAggregator: expected the share of the items only user 0 leads to to drop from %v, got %v", counted, weighted)
+	}
+
+	trust := ReferrerQualityAggregator{Quality: func(user int) float64 { return float64(user) }}
+	scores := trust.Aggregate([]Visit{{Item: 0, Referrer: 2}, {Item: 0, Referrer: 3}, {Item: 1, Referrer: 0}})
+	if scores[0] != 5 || scores[1] != 0 {
+		t.Errorf("ReferrerQualityAggregator: expected scores of 5 and 0, got %v", scores)
+	}
+}
+
+func TestDepthCounts(t *testing.T) {
+	visits := []Visit{
+		{Item: 0, Depth: 1}, {Item: 1, Depth: 1},
+		{Item: 0, Depth: 2}, {Item: 2, Depth: 2},
+		{Item: 2, Depth: 3}, {Item: 2, Depth: 3},
+	}
+
+	counts := CountByDepth(visits)
+	expected := DepthCounts{0: {1, 1, 0}, 1: {1, 0, 0}, 2: {0, 1, 2}}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("DepthCounts: expected %v, got %v", expected, counts)
+	}
+
+	weights := []float64{1, 0.5, 0.25}
+	combined, err := counts.Combine(weights)
+	if err != nil {
+		t.Fatalf("DepthCounts: Combine should not have raised an error but did: %v", err)
+	}
+	expectedScores := []ScoredItem{{Item: 0, Score: 1.5}, {Item: 1, Score: 1}, {Item: 2, Score: 1}}
+	if !reflect.DeepEqual(combined, expectedScores) {
+		t.Errorf("DepthCounts: expected combined scores %v, got %v", expectedScores, combined)
+	}
+	scores := DepthWeightsAggregator{Weights: weights}.Aggregate(visits)
+	for _, s := range expectedScores {
+		if scores[s.Item] != s.Score {
+			t.Errorf("DepthWeightsAggregator: expected item %d to score %v, got %v", s.Item, s.Score, scores[s.Item])
+		}
+	}
+
+	if _, err := counts.Combine(weights[:2]); err == nil {
+		t.Errorf("DepthCounts: Combine should have rejected too few weights")
+	}
+}
+
+func TestBirdProcessScores(t *testing.T) {
+	itemWeights := []float64{1, 2, 3, 4}
+	usersToItems := [][]int{[]int{0, 1, 2}, []int{1, 3}, []int{0, 2, 3}}
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 3, Weight: 2}}
+	cfg := NewBirdCfg()
+	cfg.Depth = 3
+	cfg.Draws = 100
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("ProcessScores: Bird initialization should not have raised an error but did: %v", err)
+	}
+	bird.ReSeed(42)
+	counts, err := bird.ProcessCounts(query)
+	if err != nil {
+		t.Fatalf("ProcessScores: ProcessCounts should not have raised an error but did: %v", err)
+	}
+	bird.ReSeed(42)
+	scored, err := bird.ProcessScores(query)
+	if err != nil {
+		t.Fatalf("ProcessScores: should not have raised an error but did: %v", err)
+	}
+	if len(scored) != len(counts) {
+		t.Fatalf("ProcessScores: expected %d scored items, got %d", len(counts), len(scored))
+	}
+	for i, s := range scored {
+		if s.Score != float64(counts[s.Item]) {
+			t.Errorf("ProcessScores: expected item %d to be scored by its %d visits, got %v", s.Item, counts[s.Item], s.Score)
+		}
+		if i > 0 && s.Score > scored[i-1].Score {
+			t.Errorf("ProcessScores: items are not in descending order of score: %v", scored)
+		}
+	}
+
+	bird.ReSeed(42)
+	depthCounts, err := bird.ProcessDepthCounts(query)
+	if err != nil {
+		t.Fatalf("ProcessScores: ProcessDepthCounts should not have raised an error but did: %v", err)
+	}
+	for item, n := range counts {
+		if c := depthCounts[item]; len(c) != cfg.Depth || c[0]+c[1]+c[2] != n {
+			t.Errorf("ProcessScores: expected the %d visits of item %d to be split by depth, got %v", n, item, c)
+		}
+	}
+
+	// With a depth of 1, each walk visits an item at most once, so the
+	// number of visits n of an item follows a binomial distribution whose
+	// estimated standard error is sqrt(D/(D-1) n (1 - n/D)).
+	cfg.Depth = 1
+	scored, err = bird.ProcessScores(query)
+	if err != nil {
+		t.Fatalf("ProcessScores: should not have raised an error but did: %v", err)
+	}
+	for _, s := range scored {
+		d := float64(cfg.Draws)
+		expected := math.Sqrt(d / (d - 1) * s.Score * (1 - s.Score/d))
+		if math.Abs(s.StdErr-expected) > 1e-9 {
+			t.Errorf("ProcessScores: expected item %d to have a standard error of %v, got %v", s.Item, expected, s.StdErr)
+		}
+	}
+	cfg.Depth = 3
+
+	// No one has interacted with item 4, so that about half of the walks
+	// are dropped, and each remaining walk visits item 0 once, through user 3.
+	// The standard error is computed over the walks that were performed, so
+	// it is 0.
+	coldBird, err := NewBird(cfg, []float64{1, 1, 1, 1, 1}, [][]int{[]int{1}, []int{2}, []int{3}, []int{0}})
+	if err != nil {
+		t.Fatalf("ProcessScores: Bird initialization should not have raised an error but did: %v", err)
+	}
+	cfg.Depth = 1
+	scored, err = coldBird.ProcessScores([]QueryItem{{Item: 0, Weight: 1}, {Item: 4, Weight: 1}})
+	if err != nil {
+		t.Fatalf("ProcessScores: should not have raised an error but did: %v", err)
+	}
+	if len(scored) != 1 || scored[0].Item != 0 || scored[0].StdErr != 0 {
+		t.Errorf("ProcessScores: expected item 0 to be visited once by each walk performed and to have no standard error, got %+v", scored)
+	}
+	cfg.Depth = 3
+
+	var visits []Visit
+	cfg.Aggregator = ScoreAggregatorFunc(func(v []Visit) map[int]float64 {
+		visits = v
+		return map[int]float64{}
+	})
+	if _, err := bird.ProcessScores(query); err != nil {
+		t.Fatalf("ProcessScores: should not have raised an error but did: %v", err)
+	}
+	if len(visits) != cfg.Draws*cfg.Depth {
+		t.Fatalf("ProcessScores: expected the aggregator to receive %d visits, got %d", cfg.Draws*cfg.Depth, len(visits))
+	}
+	for i, v := range visits {
+		if v.Depth != i/cfg.Draws+1 || v.Walk != i%cfg.Draws {
+			t.Fatalf("ProcessScores: visit %d should be at depth %d of walk %d, got %+v", i, i/cfg.Draws+1, i%cfg.Draws, v)
+		}
+	}
+}
+
+func TestBirdRecommendItemsScored(t *testing.T) {
+	// Every user has the blockbuster item 0, which the walks from item 1
+	// reach 3 times out of 5; the mid-tail item 2, with 2 users, is reached
+	// as often as item 1.
+	itemWeights := []float64{3, 1, 1}
+	usersToItems := make([][]int, 10)
+	for u := range usersToItems {
+		usersToItems[u] = []int{0}
+	}
+	usersToItems[0] = []int{0, 1, 2}
+	usersToItems[1] = []int{0, 1, 2}
+	cfg := NewBirdCfg()
+	cfg.Draws = 5000
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("RecommendItemsScored: Bird initialization should not have raised an error but did: %v", err)
+	}
+	query := []QueryItem{{Item: 1, Weight: 1}}
+
+	counted, err := bird.RecommendItemsScored(query, 3, ScoreOptions{})
+	if err != nil {
+		t.Fatalf("RecommendItemsScored: should not have raised an error but did: %v", err)
+	}
+	if counted[0].Item != 0 {
+		t.Errorf("RecommendItemsScored: without penalty the blockbuster should come first, got %v", counted)
+	}
+
+	penalized, err := bird.RecommendItemsScored(query, 2, ScoreOptions{PopularityPenalty: 1})
+	if err != nil {
+		t.Fatalf("RecommendItemsScored: should not have raised an error but did: %v", err)
+	}
+	if len(penalized) != 2 {
+		t.Fatalf("RecommendItemsScored: expected 2 items, got %v", penalized)
+	}
+	for _, s := range penalized {
+		if s.Item == 0 {
+			t.Errorf("RecommendItemsScored: with a penalty the mid-tail items should come before the blockbuster, got %v", penalized)
+		}
+	}
+
+	for _, beta := range []float64{-0.5, 1.5, math.NaN()} {
+		if _, err := bird.RecommendItemsScored(query, 3, ScoreOptions{PopularityPenalty: beta}); err == nil {
+			t.Errorf("RecommendItemsScored: a penalty of %v should have been rejected", beta)
+		}
+	}
+	if _, err := bird.RecommendItemsScored(query, -1, ScoreOptions{}); err == nil {
+		t.Errorf("RecommendItemsScored: a negative number of items should have been rejected")
+	} else if _, ok := err.(*InvalidInputError); !ok {
+		t.Errorf("RecommendItemsScored: expected an *InvalidInputError, got %T", err)
+	}
+}
@@ -0,0 +1,81 @@
+package birdland
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// splitAvoided separates the items of query with a negative weight, which
+// the recommendations avoid, from those the walks start from. query is
+// returned as it is when it has no such item.
+func splitAvoided(query []QueryItem) ([]QueryItem, []QueryItem) {
+	var starts, avoided []QueryItem
+	for i, q := range query {
+		if q.Weight >= 0 || math.IsNaN(q.Weight) {
+			if avoided != nil {
+				starts = append(starts, q)
+			}
+			continue
+		}
+		if avoided == nil {
+			starts = append(starts, query[:i]...)
+		}
+		avoided = append(avoided, q)
+	}
+	if avoided == nil {
+		return query, nil
+	}
+
+	return starts, avoided
+}
+
+// avoid divides, in place, the score and standard error of each item of
+// ranked by exp(Σ w c), for each avoided item of weight -w, c being the share
+// of the users of the avoided item who interacted with the item, and sorts
+// ranked again. It takes a pass over the collections of the users of the
+// avoided items.
+func (b *Bird) avoid(ranked []ScoredItem, avoided []QueryItem) error {
+	if len(avoided) == 0 {
+		return nil
+	}
+
+	penalties := make(map[int]float64)
+	for _, a := range avoided {
+		if err := b.checkQueryItem(QueryItem{Item: a.Item, Weight: -a.Weight}); err != nil {
+			return fmt.Errorf("the avoided item %d %v", a.Item, err)
+		}
+		b.loadItemUsers([]int{a.Item})
+		users := make(map[int]bool)
+		for _, user := range b.itemUsers(a.Item) {
+			users[user] = true
+		}
+
+		// Each user counts once for an item, however many times they
+		// interacted with it.
+		counts := make(map[int]int)
+		lastUser := make(map[int]int)
+		for user := range users {
+			for _, item := range b.UsersToItems[user] {
+				if last, ok := lastUser[item]; ok && last == user {
+					continue
+				}
+				lastUser[item] = user
+				counts[item]++
+			}
+		}
+		for item, n := range counts {
+			penalties[item] -= a.Weight * float64(n) / float64(len(users))
+		}
+	}
+
+	for i, s := range ranked {
+		if p := penalties[s.Item]; p > 0 {
+			ranked[i].Score *= math.Exp(-p)
+			ranked[i].StdErr *= math.Exp(-p)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranksBefore(ranked[i], ranked[j]) })
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package birdland
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBirdAvoidedItems(t *testing.T) {
+	// The walks from item 0 reach item 3 twice as often as item 2, but the
+	// two users of item 4 both interacted with item 3.
+	itemWeights := []float64{1, 1, 1, 1, 1}
+	usersToItems := [][]int{[]int{0, 2}, []int{0, 3}, []int{0, 3}, []int{3, 4}, []int{3, 4, 3}}
+	cfg := NewBirdCfg()
+	cfg.Draws = 3000
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("AvoidedItems: Bird initialization should not have raised an error but did: %v", err)
+	}
+	query := []QueryItem{{Item: 0, Weight: 1}}
+	bird.ReSeed(42)
+	scored, err := bird.ProcessScores(query)
+	if err != nil {
+		t.Fatalf("AvoidedItems: ProcessScores should not have raised an error but did: %v", err)
+	}
+	rank := func(scored []ScoredItem, item int) int {
+		for i, s := range scored {
+			if s.Item == item {
+				return i
+			}
+		}
+		return -1
+	}
+	if rank(scored, 3) > rank(scored, 2) {
+		t.Fatalf("AvoidedItems: expected item 3 before item 2 without avoided items, got %v", scored)
+	}
+	expected := make(map[int]float64)
+	for _, s := range scored {
+		expected[s.Item] = s.Score
+	}
+	expected[3] *= math.Exp(-2)
+
+	bird.ReSeed(42)
+	avoided, err := bird.ProcessScores(append(query, QueryItem{Item: 4, Weight: -2}))
+	if err != nil {
+		t.Fatalf("AvoidedItems: ProcessScores should not have raised an error but did: %v", err)
+	}
+	if rank(avoided, 3) < rank(avoided, 2) {
+		t.Errorf("AvoidedItems: expected item 3 to be demoted below item 2, got %v", avoided)
+	}
+	for _, s := range avoided {
+		if math.Abs(s.Score-expected[s.Item]) > 1e-9 {
+			t.Errorf("AvoidedItems: expected item %d to score %v, got %v", s.Item, expected[s.Item], s.Score)
+		}
+	}
+
+	if _, _, err := bird.Process(append(query, QueryItem{Item: 4, Weight: -2})); err == nil {
+		t.Errorf("AvoidedItems: Process should have rejected a negative weight")
+	}
+	if _, err := bird.ProcessScores(append(query, QueryItem{Item: 9, Weight: -1})); err == nil {
+		t.Errorf("AvoidedItems: an avoided item out of the graph should have been rejected")
+	}
+}
@@ -2,7 +2,10 @@ package birdland
 
 import (
 	"fmt"
-	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -10,13 +13,137 @@ import (
 )
 
 type QueryItem struct {
-	Item   int
-	Weight float64 // for instance number of past interactions with the item
+	Item int
+
+	// Weight is, for instance, the number of past interactions with the
+	// item. A negative weight -w means that the item should be avoided: no
+	// walk starts from it, and the methods that rank the visited items,
+	// such as ProcessScores and RecommendItemsScored, divide the score of
+	// each item by exp(w c), c being the share of the users of the avoided
+	// item who also interacted with the item. The other methods reject
+	// negative weights.
+	Weight float64
+
+	// Draws is the number of walks that start from the item, 0 meaning
+	// that their number is left to chance. The walks that are not assigned
+	// to an item start from items drawn in proportion to their weight among
+	// those with no Draws, or among all the query items if they all have
+	// one. The total number of walks is still Cfg.Draws.
+	Draws int
 }
 
 type BirdCfg struct {
-	Depth int `yaml:"depth"`
-	Draws int `yaml:"draws"`
+	Depth     int `yaml:"depth" json:"depth"`
+	Draws     int `yaml:"draws" json:"draws"`
+	MaxVisits int `yaml:"max_visits" json:"max_visits"` // cap on the number of visits returned by Process, 0 means no cap
+
+	// MaxUserItems caps the size of the users' collections, 0 means no cap.
+	// Larger collections only keep their MaxUserItems items with the highest
+	// sampling weight (global weight times edge weight); ties are resolved
+	// in favor of the items that come first, and the kept items stay in
+	// their original order. The cap is applied when the Bird is built.
+	MaxUserItems int `yaml:"max_user_items" json:"max_user_items"`
+
+	InitWorkers int `yaml:"init_workers" json:"init_workers"` // goroutines building the samplers, 0 means GOMAXPROCS
+	Parallelism int `yaml:"parallelism" json:"parallelism"`   // goroutines performing the walks of Process, 0 or 1 means serial
+
+	// LazyItemsToUsers leaves ItemsToUsers nil and builds the users of an
+	// item the first time a walk reaches it. This halves the memory taken by
+	// the adjacency lists when the walks only reach a small part of the
+	// catalog, at the cost of a pass over the graph the first time new items
+	// are reached: once per step for Process, but once per new item for
+	// ProcessSeeded, whose walks move on their own.
+	LazyItemsToUsers bool `yaml:"lazy_items_to_users" json:"lazy_items_to_users"`
+
+	Dangling DanglingPolicy `yaml:"dangling" json:"dangling"` // what happens to walks that reach a dead end
+
+	// QueryTopK restricts the starting points of the walks to the QueryTopK
+	// query items with the highest combined weight (query weight times
+	// global weight), ties being resolved in favor of the items that come
+	// first. 0 means every item of the query.
+	QueryTopK int `yaml:"query_top_k" json:"query_top_k"`
+
+	// QueryNormalization normalizes the weights of the query items before
+	// they are multiplied by the global weights of the items. The default,
+	// QueryWeightsRaw, uses them as they are.
+	QueryNormalization QueryNormalization `yaml:"query_normalization" json:"query_normalization"`
+
+	// StartJaccardThreshold restricts the starting points of the walks to
+	// the query items whose users have a Jaccard index with the users of all
+	// the query items above the threshold, in [0, 1). The users of an item
+	// are part of the query's, so the index is the share of the query's
+	// users who interacted with the item. It is applied before QueryTopK; 0
+	// means no filtering.
+	StartJaccardThreshold float64 `yaml:"start_jaccard_threshold" json:"start_jaccard_threshold"`
+
+	// GumbelStarts, when positive, starts the walks from GumbelStarts
+	// distinct query items drawn with the Gumbel-max trick, in proportion to
+	// the weights they are otherwise drawn with, each starting an equal
+	// share of the Draws walks; the first Draws % GumbelStarts items drawn
+	// start one more. It is ignored when a query item has Draws, and all the
+	// items are used when the query has fewer positive weights.
+	GumbelStarts int `yaml:"gumbel_starts" json:"gumbel_starts"`
+
+	// CompactSamplers stores the tables of the users' samplers with 32-bit
+	// values, which halves the memory they take, by building them with
+	// CompactAliasSamplerFactory. It has no effect on a Bird created with
+	// NewBirdWithSamplerFactory.
+	CompactSamplers bool `yaml:"compact_samplers" json:"compact_samplers"`
+
+	// LinearSamplerMaxDegree makes the users with at most that many items
+	// draw from a LinearSampler, which is faster and smaller than the other
+	// samplers for small collections, through LinearSamplerFactory; 0 means
+	// never. DefaultLinearSamplerMaxDegree is a good value. It has no effect
+	// on a Bird created with NewBirdWithSamplerFactory.
+	LinearSamplerMaxDegree int `yaml:"linear_sampler_max_degree" json:"linear_sampler_max_degree"`
+
+	// ExcludeSelfLoops makes a walk draw again from the user's collection
+	// when it draws the item it comes from, up to maxSelfLoopRetries times,
+	// since such a step adds no information. The walk stays on the item
+	// when the user has a single item, or keeps drawing it.
+	ExcludeSelfLoops bool `yaml:"exclude_self_loops" json:"exclude_self_loops"`
+
+	// Branching is the number of items ProcessBranching draws from the
+	// collection of each user a walk goes through, so that every walk splits
+	// into Branching walks at each step; 0 or 1 means no branching.
+	// BranchingDistinct makes the items drawn from a collection at a step
+	// distinct.
+	Branching         int  `yaml:"branching" json:"branching"`
+	BranchingDistinct bool `yaml:"branching_distinct" json:"branching_distinct"`
+
+	// DepthMode tells how long the walks are: Depth steps with the default,
+	// DepthFixed, or a number of steps drawn from a geometric distribution
+	// with DepthGeometric, a walk performing each step after the first with
+	// probability Continuation, in [0, 1). Depth then caps the length of the
+	// walks and should be well above the mean, 1/(1-Continuation). The walks
+	// of Weaver always have a fixed depth.
+	DepthMode    DepthMode `yaml:"depth_mode" json:"depth_mode"`
+	Continuation float64   `yaml:"continuation" json:"continuation"`
+
+	// UniformUserSampling makes the walks draw the items of a user's
+	// collection uniformly, ignoring the global and edge weights, instead
+	// of with a sampler. No sampler is built, which speeds up building the
+	// Bird when the weights are not informative; UserSampler then returns
+	// nil, and the sampler factory of NewBirdWithSamplerFactory,
+	// CompactSamplers and LinearSamplerMaxDegree are ignored. Save still
+	// writes the samplers built from the weights.
+	UniformUserSampling bool `yaml:"uniform_user_sampling" json:"uniform_user_sampling"`
+
+	// QuerySamplerCache is the number of queries ProcessReader keeps the
+	// prepared query sampler of, so that identical queries of a batch, with
+	// the same items, weights and draws in the same order, share it; 0
+	// means none. The walks are the same with or without the cache.
+	QuerySamplerCache int `yaml:"query_sampler_cache" json:"query_sampler_cache"`
+
+	// TraceWalks makes Walk keep, for each walk, the item it started from
+	// and, for each item, the positions of its visits, so that ExplainItem
+	// can explain them. It takes an extra int per walk and per visit.
+	TraceWalks bool `yaml:"trace_walks" json:"trace_walks"`
+
+	// Aggregator turns the visits of the walks into the scores returned by
+	// ProcessScores, nil means CountAggregator. It is not saved with the
+	// Bird.
+	Aggregator ScoreAggregator `yaml:"-" json:"-"`
 }
 
 func NewBirdCfg() *BirdCfg {
@@ -31,165 +158,816 @@ func NewBirdCfg() *BirdCfg {
 // Bird is a recommendation engine that performs random walks on the
 // user-item bipartite graph.
 type Bird struct {
-	Cfg               *BirdCfg
-	ItemWeights       []float64              // global weight attributed to items
-	UsersToItems      [][]int                // user-item adjacency matrix
-	ItemsToUsers      [][]int                // item-user adjacency matrix
-	UserItemsSamplers []sampler.AliasSampler // samplers to randomly draw items from a user's collection
-	RandSource        *rand.Rand
+	Cfg          *BirdCfg
+	ItemWeights  []float64    // global weight attributed to items
+	UsersToItems [][]int      // user-item adjacency matrix
+	ItemsToUsers [][]int      // item-user adjacency matrix, nil if Cfg.LazyItemsToUsers is set or the lists are stored externally
+	EdgeWeights  [][]float64  // optional weight of each user-item interaction, aligned with UsersToItems
+	RandSource   sampler.Rand // source of all the random draws of the Bird, including those from its samplers
+	Metrics      Metrics      // optional receiver of measures of the walks, nil discards them
+
+	// samplers to randomly draw items from a user's collection, see
+	// UserSampler
+	userItemsSamplers []sampler.AliasSampler
+
+	caches *graphCaches // statistics and rankings of the graph, replaced by every change
+	unmap  func() error // releases the memory mapping of a Bird opened with OpenMapped
+
+	lazyItemsToUsers     *lazyItemsToUsers // users of the items reached so far if Cfg.LazyItemsToUsers is set
+	externalItemsToUsers Adjacency         // users of the items if they are stored outside of the Bird
+
+	// samplers of the large collections modified incrementally, which
+	// replace the users' entries in userItemsSamplers
+	fenwickSamplers map[int]*sampler.FenwickSampler
+
+	// samplers of a Bird created with NewBirdWithSamplerFactory, which
+	// replace userItemsSamplers
+	samplerFactory SamplerFactory
+	customSamplers []sampler.Sampler
+
+	// weights of the users of each item, aligned with ItemsToUsers, and the
+	// samplers the walks choose the referrers with, if SetReferrerWeights
+	// was called
+	referrerWeights  [][]float64
+	referrerSamplers []sampler.AliasSampler
+
+	version     Version  // incremented by every change to the graph
+	changes     []change // log of the changes since changesFrom
+	changesFrom Version
 }
 
-// NewBird creates a new recommender from input data.
+// graphCaches holds what is derived from the graph on first use. A change
+// to the graph replaces the caches with empty ones rather than resetting
+// them, so that the readers still holding the former ones are not disturbed.
+type graphCaches struct {
+	statsOnce sync.Once
+	stats     GraphStats
+
+	// items ranked by popularity for each FallbackPolicy but FallbackNone
+	popularityOnce [numFallbacks]sync.Once
+	popularity     [numFallbacks][]int
+}
+
+// NewBird creates a new recommender from input data. It returns a nil Bird
+// along with the error when it fails; an *InvalidInputError when cfg,
+// itemWeights or usersToItems are invalid, and another error for internal
+// failures such as the failure to build a sampler. The other constructors
+// follow the same contract.
 func NewBird(cfg *BirdCfg, itemWeights []float64, usersToItems [][]int) (*Bird, error) {
-	if cfg.Depth < 1 {
-		return nil, errors.New("the depth must be greater than or equal to 1")
+	return NewBirdWithEdgeWeights(cfg, itemWeights, usersToItems, nil)
+}
+
+// NewBirdFromInteractions creates a new recommender where the global weight
+// of each item is its number of interactions, i.e. its popularity. The items
+// are numbered from 0 to the largest item in usersToItems; those no one has
+// interacted with get a zero weight, and are ignored if they appear in a
+// query.
+func NewBirdFromInteractions(cfg *BirdCfg, usersToItems [][]int) (*Bird, error) {
+	var itemWeights []float64
+	for u, userItems := range usersToItems {
+		for _, item := range userItems {
+			if item < 0 {
+				return nil, &InvalidInputError{Err: fmt.Errorf("user %d refers to a negative item", u)}
+			}
+			for len(itemWeights) <= item {
+				itemWeights = append(itemWeights, 0)
+			}
+			itemWeights[item]++
+		}
 	}
 
-	if cfg.Draws < 1 {
-		return nil, errors.New("the number of draws must be greater than or equal to 1")
+	return NewBird(cfg, itemWeights, usersToItems)
+}
+
+// NewBirdWithEdgeWeights creates a new recommender where each user-item
+// interaction has its own weight, for instance a rating or a number of plays.
+// edgeWeights is aligned with usersToItems, and items are drawn from a user's
+// collection with a probability proportional to the product of the item's
+// global weight and the interaction's weight. A nil edgeWeights is equivalent
+// to NewBird.
+func NewBirdWithEdgeWeights(cfg *BirdCfg, itemWeights []float64, usersToItems [][]int,
+	edgeWeights [][]float64) (*Bird, error) {
+
+	return newBird(cfg, itemWeights, usersToItems, edgeWeights, nil)
+}
+
+// newBird creates a new recommender whose samplers are built with factory,
+// or are AliasSamplers when factory is nil and neither Cfg.CompactSamplers nor
+// Cfg.LinearSamplerMaxDegree is set.
+func newBird(cfg *BirdCfg, itemWeights []float64, usersToItems [][]int,
+	edgeWeights [][]float64, factory SamplerFactory) (*Bird, error) {
+
+	if err := validateBirdCfg(cfg); err != nil {
+		return nil, &InvalidInputError{Err: err}
 	}
 
-	randSource := rand.New(rand.NewSource(time.Now().UnixNano()))
+	randSource := newRandSource()
 
 	err := validateBirdInputs(itemWeights, usersToItems)
 	if err != nil {
-		return &Bird{}, errors.Wrap(err, "invalid input")
+		return nil, &InvalidInputError{Err: err}
 	}
 
-	userItemsSampler, err := initUserItemsSamplers(randSource, itemWeights, usersToItems)
+	err = validateEdgeWeights(usersToItems, edgeWeights)
 	if err != nil {
-		return &Bird{}, errors.Wrap(err, "cannot initialize samplers")
+		return nil, &InvalidInputError{Err: errors.Wrap(err, "invalid edge weights")}
 	}
 
-	// we sacrifice memory for speed by storing the two complementary adjacency lists.
-	itemsToUsers := permuteAdjacencyList(len(itemWeights), usersToItems)
+	if cfg.MaxUserItems > 0 {
+		usersToItems, edgeWeights = capUserItems(cfg.MaxUserItems, itemWeights, usersToItems, edgeWeights)
+	}
+
+	if cfg.UniformUserSampling {
+		factory = nil
+	}
+	if factory == nil {
+		factory = cfgSamplerFactory(cfg)
+	}
+
+	var userItemsSampler []sampler.AliasSampler
+	var customSamplers []sampler.Sampler
+	switch {
+	case cfg.UniformUserSampling:
+		userItemsSampler = make([]sampler.AliasSampler, len(usersToItems))
+	case factory == nil:
+		userItemsSampler, err = initUserItemsSamplers(randSource, itemWeights, usersToItems, edgeWeights, cfg.InitWorkers)
+	default:
+		userItemsSampler = make([]sampler.AliasSampler, len(usersToItems))
+		customSamplers, err = initCustomSamplers(factory, randSource, itemWeights, usersToItems, edgeWeights, cfg.InitWorkers)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot initialize samplers")
+	}
 
 	b := Bird{
 		Cfg:               cfg,
 		RandSource:        randSource,
 		ItemWeights:       itemWeights,
 		UsersToItems:      usersToItems,
-		ItemsToUsers:      itemsToUsers,
-		UserItemsSamplers: userItemsSampler,
+		EdgeWeights:       edgeWeights,
+		userItemsSamplers: userItemsSampler,
+		caches:            &graphCaches{},
+		samplerFactory:    factory,
+		customSamplers:    customSamplers,
 	}
+	b.indexItemsToUsers()
 
 	return &b, nil
 }
 
+// cfgSamplerFactory returns the factory that builds the samplers required by
+// cfg.CompactSamplers and cfg.LinearSamplerMaxDegree, or nil if they are not
+// set or cfg.UniformUserSampling is.
+func cfgSamplerFactory(cfg *BirdCfg) SamplerFactory {
+	switch {
+	case cfg.UniformUserSampling:
+		return nil
+	case cfg.LinearSamplerMaxDegree > 0:
+		larger := AliasSamplerFactory
+		if cfg.CompactSamplers {
+			larger = CompactAliasSamplerFactory
+		}
+		return LinearSamplerFactory(cfg.LinearSamplerMaxDegree, larger)
+	case cfg.CompactSamplers:
+		return CompactAliasSamplerFactory
+	}
+
+	return nil
+}
+
+// validateBirdCfg returns an error if a parameter of cfg is out of range.
+func validateBirdCfg(cfg *BirdCfg) error {
+	if cfg.Depth < 1 {
+		return errors.New("the depth must be greater than or equal to 1")
+	}
+
+	if cfg.Draws < 1 {
+		return errors.New("the number of draws must be greater than or equal to 1")
+	}
+
+	if cfg.MaxVisits < 0 {
+		return errors.New("the maximum number of visits must be positive")
+	}
+
+	if cfg.MaxUserItems < 0 {
+		return errors.New("the maximum number of items per user must be positive")
+	}
+
+	if cfg.InitWorkers < 0 {
+		return errors.New("the number of initialization workers must be positive")
+	}
+
+	if cfg.Parallelism < 0 {
+		return errors.New("the parallelism must be positive")
+	}
+
+	if cfg.QueryTopK < 0 {
+		return errors.New("the number of top query items must be positive")
+	}
+
+	if cfg.LinearSamplerMaxDegree < 0 {
+		return errors.New("the largest collection drawn from linearly must be positive")
+	}
+
+	if cfg.GumbelStarts < 0 {
+		return errors.New("the number of Gumbel starting items must be positive")
+	}
+
+	if cfg.QuerySamplerCache < 0 {
+		return errors.New("the size of the query sampler cache must be positive")
+	}
+
+	if cfg.Branching < 0 {
+		return errors.New("the branching factor must be positive")
+	}
+
+	if !(cfg.StartJaccardThreshold >= 0 && cfg.StartJaccardThreshold < 1) {
+		return fmt.Errorf("the Jaccard threshold of the starting items must be in [0, 1), got %v", cfg.StartJaccardThreshold)
+	}
+
+	if cfg.QueryNormalization < QueryWeightsRaw || cfg.QueryNormalization > QueryWeightsSoftmax {
+		return fmt.Errorf("unknown query normalization %d", cfg.QueryNormalization)
+	}
+
+	if cfg.Dangling < DanglingFail || cfg.Dangling > DanglingRestart {
+		return fmt.Errorf("unknown dangling policy %d", cfg.Dangling)
+	}
+
+	if cfg.DepthMode < DepthFixed || cfg.DepthMode > DepthGeometric {
+		return fmt.Errorf("unknown depth mode %d", cfg.DepthMode)
+	}
+
+	if cfg.DepthMode == DepthGeometric && !(cfg.Continuation >= 0 && cfg.Continuation < 1) {
+		return fmt.Errorf("the continuation probability must be in [0, 1), got %v", cfg.Continuation)
+	}
+
+	return nil
+}
+
 // Process randomly samples items from the query and performs random walks
 // starting from them. Returns a list of items and a list of
-// users who referred this item in the walk.
+// users who referred this item in the walk; both lists are aligned, the item
+// at index i having been drawn from the collection of the user at index i
+// (see ProcessPairs). If Cfg.MaxVisits is set, the walk
+// stops as soon as that many items have been visited and what was collected
+// so far is returned. Walks that reach a dead end are handled according to
+// Cfg.Dangling; the visits of dropped walks are left out.
+//
+// When Cfg.Parallelism is greater than 1 the walks are spread over that many
+// goroutines with ProcessSeeded, seeded from RandSource. The result is then
+// the same for every Parallelism greater than 1 given the state of
+// RandSource, but differs from the serial one.
 func (b *Bird) Process(query []QueryItem) ([]int, []int, error) {
 	if len(query) == 0 {
-		return nil, nil, errors.New("empty query")
+		return nil, nil, &EmptyQueryError{}
 	}
 
-	stepItems, err := b.sampleItemsFromQuery(query)
+	if b.Cfg.Parallelism > 1 {
+		return b.ProcessSeeded(query, b.drawSeed(), b.Cfg.Parallelism)
+	}
+
+	start := time.Now()
+	starts, s, err := b.startWalks(query)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "cannot sample items")
+		return nil, nil, wrap(err, "cannot sample items")
 	}
 
-	var items []int
-	var referrers []int
-	for d := 0; d < b.Cfg.Depth; d++ {
-		stepItems, stepReferrers, err := b.step(stepItems)
-		if err != nil {
-			return nil, nil, errors.Wrap(err, "cannot step through items")
-		}
+	draws, depth := len(starts), b.walkDepth(len(starts))
+	items := make([]int, 0, draws*depth)
+	referrers := make([]int, 0, draws*depth)
+	err = b.newWalker(b.RandSource, s).run(starts, depth, func(d int, stepItems, stepReferrers []int) bool {
 		items = append(items, stepItems...)
 		referrers = append(referrers, stepReferrers...)
+		return true
+	})
+	if err != nil {
+		return nil, nil, wrap(err, "cannot step through items")
+	}
+	if b.Cfg.Dangling != DanglingFail || b.Cfg.DepthMode != DepthFixed {
+		dropDeadEnds(&items, &referrers)
 	}
+	capVisits(b.Cfg.MaxVisits, &items, &referrers)
+	b.observeProcess(start, items)
 
 	return items, referrers, nil
 }
 
+// ItemReferrer is an item visited by a random walk along with the user whose
+// collection it was drawn from.
+type ItemReferrer struct {
+	Item     int
+	Referrer int
+}
+
+// ProcessPairs performs the same random walks as Process but returns each
+// visited item paired with its referrer, in the same order.
+func (b *Bird) ProcessPairs(query []QueryItem) ([]ItemReferrer, error) {
+	items, referrers, err := b.Process(query)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]ItemReferrer, len(items))
+	for i, item := range items {
+		pairs[i] = ItemReferrer{Item: item, Referrer: referrers[i]}
+	}
+
+	return pairs, nil
+}
+
+// walkDepth returns the number of steps needed for walks random walks to
+// collect the visits returned by Process, which is less than Cfg.Depth when
+// Cfg.MaxVisits is reached earlier. walks is the number of walks actually
+// started, which is less than Cfg.Draws when some would start from items no
+// one has interacted with.
+func (b *Bird) walkDepth(walks int) int {
+	depth := b.Cfg.Depth
+	if b.Cfg.MaxVisits > 0 && walks > 0 && (b.Cfg.MaxVisits+walks-1)/walks < depth {
+		depth = (b.Cfg.MaxVisits + walks - 1) / walks
+	}
+
+	return depth
+}
+
+// capVisits truncates the visited items and referrers to at most maxVisits
+// elements. It returns true when the cap has been reached, in which case the
+// walk should stop. A maxVisits of 0 means there is no cap.
+func capVisits(maxVisits int, items, referrers *[]int) bool {
+	if maxVisits == 0 || len(*items) < maxVisits {
+		return false
+	}
+	*items = (*items)[:maxVisits]
+	*referrers = (*referrers)[:maxVisits]
+
+	return true
+}
+
+// ErrNoItemsSampled is returned when every walk would start from an item no
+// one has interacted with, so that no walk can be performed.
+var ErrNoItemsSampled = errors.New("no items were sampled, check that the query refers to actual items")
+
 // sampleItemsFromQuery returns a slice of items that will be the starting
 // points of the subsequent random walks. If the query refers to an item that
-// has no record in ItemsToUsers (i.e. no one has interacted with it), the item
-// is ignored.
+// has no record in ItemsToUsers (i.e. no one has interacted with it), the
+// walks drawn from it are dropped, so that there may be fewer than Cfg.Draws
+// items, and ErrNoItemsSampled is returned if they all are. Queries whose
+// combined weights (query weight times global weight) are all zero cannot be
+// sampled from and return an error.
 func (b *Bird) sampleItemsFromQuery(query []QueryItem) ([]int, error) {
+	items, _, err := b.startWalks(query)
+	return items, err
+}
 
-	weights := make([]float64, len(query))
-	items := make([]int, len(query))
-	for i, q := range query {
-		weights[i] = q.Weight * b.ItemWeights[q.Item]
-		items[i] = q.Item
-	}
-	s, err := sampler.NewAliasSampler(b.RandSource, weights)
+// startWalks is like sampleItemsFromQuery but also returns the sampler of the
+// query, from which the walks that reach a dead end are restarted.
+func (b *Bird) startWalks(query []QueryItem) ([]int, *querySampler, error) {
+	fixed, s, err := b.queryStarts(query, b.RandSource)
 	if err != nil {
-		return nil, errors.Wrap(err, "cannot create sampler")
+		return nil, nil, err
 	}
+	b.loadQueryItemUsers(query)
 
 	sampledItems := make([]int, b.Cfg.Draws)
-	for i, iid := range s.Sample(b.Cfg.Draws) {
-		if len(b.ItemsToUsers[items[iid]]) == 0 {
+	live := 0
+	for i := range sampledItems {
+		var item int
+		if i < len(fixed) {
+			item = fixed[i]
+		} else {
+			item = s.sample(b.RandSource)
+		}
+		if len(b.itemUsers(item)) == 0 {
 			continue
 		}
-		sampledItems[i] = items[iid]
+		sampledItems[live] = item
+		live++
+	}
+	if live == 0 {
+		return nil, nil, ErrNoItemsSampled
 	}
+	sampledItems = sampledItems[:live]
+	if len(fixed) > 0 {
+		shuffle(sampledItems, b.RandSource)
+	}
+
+	return sampledItems, s, nil
+}
+
+// querySampler draws items from a query with a probability proportional to
+// the product of their query weight and global weight. Queries are short and
+// only used for one call, so a binary search over the cumulative weights is
+// cheaper than building an alias table.
+type querySampler struct {
+	query      []QueryItem
+	cumulative []float64
+	last       int // last item with a positive weight
+}
 
-	if len(sampledItems) == 0 {
-		return nil, errors.New("no items were sampled," +
-			"check that the query refers to actual items.")
+// newQuerySampler returns a sampler over the query's items. The error caused
+// by sampler.ErrZeroTotalWeight is returned when no item can be drawn.
+func (b *Bird) newQuerySampler(query []QueryItem) (querySampler, error) {
+	weights, err := b.queryWeights(query)
+	if err != nil {
+		return querySampler{}, err
 	}
 
-	return sampledItems, nil
+	return newWeightedQuerySampler(query, weights)
+}
+
+// queryWeights returns the weights with which the query items are drawn:
+// their query weight, normalized according to Cfg.QueryNormalization, times
+// their global weight, restricted by Cfg.StartJaccardThreshold and
+// Cfg.QueryTopK.
+func (b *Bird) queryWeights(query []QueryItem) ([]float64, error) {
+	weights := make([]float64, len(query))
+	for i, q := range query {
+		if err := b.checkQueryItem(q); err != nil {
+			return nil, invalidQueryItem(i, q, err.Error())
+		}
+		weights[i] = q.Weight
+	}
+	normalizeQueryWeights(weights, b.Cfg.QueryNormalization)
+	for i, q := range query {
+		weights[i] *= b.ItemWeights[q.Item]
+	}
+	if b.Cfg.StartJaccardThreshold > 0 {
+		b.dropWeakStarts(query, weights)
+	}
+	if k := b.Cfg.QueryTopK; k > 0 && k < len(query) {
+		keepTopWeights(weights, k)
+	}
+
+	return weights, nil
+}
+
+// newWeightedQuerySampler returns a sampler that draws the query items with
+// the given weights, which it takes over.
+func newWeightedQuerySampler(query []QueryItem, weights []float64) (querySampler, error) {
+	// The weights are written in place of their cumulative sums.
+	s := querySampler{query: query, cumulative: weights}
+	var totalWeight float64
+	for i, weight := range s.cumulative {
+		if weight > 0 {
+			s.last = i
+		}
+		totalWeight += weight
+		s.cumulative[i] = totalWeight
+	}
+
+	if totalWeight == 0 {
+		return querySampler{}, errors.Wrap(sampler.ErrZeroTotalWeight, "all query items have zero weight, "+
+			"check the query weights and the items' global weights")
+	}
+
+	return s, nil
+}
+
+// keepTopWeights sets to zero all the weights but the k largest ones, ties
+// being resolved in favor of the weights that come first.
+func keepTopWeights(weights []float64, k int) {
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return weights[order[i]] > weights[order[j]] })
+	for _, i := range order[k:] {
+		weights[i] = 0
+	}
+}
+
+// sample draws one item from the query.
+func (s querySampler) sample(source sampler.Rand) int {
+	x := source.Float64() * s.cumulative[len(s.cumulative)-1]
+
+	// Find the first item whose cumulative weight exceeds x; items with a
+	// zero weight do not increase it and are never chosen.
+	lo, hi := 0, len(s.cumulative)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if s.cumulative[mid] > x {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	// Rounding can make x equal to the total weight.
+	if lo == len(s.cumulative) {
+		lo = s.last
+	}
+
+	return s.query[lo].Item
 }
 
 // step performs one random walk step for each incoming item. It returns a
 // slice of visited items along with the 'referrers', i.e. the users that were
 // visited to reach these items.
 func (b *Bird) step(items []int) ([]int, []int, error) {
-
+	newItems := make([]int, len(items))
 	referrers := make([]int, len(items))
-	for i, item := range items {
-		relatedUsers := b.ItemsToUsers[item]
-		if len(relatedUsers) == 0 {
-			return nil, nil, fmt.Errorf("cannot perform step: no one has interacted with item %d", item)
+	err := b.newWalker(b.RandSource, nil).step(items, newItems, referrers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newItems, referrers, nil
+}
+
+// sampleItem samples one item from a user's collection, drawing from
+// RandSource rather than from the source of the user's sampler so that
+// replacing RandSource affects every draw. Users with an empty
+// collection have no sampler and are dead ends for the walk; they should never
+// be reached since they do not appear in ItemsToUsers.
+func (b *Bird) sampleItem(user int) (int, error) {
+	return b.sampleItemWith(user, b.RandSource)
+}
+
+// maxSelfLoopRetries is the number of times a walk draws again the item it
+// steps to when it is the item it comes from and Cfg.ExcludeSelfLoops is set.
+const maxSelfLoopRetries = 8
+
+// sampleNextItem samples the item a walk coming from item from steps to
+// through user, avoiding from if Cfg.ExcludeSelfLoops is set.
+func (b *Bird) sampleNextItem(user, from int, source sampler.Rand) (int, error) {
+	item, err := b.sampleItemWith(user, source)
+	if err != nil || !b.Cfg.ExcludeSelfLoops || len(b.UsersToItems[user]) == 1 {
+		return item, err
+	}
+
+	for r := 0; item == from && r < maxSelfLoopRetries; r++ {
+		item, err = b.sampleItemWith(user, source)
+		if err != nil {
+			return 0, err
 		}
-		referrers[i] = relatedUsers[b.RandSource.Intn(len(relatedUsers))]
 	}
 
-	newItems := make([]int, len(items))
-	for j, user := range referrers {
-		newItems[j] = b.sampleItem(user)
+	return item, nil
+}
+
+// UserSampler returns the sampler the walks draw from the collection of user
+// with, which returns indices in UsersToItems[user]: an alias sampler, the
+// sampler built by the factory of NewBirdWithSamplerFactory,
+// CompactSamplers or LinearSamplerMaxDegree, or the Fenwick sampler of a
+// collection modified incrementally. It returns nil with
+// Cfg.UniformUserSampling, or for an empty collection of a Bird created with
+// a factory.
+func (b *Bird) UserSampler(user int) sampler.Sampler {
+	if b.Cfg.UniformUserSampling {
+		return nil
+	}
+	if b.customSamplers != nil {
+		return b.customSamplers[user]
+	}
+	if f := b.fenwickSampler(user); f != nil {
+		return f
 	}
 
-	return newItems, referrers, nil
+	return &b.userItemsSamplers[user]
 }
 
-// sampleItem samples one item from a user's collection.
-func (b *Bird) sampleItem(user int) int {
-	s := b.UserItemsSamplers[user]
-	sampledItem := b.UsersToItems[user][s.Sample(1)[0]]
+// sampleItemWith samples one item from a user's collection using source
+// rather than the random source of the user's sampler. The sampler is read in
+// place rather than copied out of the Bird. Collections of a single
+// item are returned without drawing from source.
+func (b *Bird) sampleItemWith(user int, source sampler.Rand) (int, error) {
+	switch len(b.UsersToItems[user]) {
+	case 0:
+		return 0, fmt.Errorf("user %d has an empty collection", user)
+	case 1:
+		return b.UsersToItems[user][0], nil
+	}
+	if b.Cfg.UniformUserSampling {
+		return b.UsersToItems[user][source.Intn(len(b.UsersToItems[user]))], nil
+	}
+	if b.customSamplers != nil {
+		return b.UsersToItems[user][b.customSamplers[user].SampleWith(source)], nil
+	}
+	if f := b.fenwickSampler(user); f != nil {
+		return b.UsersToItems[user][f.SampleWith(source)], nil
+	}
+	s := &b.userItemsSamplers[user]
+	sampledItem := b.UsersToItems[user][s.SampleWith(source)]
+
+	return sampledItem, nil
+}
+
+// capUserItems returns the collections truncated to their maxItems items with
+// the highest sampling weight, along with the matching edge weights. Users
+// under the cap keep their slices; the others get new ones, so the input is
+// never modified.
+func capUserItems(maxItems int, itemWeights []float64, usersToItems [][]int,
+	edgeWeights [][]float64) ([][]int, [][]float64) {
+
+	capped := make([][]int, len(usersToItems))
+	var cappedWeights [][]float64
+	if edgeWeights != nil {
+		cappedWeights = make([][]float64, len(edgeWeights))
+	}
+
+	for u, userItems := range usersToItems {
+		capped[u] = userItems
+		if edgeWeights != nil {
+			cappedWeights[u] = edgeWeights[u]
+		}
+		if len(userItems) <= maxItems {
+			continue
+		}
+
+		weight := func(j int) float64 {
+			w := itemWeights[userItems[j]]
+			if edgeWeights != nil {
+				w *= edgeWeights[u][j]
+			}
+			return w
+		}
+		positions := make([]int, len(userItems))
+		for j := range positions {
+			positions[j] = j
+		}
+		sort.SliceStable(positions, func(a, b int) bool { return weight(positions[a]) > weight(positions[b]) })
+		positions = positions[:maxItems]
+		sort.Ints(positions)
+
+		capped[u] = make([]int, maxItems)
+		for k, j := range positions {
+			capped[u][k] = userItems[j]
+		}
+		if edgeWeights != nil {
+			cappedWeights[u] = make([]float64, maxItems)
+			for k, j := range positions {
+				cappedWeights[u][k] = edgeWeights[u][j]
+			}
+		}
+	}
 
-	return sampledItem
+	return capped, cappedWeights
 }
 
 // initUserItemsSamplers initializes the samplers that are used to sample from
 // a user's items collection (one sampler per user). We use the alias sampling
-// method which has proven sensibly better in benchmarks.
-func initUserItemsSamplers(randSource *rand.Rand,
+// method which has proven sensibly better in benchmarks. Users with an empty
+// collection are left with a zero-value sampler, which is never drawn from:
+// they do not appear in ItemsToUsers, so the walks never go through them.
+// Users with a single item get a sampler that always draws it, whatever its
+// weight.
+//
+// The users are shared among workers goroutines (GOMAXPROCS when workers is
+// 0). Building the tables does not consume randomness, so the samplers do not
+// depend on the number of workers; they all share randSource to sample. The
+// first error stops the other workers. Each worker reuses the same working
+// memory, sized for the largest collection, for all its users.
+func initUserItemsSamplers(randSource sampler.Rand,
 	itemWeights []float64,
-	userToItems [][]int) ([]sampler.AliasSampler, error) {
+	userToItems [][]int,
+	edgeWeights [][]float64,
+	workers int) ([]sampler.AliasSampler, error) {
 
-	userItemsSamplers := make([]sampler.AliasSampler, len(userToItems))
-	for i, userItems := range userToItems {
+	var maxDegree int
+	for _, userItems := range userToItems {
+		if len(userItems) > maxDegree {
+			maxDegree = len(userItems)
+		}
+	}
 
-		weights := make([]float64, len(userItems))
-		for j, item := range userItems {
-			weights[j] = itemWeights[item]
+	userItemsSamplers := make([]sampler.AliasSampler, len(userToItems))
+	err := forEachUserPerWorker(len(userToItems), workers, func() func(int) error {
+		scratch := sampler.NewAliasScratch(maxDegree)
+		weights := make([]float64, maxDegree)
+		return func(i int) error {
+			switch len(userToItems[i]) {
+			case 0:
+				return nil
+			case 1:
+				userItemsSamplers[i] = singleItemSampler(randSource)
+				return nil
+			}
+			var userEdgeWeights []float64
+			if edgeWeights != nil {
+				userEdgeWeights = edgeWeights[i]
+			}
+			userWeights := weights[:len(userToItems[i])]
+			fillUserSamplingWeights(userWeights, itemWeights, userToItems[i], userEdgeWeights)
+			userItemsSampler, err := sampler.NewAliasSamplerScratch(randSource, userWeights, scratch)
+			if err != nil {
+				return errors.Wrap(err, "could not initialize the probability and alias tables")
+			}
+			userItemsSamplers[i] = *userItemsSampler
+			return nil
 		}
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		userItemsSampler, err := sampler.NewAliasSampler(randSource, weights)
-		if err != nil {
-			return nil, errors.Wrap(err, "could not initialize the probability and alias tables")
+	return userItemsSamplers, nil
+}
+
+// forEachUser calls f for each of the numUsers users, sharing them among
+// workers goroutines (GOMAXPROCS when workers is 0). The first error stops
+// the other workers and is returned along with the user it occurred for.
+func forEachUser(numUsers, workers int, f func(user int) error) error {
+	return forEachUserPerWorker(numUsers, workers, func() func(int) error { return f })
+}
+
+// forEachUserPerWorker is forEachUser with a function per worker: each worker
+// calls newWorker once and then the function it returns for its users, which
+// lets it keep state, such as buffers, that is not shared with the others.
+func forEachUserPerWorker(numUsers, workers int, newWorker func() func(user int) error) error {
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// Users are handed out in batches to limit contention on the counter.
+	const batchSize = 256
+	var next int64
+	var failed int32
+	var firstErr error
+	var errOnce sync.Once
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f := newWorker()
+			for atomic.LoadInt32(&failed) == 0 {
+				start := int(atomic.AddInt64(&next, batchSize)) - batchSize
+				if start >= numUsers {
+					return
+				}
+				end := minInt(start+batchSize, numUsers)
+				for i := start; i < end; i++ {
+					if err := f(i); err != nil {
+						errOnce.Do(func() { firstErr = errors.Wrapf(err, "user %d", i) })
+						atomic.StoreInt32(&failed, 1)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+// newUserItemsSampler initializes the sampler of a single user's collection,
+// weighted by the items' global weights, times the interactions' weights when
+// edgeWeights is not nil. Empty collections get a zero-value sampler.
+func newUserItemsSampler(randSource sampler.Rand, itemWeights []float64, userItems []int,
+	edgeWeights []float64) (sampler.AliasSampler, error) {
+
+	return newSamplerFromWeights(randSource, userSamplingWeights(itemWeights, userItems, edgeWeights))
+}
+
+// userSamplingWeights returns the weights with which the items of a user's
+// collection are sampled.
+func userSamplingWeights(itemWeights []float64, userItems []int, edgeWeights []float64) []float64 {
+	weights := make([]float64, len(userItems))
+	fillUserSamplingWeights(weights, itemWeights, userItems, edgeWeights)
+
+	return weights
+}
+
+// fillUserSamplingWeights writes the sampling weights of a user's collection
+// to weights, which must be as long as userItems.
+func fillUserSamplingWeights(weights []float64, itemWeights []float64, userItems []int, edgeWeights []float64) {
+	for j, item := range userItems {
+		weights[j] = itemWeights[item]
+		if edgeWeights != nil {
+			weights[j] *= edgeWeights[j]
 		}
+	}
+}
 
-		userItemsSamplers[i] = *userItemsSampler
+// newSamplerFromWeights builds the sampler of a user's collection from its
+// sampling weights. An empty collection gets a zero-value sampler, which
+// must never be drawn from, and a collection of a single item a sampler that
+// always draws it, whatever its weight.
+func newSamplerFromWeights(randSource sampler.Rand, weights []float64) (sampler.AliasSampler, error) {
+	switch len(weights) {
+	case 0:
+		return sampler.AliasSampler{}, nil
+	case 1:
+		return singleItemSampler(randSource), nil
 	}
 
-	return userItemsSamplers, nil
+	userItemsSampler, err := sampler.NewAliasSampler(randSource, weights)
+	if err != nil {
+		return sampler.AliasSampler{}, errors.Wrap(err, "could not initialize the probability and alias tables")
+	}
+
+	return *userItemsSampler, nil
+}
+
+// singleItemSampler returns the sampler of a collection of a single item,
+// which always draws it. Its weight plays no part, so that an item of zero
+// global weight can still be reached through a user who only has this item.
+func singleItemSampler(randSource sampler.Rand) sampler.AliasSampler {
+	return sampler.AliasSampler{
+		ProbabilityTable: []float64{1},
+		AliasTable:       []int{0},
+		Source:           randSource,
+	}
 }
 
 // validateBirdInput checks the validity of the data fed to Bird.  It returns
@@ -207,8 +985,11 @@ func validateBirdInputs(itemWeights []float64, usersToItems [][]int) error {
 	// Check that there is a weight for each item present in adjacency tables.
 	numItems := len(itemWeights)
 	var m int
-	for _, userItems := range usersToItems {
+	for u, userItems := range usersToItems {
 		for _, item := range userItems {
+			if item < 0 {
+				return fmt.Errorf("user %d refers to a negative item", u)
+			}
 			if item > m {
 				m = item
 			}
@@ -221,6 +1002,74 @@ func validateBirdInputs(itemWeights []float64, usersToItems [][]int) error {
 	return nil
 }
 
+// validateEdgeWeights checks that the edge weights, if any, are aligned with
+// the adjacency list and positive.
+func validateEdgeWeights(usersToItems [][]int, edgeWeights [][]float64) error {
+	if edgeWeights == nil {
+		return nil
+	}
+
+	if len(edgeWeights) != len(usersToItems) {
+		return fmt.Errorf("there are %d users in the edge weights but %d in UsersToItems",
+			len(edgeWeights), len(usersToItems))
+	}
+	for u, userWeights := range edgeWeights {
+		if len(userWeights) != len(usersToItems[u]) {
+			return fmt.Errorf("user %d has %d edge weights but %d items",
+				u, len(userWeights), len(usersToItems[u]))
+		}
+		for _, w := range userWeights {
+			if w < 0 {
+				return fmt.Errorf("user %d has a negative edge weight", u)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GraphReport lists the nodes of the user-item graph that do not take part in
+// any interaction.
+type GraphReport struct {
+	EmptyUsers  []int // users with an empty collection
+	OrphanItems []int // items no one has interacted with
+}
+
+// OK tells whether the graph has neither empty users nor orphan items.
+func (r GraphReport) OK() bool {
+	return len(r.EmptyUsers) == 0 && len(r.OrphanItems) == 0
+}
+
+// InspectGraph is a stricter, optional, validation of the data fed to Bird.
+// It reports the users with an empty collection and the items no one has
+// interacted with. Bird handles both, but they usually point to data quality
+// issues; unlike validateBirdInputs it does not fail so that callers can
+// decide whether to prune them. Items outside of [0, len(itemWeights)) are
+// ignored.
+func InspectGraph(itemWeights []float64, usersToItems [][]int) GraphReport {
+	var report GraphReport
+
+	interacted := make([]bool, len(itemWeights))
+	for u, userItems := range usersToItems {
+		if len(userItems) == 0 {
+			report.EmptyUsers = append(report.EmptyUsers, u)
+		}
+		for _, item := range userItems {
+			if item >= 0 && item < len(interacted) {
+				interacted[item] = true
+			}
+		}
+	}
+
+	for item, ok := range interacted {
+		if !ok {
+			report.OrphanItems = append(report.OrphanItems, item)
+		}
+	}
+
+	return report
+}
+
 // permuteAdjacencyList transforms the UsersToItems adjacency list into the
 // complementary ItemsToUsers adjacency list.
 func permuteAdjacencyList(numItems int, usersToItems [][]int) [][]int {
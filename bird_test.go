@@ -2,7 +2,12 @@ package birdland
 
 import (
 	"math/rand"
+	"reflect"
 	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/rlouf/birdland/sampler"
+	"github.com/rlouf/birdland/sampler/samplertest"
 )
 
 type BirdInitCase struct {
@@ -11,6 +16,7 @@ type BirdInitCase struct {
 	UsersToItems [][]int
 	Draws        int
 	Depth        int
+	MaxVisits    int
 	Valid        bool
 }
 
@@ -71,6 +77,23 @@ var birdInitTable = []BirdInitCase{
 		Draws:        1,
 		Valid:        false,
 	},
+	{
+		Name:         "Negative item in adjacency tables",
+		ItemWeights:  []float64{1, 1},
+		UsersToItems: [][]int{[]int{0, -1}},
+		Depth:        1,
+		Draws:        1,
+		Valid:        false,
+	},
+	{
+		Name:         "Negative MaxVisits",
+		ItemWeights:  []float64{1, 1},
+		UsersToItems: [][]int{[]int{0}, []int{1}},
+		Depth:        1,
+		Draws:        1,
+		MaxVisits:    -1,
+		Valid:        false,
+	},
 	{
 		Name:         "Perfectly valid input",
 		ItemWeights:  []float64{1, 1},
@@ -79,6 +102,14 @@ var birdInitTable = []BirdInitCase{
 		Draws:        1,
 		Valid:        true,
 	},
+	{
+		Name:         "Users with empty collections",
+		ItemWeights:  []float64{1, 1},
+		UsersToItems: [][]int{[]int{}, []int{0, 1}, []int{}},
+		Depth:        1,
+		Draws:        1,
+		Valid:        true,
+	},
 }
 
 func TestBirdInitialization(t *testing.T) {
@@ -86,8 +117,9 @@ func TestBirdInitialization(t *testing.T) {
 		cfg := NewBirdCfg()
 		cfg.Depth = ex.Depth
 		cfg.Draws = ex.Draws
+		cfg.MaxVisits = ex.MaxVisits
 
-		_, err := NewBird(cfg, ex.ItemWeights, ex.UsersToItems)
+		bird, err := NewBird(cfg, ex.ItemWeights, ex.UsersToItems)
 		if err != nil && ex.Valid {
 			t.Errorf("Initialization: %s: Bird initialization should not have raised "+
 				"an error but did: %v", ex.Name, err)
@@ -96,6 +128,600 @@ func TestBirdInitialization(t *testing.T) {
 			t.Errorf("Initialization: %s: Bird initialization should have raised "+
 				"an error but did not", ex.Name)
 		}
+		if err != nil && bird != nil {
+			t.Errorf("Initialization: %s: expected a nil Bird along with the error", ex.Name)
+		}
+		if _, ok := err.(*InvalidInputError); err != nil && !ok {
+			t.Errorf("Initialization: %s: expected an *InvalidInputError, got %T", ex.Name, err)
+		}
+	}
+
+	cfg := NewBirdCfg()
+	cfg.Dangling = DanglingPolicy(7)
+	bird, err := NewBird(cfg, []float64{1}, [][]int{[]int{0}})
+	if _, ok := err.(*InvalidInputError); !ok || bird != nil {
+		t.Errorf("Initialization: expected an invalid configuration to return a nil Bird and an *InvalidInputError, got %v and %T", bird, err)
+	}
+	_, err = NewBirdWithEdgeWeights(NewBirdCfg(), []float64{1}, [][]int{[]int{0}}, [][]float64{{-1}})
+	if _, ok := err.(*InvalidInputError); !ok {
+		t.Errorf("Initialization: expected invalid edge weights to return an *InvalidInputError, got %T", err)
+	}
+}
+
+func TestBirdEmptyUsers(t *testing.T) {
+	itemWeights := []float64{1, 1, 1}
+	usersToItems := [][]int{[]int{}, []int{0, 1}, []int{}, []int{1, 2}, []int{}}
+
+	cfg := NewBirdCfg()
+	cfg.Depth = 3
+	cfg.Draws = 100
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("EmptyUsers: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 2, Weight: 1}}
+	items, referrers, err := bird.Process(query)
+	if err != nil {
+		t.Fatalf("EmptyUsers: Process should not have raised an error but did: %v", err)
+	}
+	if len(items) != cfg.Depth*cfg.Draws {
+		t.Errorf("EmptyUsers: expected %d visited items, got %d", cfg.Depth*cfg.Draws, len(items))
+	}
+	for _, r := range referrers {
+		if len(usersToItems[r]) == 0 {
+			t.Errorf("EmptyUsers: user %d has an empty collection but was selected as a referrer", r)
+			break
+		}
+	}
+
+	if _, err := bird.sampleItem(0); err == nil {
+		t.Errorf("EmptyUsers: sampling from an empty collection should have raised an error but did not")
+	}
+}
+
+func TestBirdSingleItemUsers(t *testing.T) {
+	// Item 2 has a zero global weight but is the only item of user 1.
+	itemWeights := []float64{1, 1, 0}
+	usersToItems := [][]int{[]int{}, []int{2}, []int{0, 1, 2}}
+
+	bird, err := NewBird(NewBirdCfg(), itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("SingleItemUsers: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	s := bird.userItemsSamplers[1]
+	if !reflect.DeepEqual(s.ProbabilityTable, []float64{1}) || !reflect.DeepEqual(s.AliasTable, []int{0}) {
+		t.Errorf("SingleItemUsers: expected a sampler that always draws the item, got %v and %v", s.ProbabilityTable, s.AliasTable)
+	}
+	for i := 0; i < 100; i++ {
+		if item, err := bird.sampleItem(1); err != nil || item != 2 {
+			t.Fatalf("SingleItemUsers: expected user 1 to always lead to item 2, got %d and %v", item, err)
+		}
+	}
+	if len(bird.userItemsSamplers[0].AliasTable) != 0 {
+		t.Errorf("SingleItemUsers: expected a zero-value sampler for the empty collection")
+	}
+	for item, users := range bird.ItemsToUsers {
+		if indexOf(users, 0) >= 0 {
+			t.Errorf("SingleItemUsers: the user with an empty collection is a user of item %d", item)
+		}
+	}
+}
+
+func TestBirdZeroWeightQuery(t *testing.T) {
+	bird, err := NewBird(NewBirdCfg(), []float64{0, 1, 1}, [][]int{[]int{0, 1}, []int{1, 2}})
+	if err != nil {
+		t.Fatalf("ZeroWeightQuery: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	queries := map[string][]QueryItem{
+		"Zero query weights":  {{Item: 1, Weight: 0}, {Item: 2, Weight: 0}},
+		"Zero global weights": {{Item: 0, Weight: 3}},
+		"Single item":         {{Item: 1, Weight: 0}},
+	}
+	for name, query := range queries {
+		_, _, err := bird.Process(query)
+		if errors.Cause(err) != sampler.ErrZeroTotalWeight {
+			t.Errorf("ZeroWeightQuery: %s: expected Process to raise ErrZeroTotalWeight, got %v", name, err)
+		}
+	}
+}
+
+func TestBirdColdQuery(t *testing.T) {
+	// No one has interacted with item 3.
+	cfg := NewBirdCfg()
+	cfg.Draws = 1000
+	bird, err := NewBird(cfg, []float64{1, 1, 1, 1}, [][]int{[]int{0, 1}, []int{1, 2}})
+	if err != nil {
+		t.Fatalf("ColdQuery: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	cold := []QueryItem{{Item: 3, Weight: 1}}
+	if _, err := bird.sampleItemsFromQuery(cold); err != ErrNoItemsSampled {
+		t.Errorf("ColdQuery: expected ErrNoItemsSampled, got %v", err)
+	}
+	for _, parallelism := range []int{1, 4} {
+		cfg.Parallelism = parallelism
+		if _, _, err := bird.Process(cold); errors.Cause(err) != ErrNoItemsSampled {
+			t.Errorf("ColdQuery: expected Process to raise ErrNoItemsSampled with a parallelism of %d, got %v", parallelism, err)
+		}
+	}
+	cfg.Parallelism = 0
+	if _, err := bird.RecommendItemsScored(cold, 2, ScoreOptions{}); errors.Cause(err) != ErrNoItemsSampled {
+		t.Errorf("ColdQuery: expected RecommendItemsScored to raise ErrNoItemsSampled, got %v", err)
+	}
+
+	// The walks drawn from item 3 are dropped rather than started from
+	// item 0.
+	starts, err := bird.sampleItemsFromQuery([]QueryItem{{Item: 3, Weight: 1}, {Item: 2, Weight: 1}})
+	if err != nil {
+		t.Fatalf("ColdQuery: sampling the query should not have raised an error but did: %v", err)
+	}
+	if len(starts) == 0 || len(starts) == cfg.Draws {
+		t.Errorf("ColdQuery: expected about half of the %d walks to start, got %d", cfg.Draws, len(starts))
+	}
+	for _, item := range starts {
+		if item != 2 {
+			t.Fatalf("ColdQuery: expected every walk to start from item 2, got %d", item)
+		}
+	}
+}
+
+func TestBirdQueryTopK(t *testing.T) {
+	itemWeights := []float64{1, 2, 3, 4, 1}
+	usersToItems := [][]int{[]int{0, 1, 2}, []int{2, 3, 4}}
+	query := []QueryItem{{Item: 0, Weight: 10}, {Item: 1, Weight: 1}, {Item: 2, Weight: 1}, {Item: 3, Weight: 1}, {Item: 4, Weight: 3}}
+
+	// the combined weights are 10, 2, 3, 4 and 3: the top 3 items are 0, 3
+	// and 2, which wins the tie with item 4.
+	for topK, expected := range map[int][]int{0: {0, 1, 2, 3, 4}, 3: {0, 2, 3}, 10: {0, 1, 2, 3, 4}} {
+		cfg := NewBirdCfg()
+		cfg.Draws = 5000
+		cfg.QueryTopK = topK
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("QueryTopK: Bird initialization should not have raised an error but did: %v", err)
+		}
+
+		starts, err := bird.sampleItemsFromQuery(query)
+		if err != nil {
+			t.Fatalf("QueryTopK: sampling the query should not have raised an error but did: %v", err)
+		}
+		seeded := make(map[int]bool)
+		for _, item := range starts {
+			seeded[item] = true
+		}
+		for _, item := range expected {
+			if !seeded[item] {
+				t.Errorf("QueryTopK: %d: item %d never started a walk", topK, item)
+			}
+			delete(seeded, item)
+		}
+		if len(seeded) > 0 {
+			t.Errorf("QueryTopK: %d: items %v should not have started any walk", topK, seeded)
+		}
+	}
+
+	cfg := NewBirdCfg()
+	cfg.QueryTopK = -1
+	if _, err := NewBird(cfg, itemWeights, usersToItems); err == nil {
+		t.Errorf("QueryTopK: a negative QueryTopK should have raised an error but did not")
+	}
+}
+
+func TestBirdSamplingDistribution(t *testing.T) {
+	itemWeights := []float64{1, 2, 3, 4, 1}
+	usersToItems := [][]int{[]int{0, 1, 2}, []int{2, 3, 4}}
+	cfg := NewBirdCfg()
+	cfg.Draws = 20000
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("SamplingDistribution: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	// The walks start from the query items with a probability proportional
+	// to their query weight times their global weight.
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 2, Weight: 2}, {Item: 3, Weight: 1}}
+	starts, err := bird.sampleItemsFromQuery(query)
+	if err != nil {
+		t.Fatalf("SamplingDistribution: sampling the query should not have raised an error but did: %v", err)
+	}
+	counts := make([]int, len(itemWeights))
+	for _, item := range starts {
+		counts[item]++
+	}
+	samplertest.AssertCounts(t, counts, []float64{1, 0, 6, 4, 0}, 0.001)
+
+	// A step from item 2 goes through either of its users, then to one of
+	// their items with a probability proportional to its global weight.
+	expected := make([]float64, len(itemWeights))
+	for _, user := range bird.ItemsToUsers[2] {
+		var total float64
+		for _, item := range usersToItems[user] {
+			total += itemWeights[item]
+		}
+		for _, item := range usersToItems[user] {
+			expected[item] += itemWeights[item] / total / float64(len(bird.ItemsToUsers[2]))
+		}
+	}
+	items := make([]int, cfg.Draws)
+	for i := range items {
+		items[i] = 2
+	}
+	visits, _, err := bird.step(items)
+	if err != nil {
+		t.Fatalf("SamplingDistribution: step should not have raised an error but did: %v", err)
+	}
+	counts = make([]int, len(itemWeights))
+	for _, item := range visits {
+		counts[item]++
+	}
+	samplertest.AssertCounts(t, counts, expected, 0.001)
+}
+
+func TestBirdExcludeSelfLoops(t *testing.T) {
+	itemWeights := []float64{1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{2}}
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 2, Weight: 1}}
+
+	for _, parallelism := range []int{1, 2} {
+		cfg := NewBirdCfg()
+		cfg.Draws = 1000
+		cfg.Parallelism = parallelism
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("ExcludeSelfLoops: Bird initialization should not have raised an error but did: %v", err)
+		}
+
+		// Walks from item 0 step back to it half of the time, and those from
+		// item 2 always do since its only user has nothing else.
+		selfLoops := func() (int, int) {
+			items, _, err := bird.Process(query)
+			if err != nil {
+				t.Fatalf("ExcludeSelfLoops: Process should not have raised an error but did: %v", err)
+			}
+			var zeros, twos int
+			for _, item := range items {
+				switch item {
+				case 0:
+					zeros++
+				case 2:
+					twos++
+				}
+			}
+			return zeros, twos
+		}
+
+		zeros, twos := selfLoops()
+		if zeros < cfg.Draws/8 || twos < cfg.Draws/4 {
+			t.Errorf("ExcludeSelfLoops: parallelism %d: expected self-loops by default, got %d on item 0 and %d on item 2", parallelism, zeros, twos)
+		}
+		cfg.ExcludeSelfLoops = true
+		zeros, twos = selfLoops()
+		// 2^-9 of the walks from item 0 draw it 9 times in a row.
+		if zeros > cfg.Draws/100 {
+			t.Errorf("ExcludeSelfLoops: parallelism %d: %d walks stepped back to item 0", parallelism, zeros)
+		}
+		if twos < cfg.Draws/4 {
+			t.Errorf("ExcludeSelfLoops: parallelism %d: the walks from item 2 should have stayed on it, got %d", parallelism, twos)
+		}
+	}
+}
+
+func TestBirdUniformUserSampling(t *testing.T) {
+	itemWeights := []float64{1, 10, 100, 1000}
+	usersToItems := [][]int{[]int{0, 1, 2, 3}, []int{3}}
+	cfg := NewBirdCfg()
+	cfg.UniformUserSampling = true
+	cfg.CompactSamplers = true
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("UniformUserSampling: Bird initialization should not have raised an error but did: %v", err)
+	}
+	for u, s := range bird.userItemsSamplers {
+		if len(s.AliasTable) != 0 || bird.customSamplers != nil {
+			t.Errorf("UniformUserSampling: expected no sampler to be built, user %d has one", u)
+		}
+	}
+	if s := bird.UserSampler(0); s != nil {
+		t.Errorf("UniformUserSampling: expected UserSampler to return nil, got %T", s)
+	}
+
+	// The items are drawn uniformly whatever their weights.
+	counts := make([]int, len(itemWeights))
+	for i := 0; i < 40000; i++ {
+		item, err := bird.sampleItem(0)
+		if err != nil {
+			t.Fatalf("UniformUserSampling: sampling should not have raised an error but did: %v", err)
+		}
+		counts[item]++
+	}
+	samplertest.AssertCounts(t, counts, []float64{1, 1, 1, 1}, 0.001)
+
+	items, probabilities := bird.UserDistribution(0, 4)
+	if len(items) != 4 || probabilities[0] != 0.25 || probabilities[3] != 0.25 {
+		t.Errorf("UniformUserSampling: expected a uniform distribution, got %v and %v", items, probabilities)
+	}
+	if _, _, err := bird.Process([]QueryItem{{Item: 3, Weight: 1}}); err != nil {
+		t.Errorf("UniformUserSampling: Process should not have raised an error but did: %v", err)
+	}
+	if err := bird.AddInteraction(1, 0); err != nil {
+		t.Errorf("UniformUserSampling: AddInteraction should not have raised an error but did: %v", err)
+	}
+	if err := bird.Warmup(); err != nil {
+		t.Errorf("UniformUserSampling: Warmup should not have raised an error but did: %v", err)
+	}
+}
+
+func TestBirdChainedSteps(t *testing.T) {
+	// Users link the items into a chain, so that item 2 can only be reached
+	// from item 0 in two steps.
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 2}, []int{2, 3}}
+	cfg := NewBirdCfg()
+	cfg.Depth = 2
+	cfg.Draws = 100
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("ChainedSteps: Bird initialization should not have raised an error but did: %v", err)
+	}
+	items, _, err := bird.Process([]QueryItem{{Item: 0, Weight: 1}})
+	if err != nil {
+		t.Fatalf("ChainedSteps: Process should not have raised an error but did: %v", err)
+	}
+	for _, item := range items[cfg.Draws:] {
+		if item == 2 {
+			return
+		}
+	}
+	t.Errorf("ChainedSteps: expected the second step to start from the items of the first one and reach item 2")
+}
+
+func TestBirdMaxVisits(t *testing.T) {
+	itemWeights := []float64{1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 2}}
+	query := []QueryItem{{Item: 0, Weight: 1}}
+
+	for _, maxVisits := range []int{1, 50, 150, 299, 300, 1000} {
+		cfg := NewBirdCfg()
+		cfg.Depth = 3
+		cfg.Draws = 100
+		cfg.MaxVisits = maxVisits
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("MaxVisits: Bird initialization should not have raised an error but did: %v", err)
+		}
+
+		items, referrers, err := bird.Process(query)
+		if err != nil {
+			t.Fatalf("MaxVisits: Process should not have raised an error but did: %v", err)
+		}
+		expected := cfg.Depth * cfg.Draws
+		if maxVisits < expected {
+			expected = maxVisits
+		}
+		if len(items) != expected || len(referrers) != expected {
+			t.Errorf("MaxVisits: %d: expected %d visits, got %d items and %d referrers",
+				maxVisits, expected, len(items), len(referrers))
+		}
+	}
+}
+
+func TestBirdMaxVisitsColdStarts(t *testing.T) {
+	// No one has interacted with item 1, so half of the walks are dropped
+	// and the others need two steps to collect the 10 visits.
+	cfg := NewBirdCfg()
+	cfg.Depth = 3
+	cfg.Draws = 10
+	cfg.MaxVisits = 10
+	bird, err := NewBird(cfg, []float64{1, 1}, [][]int{[]int{0}})
+	if err != nil {
+		t.Fatalf("MaxVisitsColdStarts: Bird initialization should not have raised an error but did: %v", err)
+	}
+	query := []QueryItem{{Item: 0, Weight: 1, Draws: 5}, {Item: 1, Weight: 1, Draws: 5}}
+
+	items, _, err := bird.Process(query)
+	if err != nil || len(items) != cfg.MaxVisits {
+		t.Errorf("MaxVisitsColdStarts: expected Process to return %d visits, got %d (error: %v)", cfg.MaxVisits, len(items), err)
+	}
+	items, _, err = bird.ProcessSeeded(query, 42, 2)
+	if err != nil || len(items) != cfg.MaxVisits {
+		t.Errorf("MaxVisitsColdStarts: expected ProcessSeeded to return %d visits, got %d (error: %v)", cfg.MaxVisits, len(items), err)
+	}
+	counts, err := bird.ProcessCounts(query)
+	if err != nil || counts[0] != cfg.MaxVisits {
+		t.Errorf("MaxVisitsColdStarts: expected ProcessCounts to count %d visits, got %v (error: %v)", cfg.MaxVisits, counts, err)
+	}
+	visits, _, err := bird.walkVisits(query)
+	if err != nil || len(visits) != cfg.MaxVisits {
+		t.Errorf("MaxVisitsColdStarts: expected %d visits to be scored, got %d (error: %v)", cfg.MaxVisits, len(visits), err)
+	}
+}
+
+func TestBirdProcessPairs(t *testing.T) {
+	cfg := NewBirdCfg()
+	cfg.Depth = 2
+	cfg.Draws = 50
+	bird, err := NewBird(cfg, []float64{1, 2, 3}, [][]int{[]int{0, 1}, []int{1, 2}, []int{2}})
+	if err != nil {
+		t.Fatalf("ProcessPairs: Bird initialization should not have raised an error but did: %v", err)
+	}
+	query := []QueryItem{{Item: 1, Weight: 1}}
+
+	bird.ReSeed(42)
+	items, referrers, err := bird.Process(query)
+	if err != nil {
+		t.Fatalf("ProcessPairs: Process should not have raised an error but did: %v", err)
+	}
+	bird.ReSeed(42)
+	pairs, err := bird.ProcessPairs(query)
+	if err != nil {
+		t.Fatalf("ProcessPairs: should not have raised an error but did: %v", err)
+	}
+
+	if len(pairs) != len(items) {
+		t.Fatalf("ProcessPairs: expected %d pairs, got %d", len(items), len(pairs))
+	}
+	for i, pair := range pairs {
+		if pair.Item != items[i] || pair.Referrer != referrers[i] {
+			t.Fatalf("ProcessPairs: pair %d is %+v, expected {%d %d}", i, pair, items[i], referrers[i])
+		}
+		if indexOf(bird.UsersToItems[pair.Referrer], pair.Item) == -1 {
+			t.Errorf("ProcessPairs: item %d does not belong to the collection of its referrer %d", pair.Item, pair.Referrer)
+		}
+	}
+
+	if _, err := bird.ProcessPairs(nil); err == nil {
+		t.Errorf("ProcessPairs: an empty query should have raised an error but did not")
+	}
+}
+
+func TestNewBirdFromInteractions(t *testing.T) {
+	usersToItems := [][]int{[]int{0, 2}, []int{}, []int{2, 3}, []int{2}}
+	bird, err := NewBirdFromInteractions(NewBirdCfg(), usersToItems)
+	if err != nil {
+		t.Fatalf("FromInteractions: initialization should not have raised an error but did: %v", err)
+	}
+	expected := []float64{1, 0, 3, 1}
+	if !reflect.DeepEqual(bird.ItemWeights, expected) {
+		t.Errorf("FromInteractions: expected item weights %v, got %v", expected, bird.ItemWeights)
+	}
+
+	for name, usersToItems := range map[string][][]int{
+		"No interactions": [][]int{[]int{}, []int{}},
+		"Negative item":   [][]int{[]int{0, -1}},
+	} {
+		if _, err := NewBirdFromInteractions(NewBirdCfg(), usersToItems); err == nil {
+			t.Errorf("FromInteractions: %s: should have raised an error but did not", name)
+		}
+	}
+}
+
+func TestBirdMaxUserItems(t *testing.T) {
+	cfg := NewBirdCfg()
+	cfg.MaxUserItems = 2
+	itemWeights := []float64{1, 3, 2, 3, 0.5}
+	usersToItems := [][]int{[]int{0, 1, 2, 3}, []int{4, 0}, []int{4, 2, 0}}
+	edgeWeights := [][]float64{[]float64{1, 1, 1, 1}, []float64{1, 1}, []float64{10, 1, 1}}
+
+	bird, err := NewBirdWithEdgeWeights(cfg, itemWeights, usersToItems, edgeWeights)
+	if err != nil {
+		t.Fatalf("MaxUserItems: initialization should not have raised an error but did: %v", err)
+	}
+
+	// User 0 keeps items 1 and 3 (weight 3), user 2 keeps item 4 (weight 5)
+	// and item 2 (weight 2).
+	expected := [][]int{[]int{1, 3}, []int{4, 0}, []int{4, 2}}
+	if !reflect.DeepEqual(bird.UsersToItems, expected) {
+		t.Errorf("MaxUserItems: expected collections %v, got %v", expected, bird.UsersToItems)
+	}
+	expectedWeights := [][]float64{[]float64{1, 1}, []float64{1, 1}, []float64{10, 1}}
+	if !reflect.DeepEqual(bird.EdgeWeights, expectedWeights) {
+		t.Errorf("MaxUserItems: expected edge weights %v, got %v", expectedWeights, bird.EdgeWeights)
+	}
+	if len(usersToItems[0]) != 4 {
+		t.Errorf("MaxUserItems: the input collections should not be modified")
+	}
+	if len(bird.ItemsToUsers[0]) != 1 {
+		t.Errorf("MaxUserItems: only user 1 should still refer to item 0, got %v", bird.ItemsToUsers[0])
+	}
+
+	cfg.MaxUserItems = -1
+	if _, err := NewBird(cfg, itemWeights, usersToItems); err == nil {
+		t.Errorf("MaxUserItems: a negative cap should have raised an error but did not")
+	}
+}
+
+func TestBirdInitWorkers(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	numItems, numUsers := 100, 2000
+	itemWeights := make([]float64, numItems)
+	for i := range itemWeights {
+		itemWeights[i] = r.Float64()
+	}
+	usersToItems := make([][]int, numUsers)
+	for u := range usersToItems {
+		usersToItems[u] = r.Perm(numItems)[:r.Intn(20)]
+	}
+
+	var expected []sampler.AliasSampler
+	for _, workers := range []int{1, 3, 0} {
+		cfg := NewBirdCfg()
+		cfg.InitWorkers = workers
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("InitWorkers: %d workers: initialization should not have raised an error but did: %v", workers, err)
+		}
+		if expected == nil {
+			expected = bird.userItemsSamplers
+			continue
+		}
+		for u := range expected {
+			if !reflect.DeepEqual(bird.userItemsSamplers[u].ProbabilityTable, expected[u].ProbabilityTable) ||
+				!reflect.DeepEqual(bird.userItemsSamplers[u].AliasTable, expected[u].AliasTable) {
+				t.Fatalf("InitWorkers: %d workers: the sampler of user %d differs from the serial one", workers, u)
+			}
+		}
+	}
+
+	cfg := NewBirdCfg()
+	cfg.InitWorkers = -1
+	if _, err := NewBird(cfg, itemWeights, usersToItems); err == nil {
+		t.Errorf("InitWorkers: a negative number of workers should have raised an error but did not")
+	}
+}
+
+func TestInspectGraph(t *testing.T) {
+	report := InspectGraph([]float64{1, 1, 1, 1}, [][]int{[]int{0, 2}, []int{}, []int{2}, []int{}})
+	if !reflect.DeepEqual(report.EmptyUsers, []int{1, 3}) {
+		t.Errorf("InspectGraph: expected empty users [1 3], got %v", report.EmptyUsers)
+	}
+	if !reflect.DeepEqual(report.OrphanItems, []int{1, 3}) {
+		t.Errorf("InspectGraph: expected orphan items [1 3], got %v", report.OrphanItems)
+	}
+	if report.OK() {
+		t.Errorf("InspectGraph: the report should not be OK")
+	}
+
+	report = InspectGraph([]float64{1, 1}, [][]int{[]int{0}, []int{1}})
+	if !report.OK() {
+		t.Errorf("InspectGraph: expected a clean report, got %+v", report)
+	}
+}
+
+func TestBirdEdgeWeights(t *testing.T) {
+	cfg := NewBirdCfg()
+	itemWeights := []float64{1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{}}
+
+	bird, err := NewBirdWithEdgeWeights(cfg, itemWeights, usersToItems, [][]float64{[]float64{0, 2}, []float64{}})
+	if err != nil {
+		t.Fatalf("Edge weights: initialization should not have raised an error but did: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		item, err := bird.sampleItem(0)
+		if err != nil {
+			t.Fatalf("Edge weights: sampling should not have raised an error but did: %v", err)
+		}
+		if item != 1 {
+			t.Fatalf("Edge weights: the interaction with item 0 has a zero weight but was sampled")
+		}
+	}
+
+	invalid := [][][]float64{
+		[][]float64{[]float64{1, 1}},
+		[][]float64{[]float64{1}, []float64{}},
+		[][]float64{[]float64{1, -1}, []float64{}},
+	}
+	for _, edgeWeights := range invalid {
+		_, err := NewBirdWithEdgeWeights(cfg, itemWeights, usersToItems, edgeWeights)
+		if err == nil {
+			t.Errorf("Edge weights: %v should have raised an error, got none instead", edgeWeights)
+		}
 	}
 }
 
@@ -144,6 +770,31 @@ func BenchmarkBirdSampleItemsFromQuery1000Query2000000Items(b *testing.B) {
 	benchmarkBirdSampleItemsFromQuery(1000, 2000000, b)
 }
 
+func benchmarkBirdNewQuerySampler(querySize, numItems int, b *testing.B) {
+	itemWeights := make([]float64, numItems)
+	for i := 0; i < numItems; i++ {
+		itemWeights[i] = 10 * rand.Float64()
+	}
+	bird, err := NewBird(NewBirdCfg(), itemWeights, [][]int{[]int{0}})
+	if err != nil {
+		b.Fatalf("Unable to initialize NewQuerySampler benchmark: %v", err)
+	}
+
+	query := make([]QueryItem, querySize)
+	for i := 0; i < querySize; i++ {
+		query[i] = QueryItem{Item: rand.Intn(numItems), Weight: rand.Float64()}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = bird.newQuerySampler(query)
+	}
+}
+
+func BenchmarkBirdNewQuerySampler50Items(b *testing.B) {
+	benchmarkBirdNewQuerySampler(50, 100000, b)
+}
+
 func benchmarkBirdStep(querySize, numUsers, numItems int, b *testing.B) {
 	usersToItems := make([][]int, numUsers)
 	for i := 0; i < numUsers; i++ {
@@ -333,3 +984,111 @@ func BenchmarkBirdProcess5Depth(b *testing.B) {
 func BenchmarkBirdProcess10Depth(b *testing.B) {
 	benchmarkBirdProcess(2000000, 1000000, 100, 10000, 10, b)
 }
+
+// newBenchmarkGraph returns the weights and collections of a synthetic graph
+// where each user interacted with 1 to 100 random items.
+func newBenchmarkGraph(numItems, numUsers int) ([]float64, [][]int) {
+	r := rand.New(rand.NewSource(42))
+
+	itemWeights := make([]float64, numItems)
+	for i := range itemWeights {
+		itemWeights[i] = 10 * r.Float64()
+	}
+
+	usersToItems := make([][]int, numUsers)
+	for u := range usersToItems {
+		items := make([]int, 1+r.Intn(100))
+		for j := range items {
+			items[j] = r.Intn(numItems)
+		}
+		usersToItems[u] = items
+	}
+
+	return itemWeights, usersToItems
+}
+
+func benchmarkNewBird(numItems, numUsers int, b *testing.B) {
+	itemWeights, usersToItems := newBenchmarkGraph(numItems, numUsers)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = NewBird(NewBirdCfg(), itemWeights, usersToItems)
+	}
+}
+
+func BenchmarkNewBird10KUsers(b *testing.B)  { benchmarkNewBird(100000, 10000, b) }
+func BenchmarkNewBird100KUsers(b *testing.B) { benchmarkNewBird(100000, 100000, b) }
+
+// benchmarkBirdProcessAllocs runs Process on a synthetic graph with a query
+// made of items that have been interacted with, so that every call succeeds.
+func benchmarkBirdProcessAllocs(numItems, numUsers, draws, depth int, b *testing.B) {
+	itemWeights, usersToItems := newBenchmarkGraph(numItems, numUsers)
+
+	cfg := NewBirdCfg()
+	cfg.Depth = depth
+	cfg.Draws = draws
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		b.Fatalf("Unable to initialize the Process benchmark: %v", err)
+	}
+
+	query := make([]QueryItem, 100)
+	for i := range query {
+		query[i] = QueryItem{Item: usersToItems[i][0], Weight: 1}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := bird.Process(query); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBirdProcess1KDraws1Depth(b *testing.B) {
+	benchmarkBirdProcessAllocs(100000, 100000, 1000, 1, b)
+}
+
+func BenchmarkBirdProcess1KDraws3Depth(b *testing.B) {
+	benchmarkBirdProcessAllocs(100000, 100000, 1000, 3, b)
+}
+
+func BenchmarkBirdProcess10KDraws1Depth(b *testing.B) {
+	benchmarkBirdProcessAllocs(100000, 100000, 10000, 1, b)
+}
+
+func BenchmarkBirdProcess10KDraws3Depth(b *testing.B) {
+	benchmarkBirdProcessAllocs(100000, 100000, 10000, 3, b)
+}
+
+func BenchmarkBirdProcess10KDraws5Depth(b *testing.B) {
+	benchmarkBirdProcessAllocs(100000, 100000, 10000, 5, b)
+}
+
+func benchmarkBirdSampleItem(collectionSize int, b *testing.B) {
+	itemWeights, _ := newBenchmarkGraph(collectionSize, 0)
+	collection := make([]int, collectionSize)
+	for i := range collection {
+		collection[i] = i
+	}
+	bird, err := NewBird(NewBirdCfg(), itemWeights, [][]int{collection})
+	if err != nil {
+		b.Fatalf("Unable to initialize the sampleItem benchmark: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = bird.sampleItem(0)
+	}
+}
+
+func BenchmarkBirdSampleItem1Item(b *testing.B)        { benchmarkBirdSampleItem(1, b) }
+func BenchmarkBirdSampleItem10Items(b *testing.B)      { benchmarkBirdSampleItem(10, b) }
+func BenchmarkBirdSampleItem1000000Items(b *testing.B) { benchmarkBirdSampleItem(1000000, b) }
+
+func BenchmarkBirdProcess100KDraws3Depth10MEdges(b *testing.B) {
+	benchmarkBirdProcessAllocs(1000000, 200000, 100000, 3, b)
+}
@@ -42,6 +42,13 @@
 // Recommend users based on the same data; - Recommend new songs for a
 // playlist/radio; - Recommend playlists/radio
 //
+// Every ranking of items or users, whether returned by the Recommend
+// functions and methods, Counter.TopN or the methods that return scored items
+// or users, is in descending order of score, those with the same score being
+// sorted by ascending index, and NaN scores coming last. Identical scores
+// thus always give identical results, whatever the order in which the maps
+// they are counted in are iterated.
+//
 //
 // Emu
 //
@@ -0,0 +1,89 @@
+package birdland
+
+import (
+	"time"
+
+	"github.com/rlouf/birdland/sampler"
+)
+
+// maxDistinctDrawsPerItem bounds the number of draws sampleItems makes per
+// distinct item it is asked for, since a collection dominated by a few items
+// can take many draws to yield the others.
+const maxDistinctDrawsPerItem = 10
+
+// ProcessBranching performs random walks from the query like Process, except
+// that at every step each walk draws Cfg.Branching items from the collection
+// of the user it goes through, and continues from each of them. The frontier
+// of the walks is thus multiplied by Cfg.Branching at every step, which finds
+// more items close to the query at the cost of Cfg.Branching^Cfg.Depth times
+// more work; Cfg.MaxVisits bounds it.
+//
+// Items and referrers are aligned as in Process, and ordered by depth: the
+// items visited at a step are those drawn from the users reached at that
+// step, Cfg.Branching per user, in the order of the walks. Walks that reach a
+// dead end are handled according to Cfg.Dangling, a restarted walk
+// continuing from a single item.
+func (b *Bird) ProcessBranching(query []QueryItem) ([]int, []int, error) {
+	if len(query) == 0 {
+		return nil, nil, &EmptyQueryError{}
+	}
+
+	start := time.Now()
+	starts, s, err := b.startWalks(query)
+	if err != nil {
+		return nil, nil, wrap(err, "cannot sample items")
+	}
+
+	w := b.newWalker(b.RandSource, s)
+	if b.Cfg.Branching > 1 {
+		w.branching = b.Cfg.Branching
+	}
+	w.distinct = b.Cfg.BranchingDistinct
+	var items, referrers []int
+	err = w.run(starts, b.Cfg.Depth, func(d int, stepItems, stepReferrers []int) bool {
+		for i, item := range stepItems {
+			if item != deadEnd {
+				items = append(items, item)
+				referrers = append(referrers, stepReferrers[i])
+			}
+		}
+		return !capVisits(b.Cfg.MaxVisits, &items, &referrers)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	b.observeProcess(start, items)
+
+	return items, referrers, nil
+}
+
+// sampleItems appends k items drawn from the collection of user to dst. When
+// distinct is set the items are distinct, and fewer than k of them are
+// appended when the collection does not have enough, or when they could not
+// be found in maxDistinctDrawsPerItem draws per item.
+func (b *Bird) sampleItems(dst []int, user, k int, distinct bool, source sampler.Rand) ([]int, error) {
+	if !distinct {
+		for i := 0; i < k; i++ {
+			item, err := b.sampleItemWith(user, source)
+			if err != nil {
+				return dst, err
+			}
+			dst = append(dst, item)
+		}
+		return dst, nil
+	}
+
+	drawn := make(map[int]bool, k)
+	for i := 0; i < k*maxDistinctDrawsPerItem && len(drawn) < k; i++ {
+		item, err := b.sampleItemWith(user, source)
+		if err != nil {
+			return dst, err
+		}
+		if !drawn[item] {
+			drawn[item] = true
+			dst = append(dst, item)
+		}
+	}
+
+	return dst, nil
+}
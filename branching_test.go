@@ -0,0 +1,65 @@
+package birdland
+
+import (
+	"testing"
+)
+
+func TestBirdProcessBranching(t *testing.T) {
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1, 2}, []int{2, 3}}
+	query := []QueryItem{{Item: 0, Weight: 1}}
+
+	for _, distinct := range []bool{false, true} {
+		cfg := NewBirdCfg()
+		cfg.Draws = 10
+		cfg.Depth = 2
+		cfg.Branching = 3
+		cfg.BranchingDistinct = distinct
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("ProcessBranching: Bird initialization should not have raised an error but did: %v", err)
+		}
+		bird.ReSeed(42)
+
+		items, referrers, err := bird.ProcessBranching(query)
+		if err != nil {
+			t.Fatalf("ProcessBranching: distinct=%v: should not have raised an error but did: %v", distinct, err)
+		}
+		if len(items) != len(referrers) {
+			t.Fatalf("ProcessBranching: distinct=%v: got %d items but %d referrers", distinct, len(items), len(referrers))
+		}
+		if !distinct && len(items) != 10*3+10*3*3 {
+			t.Errorf("ProcessBranching: distinct=%v: expected %d visits, got %d", distinct, 10*3+10*3*3, len(items))
+		}
+		for i, item := range items {
+			if indexOf(usersToItems[referrers[i]], item) < 0 {
+				t.Errorf("ProcessBranching: distinct=%v: item %d does not belong to the collection of its referrer %d", distinct, item, referrers[i])
+			}
+		}
+
+		// The first step draws from user 0 only, whose 3 items are all
+		// drawn from every walk when they are distinct.
+		if distinct {
+			for w := 0; w < cfg.Draws; w++ {
+				seen := make(map[int]bool)
+				for _, item := range items[3*w : 3*(w+1)] {
+					seen[item] = true
+				}
+				if len(seen) != 3 {
+					t.Errorf("ProcessBranching: distinct=%v: expected walk %d to branch to 3 distinct items, got %v", distinct, w, items[3*w:3*(w+1)])
+				}
+			}
+		}
+
+		cfg.MaxVisits = 35
+		if items, _, _ := bird.ProcessBranching(query); len(items) != 35 {
+			t.Errorf("ProcessBranching: distinct=%v: expected the visits to be capped at 35, got %d", distinct, len(items))
+		}
+	}
+
+	cfg := NewBirdCfg()
+	cfg.Branching = -1
+	if _, err := NewBird(cfg, itemWeights, usersToItems); err == nil {
+		t.Errorf("ProcessBranching: a negative branching factor should have been rejected")
+	}
+}
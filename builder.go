@@ -0,0 +1,94 @@
+package birdland
+
+import "fmt"
+
+// BirdBuilder assembles the inputs of NewBird from individual interactions,
+// so callers do not need to aggregate them into adjacency lists themselves.
+// Users and items are identified by the same consecutive integer ids as in
+// NewBird; the largest id seen determines the number of users and items.
+//
+// Items whose weight was not set with SetItemWeight get their number of
+// interactions as weight.
+type BirdBuilder struct {
+	usersToItems [][]int
+	itemWeights  []float64
+	weightSet    []bool
+	counts       []int // number of interactions with each item
+	err          error // first invalid call, returned by Build
+}
+
+// NewBirdBuilder returns an empty builder.
+func NewBirdBuilder() *BirdBuilder {
+	return &BirdBuilder{}
+}
+
+// AddInteraction records that user interacted with item. Repeated
+// interactions are kept, which makes the item proportionally more likely to
+// be drawn from the user's collection.
+func (bb *BirdBuilder) AddInteraction(user, item int) {
+	if bb.err != nil {
+		return
+	}
+	if user < 0 || item < 0 {
+		bb.err = fmt.Errorf("invalid interaction between user %d and item %d", user, item)
+		return
+	}
+
+	bb.growItems(item)
+	for len(bb.usersToItems) <= user {
+		bb.usersToItems = append(bb.usersToItems, []int{})
+	}
+	bb.usersToItems[user] = append(bb.usersToItems[user], item)
+	bb.counts[item]++
+}
+
+// SetItemWeight sets the global weight of item, overriding its default.
+func (bb *BirdBuilder) SetItemWeight(item int, w float64) {
+	if bb.err != nil {
+		return
+	}
+	if item < 0 {
+		bb.err = fmt.Errorf("invalid item %d", item)
+		return
+	}
+	if w < 0 {
+		bb.err = fmt.Errorf("the weight of item %d must be positive", item)
+		return
+	}
+
+	bb.growItems(item)
+	bb.itemWeights[item] = w
+	bb.weightSet[item] = true
+}
+
+// Build creates a Bird from the recorded interactions and weights. The
+// builder hands its data over to the Bird and is empty afterwards. If one of
+// the previous calls was invalid, Build returns the corresponding error.
+func (bb *BirdBuilder) Build(cfg *BirdCfg) (*Bird, error) {
+	if bb.err != nil {
+		return nil, &InvalidInputError{Err: bb.err}
+	}
+
+	for item, set := range bb.weightSet {
+		if !set {
+			bb.itemWeights[item] = float64(bb.counts[item])
+		}
+	}
+
+	b, err := NewBird(cfg, bb.itemWeights, bb.usersToItems)
+	if err != nil {
+		return nil, err
+	}
+	*bb = BirdBuilder{}
+
+	return b, nil
+}
+
+// growItems makes room for item in the per-item slices.
+func (bb *BirdBuilder) growItems(item int) {
+	for len(bb.itemWeights) <= item {
+		bb.itemWeights = append(bb.itemWeights, 0)
+		bb.weightSet = append(bb.weightSet, false)
+		bb.counts = append(bb.counts, 0)
+	}
+}
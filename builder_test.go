@@ -0,0 +1,44 @@
+package birdland
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBirdBuilder(t *testing.T) {
+	builder := NewBirdBuilder()
+	builder.AddInteraction(0, 1)
+	builder.AddInteraction(2, 1)
+	builder.AddInteraction(2, 0)
+	builder.AddInteraction(0, 2)
+	builder.SetItemWeight(2, 0.5)
+	builder.SetItemWeight(3, 4)
+
+	bird, err := builder.Build(NewBirdCfg())
+	if err != nil {
+		t.Fatalf("Builder: Build should not have raised an error but did: %v", err)
+	}
+
+	expectedWeights := []float64{1, 2, 0.5, 4}
+	if !reflect.DeepEqual(bird.ItemWeights, expectedWeights) {
+		t.Errorf("Builder: expected item weights %v, got %v", expectedWeights, bird.ItemWeights)
+	}
+	expectedUsers := [][]int{[]int{1, 2}, []int{}, []int{1, 0}}
+	if !reflect.DeepEqual(bird.UsersToItems, expectedUsers) {
+		t.Errorf("Builder: expected users to items %v, got %v", expectedUsers, bird.UsersToItems)
+	}
+
+	invalid := map[string]func(*BirdBuilder){
+		"Negative user":   func(bb *BirdBuilder) { bb.AddInteraction(-1, 0) },
+		"Negative item":   func(bb *BirdBuilder) { bb.AddInteraction(0, -1) },
+		"Negative weight": func(bb *BirdBuilder) { bb.SetItemWeight(0, -1) },
+	}
+	for name, call := range invalid {
+		builder := NewBirdBuilder()
+		builder.AddInteraction(0, 0)
+		call(builder)
+		if _, err := builder.Build(NewBirdCfg()); err == nil {
+			t.Errorf("Builder: %s: Build should have raised an error but did not", name)
+		}
+	}
+}
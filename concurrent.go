@@ -0,0 +1,91 @@
+package birdland
+
+import (
+	"sync"
+)
+
+// ConcurrentBird makes a Bird safe to query while it is being updated. Queries
+// hold a read lock; updates build the new adjacency lists and samplers on
+// copies without blocking the queries, and only take the write lock to
+// install them.
+//
+// Queries are processed with ProcessSeeded, each with a seed drawn from the
+// Bird's RandSource, so that concurrent queries do not share a random source.
+type ConcurrentBird struct {
+	mu      sync.RWMutex // guards the Bird: read lock for queries, write lock to install changes
+	writeMu sync.Mutex   // serializes the updates, which read the Bird while staging
+	seedMu  sync.Mutex   // guards the RandSource of the Bird
+	bird    *Bird
+}
+
+// NewConcurrentBird wraps b. The Bird must not be used directly afterwards.
+func NewConcurrentBird(b *Bird) *ConcurrentBird {
+	return &ConcurrentBird{bird: b}
+}
+
+// Process performs random walks from the query like Bird.ProcessSeeded, using
+// Cfg.Parallelism workers.
+func (c *ConcurrentBird) Process(query []QueryItem) ([]int, []int, error) {
+	c.seedMu.Lock()
+	seed := c.bird.drawSeed()
+	c.seedMu.Unlock()
+
+	return c.ProcessSeeded(query, seed)
+}
+
+// ProcessSeeded performs random walks from the query like Bird.ProcessSeeded,
+// using Cfg.Parallelism workers.
+func (c *ConcurrentBird) ProcessSeeded(query []QueryItem, seed int64) ([]int, []int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	workers := c.bird.Cfg.Parallelism
+	if workers < 1 {
+		workers = 1
+	}
+
+	return c.bird.ProcessSeeded(query, seed, workers)
+}
+
+// View calls f with the Bird under the read lock, for the read-only methods
+// that ConcurrentBird does not wrap. f must not modify the Bird, nor draw from
+// its RandSource.
+func (c *ConcurrentBird) View(f func(b *Bird)) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	f(c.bird)
+}
+
+// AddInteraction is Bird.AddInteraction.
+func (c *ConcurrentBird) AddInteraction(user, item int) error {
+	return c.update(change{Kind: addInteraction, User: user, Item: item, Weight: 1})
+}
+
+// RemoveInteraction is Bird.RemoveInteraction.
+func (c *ConcurrentBird) RemoveInteraction(user, item int) error {
+	return c.update(change{Kind: removeInteraction, User: user, Item: item})
+}
+
+// SetItemWeight is Bird.SetItemWeight.
+func (c *ConcurrentBird) SetItemWeight(item int, weight float64) error {
+	return c.update(change{Kind: setItemWeight, Item: item, Weight: weight})
+}
+
+// update stages the change, including the new samplers, while queries are
+// still running, then installs it under the write lock.
+func (c *ConcurrentBird) update(ch change) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	staged, err := c.bird.stageChange(ch, true)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.bird.commitChange(staged)
+	c.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package birdland
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestConcurrentBird(t *testing.T) {
+	cfg := NewBirdCfg()
+	cfg.Depth = 2
+	cfg.Draws = 100
+	bird, err := NewBird(cfg, []float64{1, 1, 1, 1}, [][]int{[]int{0, 1}, []int{1, 2}, []int{2, 3}})
+	if err != nil {
+		t.Fatalf("ConcurrentBird: Bird initialization should not have raised an error but did: %v", err)
+	}
+	concurrent := NewConcurrentBird(bird)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				if _, _, err := concurrent.Process([]QueryItem{{Item: 1, Weight: 1}}); err != nil {
+					t.Errorf("ConcurrentBird: Process should not have raised an error but did: %v", err)
+					return
+				}
+				concurrent.View(func(b *Bird) { _ = b.Stats() })
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if err := concurrent.AddInteraction(3+i, i%4); err != nil {
+				t.Errorf("ConcurrentBird: AddInteraction should not have raised an error but did: %v", err)
+				return
+			}
+			if err := concurrent.SetItemWeight(i%4, float64(1+i%3)); err != nil {
+				t.Errorf("ConcurrentBird: SetItemWeight should not have raised an error but did: %v", err)
+				return
+			}
+			if i%2 == 0 {
+				if err := concurrent.RemoveInteraction(3+i, i%4); err != nil {
+					t.Errorf("ConcurrentBird: RemoveInteraction should not have raised an error but did: %v", err)
+					return
+				}
+			}
+		}
+	}()
+	wg.Wait()
+
+	concurrent.View(func(b *Bird) {
+		if len(b.UsersToItems) != 203 {
+			t.Errorf("ConcurrentBird: expected 203 users after the updates, got %d", len(b.UsersToItems))
+		}
+		if b.Version() != 500 {
+			t.Errorf("ConcurrentBird: expected version 500 after the updates, got %d", b.Version())
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package birdland
+
+import (
+	"fmt"
+	"math"
+)
+
+// ConfidentItem is a recommended item along with the mean and the standard
+// deviation of its score over batches of walks.
+type ConfidentItem struct {
+	Item   int
+	Mean   float64
+	StdDev float64
+}
+
+// RecommendItemsWithConfidence returns the n items with the highest mean
+// score for query, along with how much their score varies from one set of
+// walks to another. It performs the walks of ProcessScores once and splits
+// the walks into batches of consecutive walks, which Cfg.Aggregator
+// (or the number of visits if it is nil) scores separately. The score of an
+// item in a batch is divided by the number of walks in the batch, so that a
+// mean multiplied by Cfg.Draws is comparable to the scores of ProcessScores;
+// an item that a batch does not visit scores 0 in it.
+//
+// A standard deviation that is large compared to the mean marks an item whose
+// rank is mostly due to chance. batches must be between 2 and Cfg.Draws, and
+// at most the number of walks performed, which is less than Cfg.Draws when
+// some would start from items no one has interacted with: more batches give
+// a better estimate of the deviation but noisier batches.
+func (b *Bird) RecommendItemsWithConfidence(query []QueryItem, n, batches int) ([]ConfidentItem, error) {
+	draws := b.Cfg.Draws
+	if batches < 2 || batches > draws {
+		return nil, fmt.Errorf("the number of batches must be between 2 and the number of draws %d, got %d", draws, batches)
+	}
+
+	visits, draws, err := b.walkVisits(query)
+	if err != nil {
+		return nil, err
+	}
+	if batches > draws {
+		return nil, fmt.Errorf("the number of batches must be at most the number of walks %d, got %d", draws, batches)
+	}
+
+	aggregator := b.Cfg.Aggregator
+	if aggregator == nil {
+		aggregator = CountAggregator{}
+	}
+
+	// Walk w belongs to batch w*batches/draws, which keeps the visits of
+	// each batch in the order of Process.
+	walks := make([]int, batches)
+	for w := 0; w < draws; w++ {
+		walks[w*batches/draws]++
+	}
+	batchVisits := make([][]Visit, batches)
+	for _, v := range visits {
+		k := v.Walk * batches / draws
+		batchVisits[k] = append(batchVisits[k], v)
+	}
+
+	sums := make(map[int]float64)
+	sumSquares := make(map[int]float64)
+	for k, bv := range batchVisits {
+		for item, score := range aggregator.Aggregate(bv) {
+			if math.IsNaN(score) {
+				return nil, fmt.Errorf("the aggregator gave item %d a NaN score", item)
+			}
+			x := score / float64(walks[k])
+			sums[item] += x
+			sumSquares[item] += x * x
+		}
+	}
+
+	B := float64(batches)
+	means := make(map[int]float64, len(sums))
+	for item, sum := range sums {
+		means[item] = sum / B
+	}
+	ranked := rankItems(means, n)
+	items := make([]ConfidentItem, len(ranked))
+	for i, s := range ranked {
+		variance := (sumSquares[s.Item] - B*s.Score*s.Score) / (B - 1)
+		items[i] = ConfidentItem{Item: s.Item, Mean: s.Score, StdDev: math.Sqrt(math.Max(variance, 0))}
+	}
+
+	return items, nil
+}
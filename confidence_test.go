@@ -0,0 +1,51 @@
+package birdland
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBirdRecommendItemsWithConfidence(t *testing.T) {
+	// With a depth of 1 every walk visits either item 0 or item 1, with the
+	// same probability, so that the number of visits of item 1 in a batch of
+	// 100 walks has a standard deviation of 0.05 per walk.
+	itemWeights := []float64{1, 1}
+	usersToItems := [][]int{[]int{0, 1}}
+	cfg := NewBirdCfg()
+	cfg.Depth = 1
+	cfg.Draws = 1000
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("RecommendItemsWithConfidence: Bird initialization should not have raised an error but did: %v", err)
+	}
+	bird.ReSeed(42)
+	query := []QueryItem{{Item: 0, Weight: 1}}
+
+	items, err := bird.RecommendItemsWithConfidence(query, 5, 10)
+	if err != nil {
+		t.Fatalf("RecommendItemsWithConfidence: should not have raised an error but did: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("RecommendItemsWithConfidence: expected 2 items, got %v", items)
+	}
+	if math.Abs(items[0].Mean+items[1].Mean-1) > 1e-12 {
+		t.Errorf("RecommendItemsWithConfidence: expected the means to sum to 1 visit per walk, got %v", items)
+	}
+	if math.Abs(items[0].StdDev-items[1].StdDev) > 1e-12 {
+		t.Errorf("RecommendItemsWithConfidence: expected both items to have the same standard deviation, got %v", items)
+	}
+	if items[0].StdDev < 0.02 || items[0].StdDev > 0.1 {
+		t.Errorf("RecommendItemsWithConfidence: expected a standard deviation close to 0.05, got %v", items[0].StdDev)
+	}
+
+	bird.ReSeed(42)
+	if top, _ := bird.RecommendItemsWithConfidence(query, 1, 10); len(top) != 1 || top[0] != items[0] {
+		t.Errorf("RecommendItemsWithConfidence: expected only the first item, got %v", top)
+	}
+	for _, batches := range []int{1, cfg.Draws + 1} {
+		if _, err := bird.RecommendItemsWithConfidence(query, 5, batches); err == nil {
+			t.Errorf("RecommendItemsWithConfidence: %d batches should have been rejected", batches)
+		}
+	}
+}
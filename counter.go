@@ -0,0 +1,113 @@
+package birdland
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// Counter accumulates scores of items, such as their number of visits, and
+// extracts the items with the highest scores. The zero value is ready to
+// use, and Reset empties it for reuse without reallocating. A Counter is not
+// safe for concurrent use.
+type Counter struct {
+	scores map[int]float64
+}
+
+// Add counts one more occurence of each of items.
+func (c *Counter) Add(items []int) {
+	if c.scores == nil {
+		c.scores = make(map[int]float64)
+	}
+	for _, item := range items {
+		c.scores[item]++
+	}
+}
+
+// AddWeighted adds w to the score of item.
+func (c *Counter) AddWeighted(item int, w float64) {
+	if c.scores == nil {
+		c.scores = make(map[int]float64)
+	}
+	c.scores[item] += w
+}
+
+// Len returns the number of distinct items counted.
+func (c *Counter) Len() int {
+	return len(c.scores)
+}
+
+// Reset forgets all the scores.
+func (c *Counter) Reset() {
+	for item := range c.scores {
+		delete(c.scores, item)
+	}
+}
+
+// TopN returns the n items with the highest scores and their scores, in
+// descending order of score, items with the same score being sorted by
+// ascending index. All items are returned when there are fewer than n of
+// them. It takes O(m log n) for m distinct items.
+func (c *Counter) TopN(n int) ([]int, []float64) {
+	return topScores(c.scores, n)
+}
+
+// topScores returns the n items with the highest scores as described by
+// Counter.TopN. Only the n best items seen so far are kept in a heap whose
+// root is the worst of them.
+func topScores(scores map[int]float64, n int) ([]int, []float64) {
+	if n > len(scores) {
+		n = len(scores)
+	}
+	if n <= 0 {
+		return []int{}, []float64{}
+	}
+
+	top := make(scoreHeap, 0, n)
+	for item, score := range scores {
+		s := ScoredItem{Item: item, Score: score}
+		if len(top) < n {
+			heap.Push(&top, s)
+		} else if ranksBefore(s, top[0]) {
+			top[0] = s
+			heap.Fix(&top, 0)
+		}
+	}
+
+	sort.Slice(top, func(i, j int) bool { return ranksBefore(top[i], top[j]) })
+	items := make([]int, len(top))
+	values := make([]float64, len(top))
+	for i, s := range top {
+		items[i], values[i] = s.Item, s.Score
+	}
+
+	return items, values
+}
+
+// ranksBefore tells whether a comes before b in descending order of score,
+// ties being resolved by ascending item. NaN scores rank after all the others
+// so that the order stays total.
+func ranksBefore(a, b ScoredItem) bool {
+	if aNaN, bNaN := math.IsNaN(a.Score), math.IsNaN(b.Score); aNaN || bNaN {
+		if aNaN != bNaN {
+			return bNaN
+		}
+	} else if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.Item < b.Item
+}
+
+// scoreHeap is a heap of scored items whose root ranks last.
+type scoreHeap []ScoredItem
+
+func (h scoreHeap) Len() int            { return len(h) }
+func (h scoreHeap) Less(i, j int) bool  { return ranksBefore(h[j], h[i]) }
+func (h scoreHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *scoreHeap) Push(x interface{}) { *h = append(*h, x.(ScoredItem)) }
+func (h *scoreHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
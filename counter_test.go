@@ -0,0 +1,65 @@
+package birdland
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	var c Counter
+	c.Add([]int{3, 1, 3, 2, 3, 1})
+	c.AddWeighted(7, 2)
+	c.AddWeighted(1, 0.5)
+
+	items, scores := c.TopN(3)
+	if !reflect.DeepEqual(items, []int{3, 1, 7}) || !reflect.DeepEqual(scores, []float64{3, 2.5, 2}) {
+		t.Errorf("Counter: expected items [3 1 7] with scores [3 2.5 2], got %v with %v", items, scores)
+	}
+	if items, _ := c.TopN(10); len(items) != 4 {
+		t.Errorf("Counter: expected the 4 items counted, got %v", items)
+	}
+	if items, _ := c.TopN(0); len(items) != 0 {
+		t.Errorf("Counter: expected no items, got %v", items)
+	}
+
+	c.Reset()
+	if c.Len() != 0 {
+		t.Fatalf("Counter: expected Reset to forget the %d items", c.Len())
+	}
+	c.Add([]int{5, 4, 4, 5, 6})
+	if items, _ := c.TopN(2); !reflect.DeepEqual(items, []int{4, 5}) {
+		t.Errorf("Counter: expected tied items in ascending order [4 5] after Reset, got %v", items)
+	}
+
+	// TopN agrees with a full sort.
+	r := rand.New(rand.NewSource(42))
+	c.Reset()
+	for i := 0; i < 10000; i++ {
+		c.AddWeighted(r.Intn(1000), float64(r.Intn(20)))
+	}
+	var all []ScoredItem
+	for item, score := range c.scores {
+		all = append(all, ScoredItem{Item: item, Score: score})
+	}
+	sort.Slice(all, func(i, j int) bool { return ranksBefore(all[i], all[j]) })
+	items, scores = c.TopN(50)
+	for i := range items {
+		if items[i] != all[i].Item || scores[i] != all[i].Score {
+			t.Fatalf("Counter: item %d of the top 50 is %d with %v, expected %d with %v", i, items[i], scores[i], all[i].Item, all[i].Score)
+		}
+	}
+}
+
+func BenchmarkCounterTop50Of2M(b *testing.B) {
+	var c Counter
+	for item := 0; item < 2000000; item++ {
+		c.AddWeighted(item, float64(item%1000))
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = c.TopN(50)
+	}
+}
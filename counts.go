@@ -0,0 +1,53 @@
+package birdland
+
+// ProcessCounts performs the same random walks as Process but returns, instead
+// of the visits themselves, the number of times each item was visited. See
+// ProcessCountsWithReferrers.
+func (b *Bird) ProcessCounts(query []QueryItem) (map[int]int, error) {
+	itemCounts, _, err := b.ProcessCountsWithReferrers(query)
+
+	return itemCounts, err
+}
+
+// ProcessCountsWithReferrers performs the same random walks as Process but
+// returns, instead of the visits themselves, the number of times each item
+// was visited and the number of times each user was a referrer. The walks
+// advance in lockstep and only the current step is kept in memory, so the
+// memory used is proportional to Cfg.Draws plus the number of distinct items
+// and users visited, instead of Cfg.Draws times Cfg.Depth. Given the same
+// state of RandSource, the counts are those of the output of a serial
+// Process. The walks are always performed serially, whatever
+// Cfg.Parallelism.
+func (b *Bird) ProcessCountsWithReferrers(query []QueryItem) (map[int]int, map[int]int, error) {
+	if len(query) == 0 {
+		return nil, nil, &EmptyQueryError{}
+	}
+
+	starts, s, err := b.startWalks(query)
+	if err != nil {
+		return nil, nil, wrap(err, "cannot sample items")
+	}
+
+	itemCounts := make(map[int]int)
+	userCounts := make(map[int]int)
+	visits := 0
+	err = b.newWalker(b.RandSource, s).run(starts, b.walkDepth(len(starts)), func(d int, items, referrers []int) bool {
+		for i, item := range items {
+			if b.Cfg.MaxVisits > 0 && visits == b.Cfg.MaxVisits {
+				return false
+			}
+			if item == deadEnd {
+				continue
+			}
+			itemCounts[item]++
+			userCounts[referrers[i]]++
+			visits++
+		}
+		return true
+	})
+	if err != nil {
+		return nil, nil, wrap(err, "cannot step through items")
+	}
+
+	return itemCounts, userCounts, nil
+}
@@ -0,0 +1,52 @@
+package birdland
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBirdProcessCounts(t *testing.T) {
+	itemWeights := []float64{1, 2, 3, 4}
+	usersToItems := [][]int{[]int{0, 1, 2}, []int{1, 3}, []int{0, 2, 3}}
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 3, Weight: 2}}
+
+	for _, maxVisits := range []int{0, 250} {
+		cfg := NewBirdCfg()
+		cfg.Depth = 3
+		cfg.Draws = 100
+		cfg.MaxVisits = maxVisits
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("ProcessCounts: Bird initialization should not have raised an error but did: %v", err)
+		}
+
+		bird.ReSeed(42)
+		items, referrers, err := bird.Process(query)
+		if err != nil {
+			t.Fatalf("ProcessCounts: Process should not have raised an error but did: %v", err)
+		}
+		expectedItems, expectedUsers := make(map[int]int), make(map[int]int)
+		for i := range items {
+			expectedItems[items[i]]++
+			expectedUsers[referrers[i]]++
+		}
+
+		bird.ReSeed(42)
+		itemCounts, userCounts, err := bird.ProcessCountsWithReferrers(query)
+		if err != nil {
+			t.Fatalf("ProcessCounts: should not have raised an error but did: %v", err)
+		}
+		if !reflect.DeepEqual(itemCounts, expectedItems) {
+			t.Errorf("ProcessCounts: MaxVisits %d: expected item counts %v, got %v", maxVisits, expectedItems, itemCounts)
+		}
+		if !reflect.DeepEqual(userCounts, expectedUsers) {
+			t.Errorf("ProcessCounts: MaxVisits %d: expected user counts %v, got %v", maxVisits, expectedUsers, userCounts)
+		}
+
+		bird.ReSeed(42)
+		itemCounts, err = bird.ProcessCounts(query)
+		if err != nil || !reflect.DeepEqual(itemCounts, expectedItems) {
+			t.Errorf("ProcessCounts: MaxVisits %d: expected item counts %v, got %v (error: %v)", maxVisits, expectedItems, itemCounts, err)
+		}
+	}
+}
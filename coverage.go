@@ -0,0 +1,31 @@
+package birdland
+
+import (
+	"github.com/pkg/errors"
+)
+
+// CatalogCoverage returns the fraction of the catalog that appears in the
+// topN items recommended by ProcessScores for at least one of queries. It is
+// meant for offline evaluation on a sample of queries: a low coverage means
+// that the recommendations concentrate on a few, usually popular, items.
+func (b *Bird) CatalogCoverage(queries [][]QueryItem, topN int) (float64, error) {
+	if len(queries) == 0 {
+		return 0, errors.New("no queries to evaluate the coverage with")
+	}
+	if topN < 1 {
+		return 0, errors.New("the number of recommended items must be at least 1")
+	}
+
+	covered := make(map[int]bool)
+	for i, query := range queries {
+		scored, err := b.ProcessScores(query)
+		if err != nil {
+			return 0, wrapf(err, "cannot process query %d", i)
+		}
+		for _, s := range scored[:minInt(topN, len(scored))] {
+			covered[s.Item] = true
+		}
+	}
+
+	return float64(len(covered)) / float64(len(b.ItemWeights)), nil
+}
@@ -0,0 +1,35 @@
+package birdland
+
+import "testing"
+
+func TestBirdCatalogCoverage(t *testing.T) {
+	itemWeights := []float64{1, 1, 1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{2, 3}, []int{4}}
+	bird, err := NewBird(NewBirdCfg(), itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("CatalogCoverage: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	// Walks from items 0 and 2 only reach items 0 to 3.
+	queries := [][]QueryItem{{{Item: 0, Weight: 1}}, {{Item: 2, Weight: 1}}}
+	cases := map[int]float64{1: 0.4, 2: 0.8, 10: 0.8}
+	for topN, expected := range cases {
+		coverage, err := bird.CatalogCoverage(queries, topN)
+		if err != nil {
+			t.Fatalf("CatalogCoverage: should not have raised an error but did: %v", err)
+		}
+		if coverage != expected {
+			t.Errorf("CatalogCoverage: expected a coverage of %v for the top %d items, got %v", expected, topN, coverage)
+		}
+	}
+
+	if _, err := bird.CatalogCoverage(nil, 1); err == nil {
+		t.Errorf("CatalogCoverage: no queries should have raised an error but did not")
+	}
+	if _, err := bird.CatalogCoverage(queries, 0); err == nil {
+		t.Errorf("CatalogCoverage: a top 0 should have raised an error but did not")
+	}
+	if _, err := bird.CatalogCoverage([][]QueryItem{{{Item: 7, Weight: 1}}}, 1); err == nil {
+		t.Errorf("CatalogCoverage: an invalid query should have raised an error but did not")
+	}
+}
@@ -0,0 +1,63 @@
+package birdland
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rlouf/birdland/sampler"
+)
+
+// DanglingPolicy tells what happens to a random walk that reaches a dead end,
+// i.e. an item no one has interacted with or a user with an empty collection.
+// Such dead ends do not appear in a consistent graph, but can be found in
+// sparse or partially pruned ones.
+type DanglingPolicy int
+
+const (
+	// DanglingFail aborts the whole processing with an error. This is the
+	// default.
+	DanglingFail DanglingPolicy = iota
+	// DanglingDrop stops the walk; the items it visited so far are kept.
+	DanglingDrop
+	// DanglingRestart restarts the walk from an item drawn from the query.
+	// The walk is dropped if no item of the query can be stepped from.
+	DanglingRestart
+)
+
+// deadEnd marks the visits of a walk that has been dropped.
+const deadEnd = -1
+
+// maxRestarts is the number of items drawn from the query to restart a walk
+// before giving up and dropping it.
+const maxRestarts = 100
+
+// restartWalk draws items from the query until a step can be performed from
+// one of them, and returns the item and the user reached by this step. It
+// returns an error when no such item was found after maxRestarts draws.
+func (b *Bird) restartWalk(s *querySampler, rng sampler.Rand) (int, int, error) {
+	if s == nil {
+		return 0, 0, errors.New("cannot restart the walk: there is no query")
+	}
+
+	for i := 0; i < maxRestarts; i++ {
+		item, user, err := b.walkStep(s.sample(rng), rng)
+		if err == nil {
+			return item, user, nil
+		}
+	}
+
+	return 0, 0, errors.Errorf("cannot restart the walk: no step could be performed from %d items of the query", maxRestarts)
+}
+
+// dropDeadEnds removes, in place, the visits of the walks that were dropped.
+func dropDeadEnds(items, referrers *[]int) {
+	kept := 0
+	for i, item := range *items {
+		if item == deadEnd {
+			continue
+		}
+		(*items)[kept] = item
+		(*referrers)[kept] = (*referrers)[i]
+		kept++
+	}
+	*items = (*items)[:kept]
+	*referrers = (*referrers)[:kept]
+}
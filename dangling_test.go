@@ -0,0 +1,78 @@
+package birdland
+
+import (
+	"testing"
+)
+
+func TestBirdDanglingPolicy(t *testing.T) {
+	itemWeights := []float64{1, 1, 1}
+	usersToItems := [][]int{
+		[]int{0, 1},
+		[]int{1, 2},
+	}
+	query := []QueryItem{{Item: 0, Weight: 1}}
+
+	cases := []struct {
+		policy      DanglingPolicy
+		parallelism int
+		fails       bool
+		full        bool // every walk reaches the full depth
+	}{
+		{DanglingFail, 1, true, false},
+		{DanglingDrop, 1, false, false},
+		{DanglingRestart, 1, false, true},
+		{DanglingFail, 2, true, false},
+		{DanglingDrop, 2, false, false},
+		{DanglingRestart, 2, false, true},
+	}
+	for _, c := range cases {
+		cfg := NewBirdCfg()
+		cfg.Depth = 4
+		cfg.Draws = 100
+		cfg.Dangling = c.policy
+		cfg.Parallelism = c.parallelism
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("Dangling: Bird initialization should not have raised an error but did: %v", err)
+		}
+		// pretend item 2 was pruned from the item-user lists only, so that
+		// walks reaching it are stuck.
+		bird.ItemsToUsers[2] = []int{}
+
+		items, referrers, err := bird.Process(query)
+		if c.fails {
+			if err == nil {
+				t.Errorf("Dangling: policy %d with parallelism %d should have raised an error but did not", c.policy, c.parallelism)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Dangling: policy %d with parallelism %d should not have raised an error but did: %v", c.policy, c.parallelism, err)
+		}
+		if len(items) != len(referrers) {
+			t.Errorf("Dangling: policy %d: got %d items but %d referrers", c.policy, len(items), len(referrers))
+		}
+		if full := len(items) == cfg.Depth*cfg.Draws; full != c.full {
+			t.Errorf("Dangling: policy %d with parallelism %d: got %d visits out of %d", c.policy, c.parallelism, len(items), cfg.Depth*cfg.Draws)
+		}
+		for i, item := range items {
+			if item == deadEnd || referrers[i] == deadEnd {
+				t.Fatalf("Dangling: policy %d: the visits of dropped walks should be left out", c.policy)
+			}
+		}
+
+		itemCounts, err := bird.ProcessCounts(query)
+		if err != nil {
+			t.Fatalf("Dangling: ProcessCounts with policy %d should not have raised an error but did: %v", c.policy, err)
+		}
+		if _, ok := itemCounts[deadEnd]; ok {
+			t.Errorf("Dangling: ProcessCounts with policy %d counted dropped walks", c.policy)
+		}
+	}
+
+	cfg := NewBirdCfg()
+	cfg.Dangling = DanglingRestart + 1
+	if _, err := NewBird(cfg, itemWeights, usersToItems); err == nil {
+		t.Errorf("Dangling: an unknown policy should have raised an error but did not")
+	}
+}
@@ -0,0 +1,153 @@
+package birdland
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Binary format of a delta, little-endian as the full format written by Save:
+//
+//	magic     "BDLT"
+//	version   1 byte
+//	versions  the version the delta applies to and the version it leads to
+//	changes   number of changes, then for each change its kind, user, item
+//	          and weight
+//	checksum  CRC-32 (IEEE) of everything above, 4 bytes
+var deltaMagic = []byte("BDLT")
+
+const deltaFormatVersion byte = 1
+
+// SaveDelta writes the changes made to the Bird since version since, so that
+// a Bird loaded from a snapshot taken at that version can catch up with
+// ApplyDelta. Changes are kept in memory until they are discarded with
+// DiscardChanges.
+func (b *Bird) SaveDelta(w io.Writer, since Version) error {
+	if since > b.version {
+		return errors.Errorf("version %d is ahead of the bird (version %d)", since, b.version)
+	}
+	if since < b.changesFrom {
+		return errors.Errorf("changes before version %d were discarded", b.changesFrom)
+	}
+
+	bw := bufio.NewWriter(w)
+	crc := crc32.NewIEEE()
+	enc := &encoder{w: io.MultiWriter(bw, crc)}
+
+	enc.writeBytes(deltaMagic)
+	enc.writeBytes([]byte{deltaFormatVersion})
+	enc.writeUint64(uint64(since))
+	enc.writeUint64(uint64(b.version))
+
+	changes := b.changes[since-b.changesFrom:]
+	enc.writeInt(len(changes))
+	for _, c := range changes {
+		enc.writeInt(int(c.Kind))
+		enc.writeInt(c.User)
+		enc.writeInt(c.Item)
+		enc.writeFloat(c.Weight)
+	}
+	if enc.err != nil {
+		return errors.Wrap(enc.err, "cannot write delta")
+	}
+
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc.Sum32())
+	if _, err := bw.Write(sum[:]); err != nil {
+		return errors.Wrap(err, "cannot write checksum")
+	}
+
+	return bw.Flush()
+}
+
+// ApplyDelta applies a delta written by SaveDelta. The delta must start at
+// the current version of the Bird: deltas applied out of order are rejected.
+// Only the samplers of the users affected by the changes are rebuilt. The
+// delta is applied as a whole: if a change turns out to be invalid or a
+// sampler cannot be rebuilt, the changes already applied are reverted and the
+// Bird is left as it was.
+func (b *Bird) ApplyDelta(r io.Reader) error {
+	crc := crc32.NewIEEE()
+	dec := &decoder{r: io.TeeReader(bufio.NewReader(r), crc)}
+
+	header := dec.readBytes(len(deltaMagic) + 1)
+	if dec.err != nil {
+		return errors.Wrap(dec.err, "cannot read header")
+	}
+	if !bytes.Equal(header[:len(deltaMagic)], deltaMagic) {
+		return errors.New("not a delta file")
+	}
+	if v := header[len(deltaMagic)]; v != deltaFormatVersion {
+		return errors.Errorf("unsupported format version %d", v)
+	}
+
+	from := Version(dec.readUint64())
+	to := Version(dec.readUint64())
+	numChanges := dec.readLength()
+	changes := make([]change, 0, minInt(numChanges, chunkSize))
+	for i := 0; i < numChanges && dec.err == nil; i++ {
+		changes = append(changes, change{
+			Kind:   changeKind(dec.readInt()),
+			User:   dec.readInt(),
+			Item:   dec.readInt(),
+			Weight: dec.readFloat(),
+		})
+	}
+	if dec.err != nil {
+		return errors.Wrap(dec.err, "cannot read delta")
+	}
+
+	expected := crc.Sum32()
+	sum := dec.readBytes(4)
+	if dec.err != nil {
+		return errors.Wrap(dec.err, "cannot read checksum")
+	}
+	if binary.LittleEndian.Uint32(sum) != expected {
+		return errors.New("checksum mismatch, the delta is corrupted")
+	}
+
+	if from != b.version {
+		return errors.Errorf("the delta applies to version %d but the bird is at version %d", from, b.version)
+	}
+	if to != from+Version(len(changes)) {
+		return errors.Errorf("the delta should contain %d changes, got %d", to-from, len(changes))
+	}
+
+	// The samplers are built once all the changes are committed, since the
+	// same users may be affected by many of them, and only installed once
+	// they are all built.
+	committed := make([]*stagedChange, 0, len(changes))
+	affected := make(map[int]bool)
+	for i, c := range changes {
+		staged, err := b.stageChange(c, false)
+		if err != nil {
+			b.revertChanges(committed)
+			return wrapf(err, "cannot apply change %d", i)
+		}
+		b.commitChange(staged)
+		committed = append(committed, staged)
+		for _, user := range staged.affected {
+			affected[user] = true
+		}
+	}
+
+	samplers, err := b.stageSamplers(affected)
+	if err != nil {
+		b.revertChanges(committed)
+		return err
+	}
+	b.commitSamplers(samplers)
+
+	return nil
+}
+
+// revertChanges reverts the committed changes, the last one first.
+func (b *Bird) revertChanges(committed []*stagedChange) {
+	for i := len(committed) - 1; i >= 0; i-- {
+		b.revertChange(committed[i])
+	}
+}
@@ -0,0 +1,114 @@
+package birdland
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestBirdDelta(t *testing.T) {
+	bird := newPersistTestBird(t)
+
+	var snapshot bytes.Buffer
+	if err := bird.Save(&snapshot); err != nil {
+		t.Fatalf("Delta: Save should not have raised an error but did: %v", err)
+	}
+	replica, err := LoadBird(&snapshot)
+	if err != nil {
+		t.Fatalf("Delta: LoadBird should not have raised an error but did: %v", err)
+	}
+	since := bird.Version()
+
+	bird.AddInteraction(1, 3)
+	bird.AddInteraction(4, 0)
+	bird.RemoveInteraction(2, 1)
+	bird.SetItemWeight(3, 10)
+
+	var delta bytes.Buffer
+	if err := bird.SaveDelta(&delta, since); err != nil {
+		t.Fatalf("Delta: SaveDelta should not have raised an error but did: %v", err)
+	}
+	data := delta.Bytes()
+
+	if err := replica.ApplyDelta(bytes.NewReader(data)); err != nil {
+		t.Fatalf("Delta: ApplyDelta should not have raised an error but did: %v", err)
+	}
+	if replica.Version() != bird.Version() {
+		t.Errorf("Delta: expected version %d, got %d", bird.Version(), replica.Version())
+	}
+	if !reflect.DeepEqual(replica.ItemWeights, bird.ItemWeights) {
+		t.Errorf("Delta: expected weights %v, got %v", bird.ItemWeights, replica.ItemWeights)
+	}
+	if !reflect.DeepEqual(replica.UsersToItems, bird.UsersToItems) {
+		t.Errorf("Delta: expected users to items %v, got %v", bird.UsersToItems, replica.UsersToItems)
+	}
+	if !reflect.DeepEqual(replica.ItemsToUsers, bird.ItemsToUsers) {
+		t.Errorf("Delta: expected items to users %v, got %v", bird.ItemsToUsers, replica.ItemsToUsers)
+	}
+	for u := range bird.userItemsSamplers {
+		if !reflect.DeepEqual(replica.userItemsSamplers[u].ProbabilityTable, bird.userItemsSamplers[u].ProbabilityTable) {
+			t.Errorf("Delta: the sampler of user %d differs", u)
+		}
+	}
+
+	if err := replica.ApplyDelta(bytes.NewReader(data)); err == nil {
+		t.Errorf("Delta: applying the same delta twice should have raised an error but did not")
+	}
+
+	corrupted := append([]byte{}, data...)
+	corrupted[len(corrupted)/2] ^= 1
+	if err := bird.ApplyDelta(bytes.NewReader(corrupted)); err == nil {
+		t.Errorf("Delta: applying a corrupted delta should have raised an error but did not")
+	}
+
+	bird.DiscardChanges(bird.Version())
+	if err := bird.SaveDelta(&delta, since); err == nil {
+		t.Errorf("Delta: saving discarded changes should have raised an error but did not")
+	}
+}
+
+func TestBirdDeltaFailure(t *testing.T) {
+	invalidChange := []change{
+		{Kind: addInteraction, User: 4, Item: 0, Weight: 1},
+		{Kind: addInteraction, User: 1, Item: 3, Weight: 1},
+		{Kind: removeInteraction, User: 1, Item: 2},
+	}
+	// User 0 is left with items of zero weight only, so that their sampler
+	// cannot be rebuilt once the changes are applied.
+	invalidSampler := []change{
+		{Kind: addInteraction, User: 4, Item: 0, Weight: 1},
+		{Kind: setItemWeight, Item: 0, Weight: 0},
+		{Kind: setItemWeight, Item: 1, Weight: 0},
+	}
+
+	for _, changes := range [][]change{invalidChange, invalidSampler} {
+		bird := newPersistTestBird(t)
+		replica := newPersistTestBird(t)
+		bird.changes = append(bird.changes, changes...)
+		bird.version += Version(len(changes))
+		var delta bytes.Buffer
+		if err := bird.SaveDelta(&delta, 0); err != nil {
+			t.Fatalf("DeltaFailure: SaveDelta should not have raised an error but did: %v", err)
+		}
+
+		itemWeights := append([]float64{}, replica.ItemWeights...)
+		usersToItems := append([][]int{}, replica.UsersToItems...)
+		itemsToUsers := append([][]int{}, replica.ItemsToUsers...)
+		if err := replica.ApplyDelta(&delta); err == nil {
+			t.Fatalf("DeltaFailure: ApplyDelta should have raised an error but did not")
+		}
+		if replica.Version() != 0 || len(replica.changes) != 0 {
+			t.Errorf("DeltaFailure: expected the bird to stay at version 0, got %d", replica.Version())
+		}
+		if !reflect.DeepEqual(replica.ItemWeights, itemWeights) ||
+			!reflect.DeepEqual(replica.UsersToItems, usersToItems) ||
+			!reflect.DeepEqual(replica.ItemsToUsers, itemsToUsers) ||
+			len(replica.userItemsSamplers) != len(usersToItems) {
+			t.Errorf("DeltaFailure: expected the bird to be left as it was, got weights %v and users to items %v",
+				replica.ItemWeights, replica.UsersToItems)
+		}
+		if _, _, err := replica.Process([]QueryItem{{Item: 0, Weight: 1}}); err != nil {
+			t.Errorf("DeltaFailure: Process should not have raised an error but did: %v", err)
+		}
+	}
+}
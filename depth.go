@@ -0,0 +1,24 @@
+package birdland
+
+import "github.com/rlouf/birdland/sampler"
+
+// DepthMode tells how the length of the random walks is chosen.
+type DepthMode int
+
+const (
+	// DepthFixed makes every walk Cfg.Depth steps long. This is the
+	// default.
+	DepthFixed DepthMode = iota
+	// DepthGeometric makes a walk perform each step after the first with
+	// probability Cfg.Continuation, so that its length follows a geometric
+	// distribution of mean 1/(1-Cfg.Continuation), as the walks of
+	// PageRank. Cfg.Depth still caps the length, which brings the mean down
+	// to (1-Cfg.Continuation^Cfg.Depth)/(1-Cfg.Continuation).
+	DepthGeometric
+)
+
+// continueWalk tells whether a walk that has performed d steps performs
+// another one, drawing from rng with DepthGeometric.
+func (b *Bird) continueWalk(d int, rng sampler.Rand) bool {
+	return d == 0 || b.Cfg.DepthMode == DepthFixed || rng.Float64() < b.Cfg.Continuation
+}
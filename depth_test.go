@@ -0,0 +1,65 @@
+package birdland
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBirdGeometricDepth(t *testing.T) {
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 2}, []int{2, 3}, []int{3, 0}}
+	query := []QueryItem{{Item: 0, Weight: 1}}
+	cfg := NewBirdCfg()
+	cfg.Depth = 50
+	cfg.Draws = 20000
+	cfg.DepthMode = DepthGeometric
+
+	// The length of a walk follows a geometric distribution of mean
+	// 1/(1-p) and standard deviation sqrt(p)/(1-p), truncated at Depth.
+	for _, p := range []float64{0, 0.5, 0.8} {
+		cfg.Continuation = p
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("GeometricDepth: Bird initialization should not have raised an error but did: %v", err)
+		}
+		bird.ReSeed(42)
+		expected := (1 - math.Pow(p, float64(cfg.Depth))) / (1 - p)
+		tolerance := 5 * math.Sqrt(p) / (1 - p) / math.Sqrt(float64(cfg.Draws))
+
+		for _, parallelism := range []int{1, 4} {
+			cfg.Parallelism = parallelism
+			items, _, err := bird.Process(query)
+			if err != nil {
+				t.Fatalf("GeometricDepth: Process should not have raised an error but did: %v", err)
+			}
+			mean := float64(len(items)) / float64(cfg.Draws)
+			if math.Abs(mean-expected) > tolerance {
+				t.Errorf("GeometricDepth: with a continuation probability of %v and a parallelism of %d, expected walks of mean length %v, got %v", p, parallelism, expected, mean)
+			}
+		}
+		cfg.Parallelism = 0
+
+		counts, err := bird.ProcessCounts(query)
+		if err != nil {
+			t.Fatalf("GeometricDepth: ProcessCounts should not have raised an error but did: %v", err)
+		}
+		var visits int
+		for _, n := range counts {
+			visits += n
+		}
+		if mean := float64(visits) / float64(cfg.Draws); math.Abs(mean-expected) > tolerance {
+			t.Errorf("GeometricDepth: with a continuation probability of %v, expected ProcessCounts to count walks of mean length %v, got %v", p, expected, mean)
+		}
+	}
+
+	for _, p := range []float64{-0.1, 1, math.NaN()} {
+		cfg.Continuation = p
+		if _, err := NewBird(cfg, itemWeights, usersToItems); err == nil {
+			t.Errorf("GeometricDepth: a continuation probability of %v should have been rejected", p)
+		}
+	}
+	cfg.DepthMode = DepthMode(2)
+	if _, err := NewBird(cfg, itemWeights, usersToItems); err == nil {
+		t.Errorf("GeometricDepth: an unknown depth mode should have been rejected")
+	}
+}
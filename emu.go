@@ -1,9 +1,6 @@
 package birdland
 
 import (
-	"math/rand"
-	"time"
-
 	"github.com/pkg/errors"
 	"github.com/rlouf/birdland/sampler"
 )
@@ -11,36 +8,31 @@ import (
 // NewEmu creates a new recommender from input data. Unlike Bird, the
 // user-to-item bipartite graph is a weighted graph.
 func NewEmu(cfg *BirdCfg, itemWeights []float64, usersToWeightedItems []map[int]float64) (*Bird, error) {
-	if cfg.Depth < 1 {
-		return nil, errors.New("depth must be greater or equal to 1")
-	}
-
-	if cfg.Draws < 1 {
-		return nil, errors.New("number of draws must be greater or equal to 1")
+	if err := validateBirdCfg(cfg); err != nil {
+		return nil, &InvalidInputError{Err: err}
 	}
 
-	randSource := rand.New(rand.NewSource(time.Now().UnixNano()))
+	randSource := newRandSource()
 
 	err := validateEmuInputs(itemWeights, usersToWeightedItems)
 	if err != nil {
-		return &Bird{}, errors.Wrap(err, "invalid input")
+		return nil, &InvalidInputError{Err: err}
 	}
 
 	userItemsSampler, usersToItems, err := initUserWeightedItemsSamplers(randSource, usersToWeightedItems)
 	if err != nil {
-		return &Bird{}, errors.Wrap(err, "cannot initialize samplers")
+		return nil, errors.Wrap(err, "cannot initialize samplers")
 	}
 
-	itemsToUsers := permuteAdjacencyList(len(itemWeights), usersToItems)
-
 	b := Bird{
 		Cfg:               cfg,
 		RandSource:        randSource,
 		ItemWeights:       itemWeights,
 		UsersToItems:      usersToItems,
-		ItemsToUsers:      itemsToUsers,
-		UserItemsSamplers: userItemsSampler,
+		userItemsSamplers: userItemsSampler,
+		caches:            &graphCaches{},
 	}
+	b.indexItemsToUsers()
 
 	return &b, nil
 }
@@ -51,13 +43,17 @@ func NewEmu(cfg *BirdCfg, itemWeights []float64, usersToWeightedItems []map[int]
 // We also concurrently create the usersToItems slice of slice since the way
 // items are ordered in the slice corresponding to each user must match the
 // order of the weights used to initialize the corresponding sampler.
-func initUserWeightedItemsSamplers(randSource *rand.Rand,
+// Users with an empty collection are left with a zero-value sampler.
+func initUserWeightedItemsSamplers(randSource sampler.Rand,
 	usersToWeightedItems []map[int]float64) ([]sampler.AliasSampler, [][]int, error) {
 
 	usersToItems := make([][]int, len(usersToWeightedItems))
 	userItemsSamplers := make([]sampler.AliasSampler, len(usersToWeightedItems))
 	for i, userItems := range usersToWeightedItems {
 		usersToItems[i] = make([]int, len(userItems))
+		if len(userItems) == 0 {
+			continue
+		}
 		weights := make([]float64, len(userItems))
 		j := 0
 		for item, w := range userItems {
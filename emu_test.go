@@ -87,6 +87,14 @@ var emuInitTable = []EmuInitCase{
 		Draws:                1,
 		Valid:                true,
 	},
+	{
+		Name:                 "Users with empty collections",
+		ItemWeights:          []float64{1, 1},
+		UsersToWeightedItems: []map[int]float64{{}, {0: 1., 1: 2.}, {}},
+		Depth:                1,
+		Draws:                1,
+		Valid:                true,
+	},
 }
 
 func TestEmuInitialization(t *testing.T) {
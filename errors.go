@@ -0,0 +1,113 @@
+package birdland
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidInput is matched, with the Is function of the standard errors
+// package, by every InvalidInputError.
+var ErrInvalidInput = errors.New("invalid input")
+
+// InvalidInputError is returned by NewBird and the other constructors when
+// their configuration or input data is invalid, as opposed to internal
+// failures. Err describes the problem.
+type InvalidInputError struct {
+	Err error
+}
+
+func (e *InvalidInputError) Error() string {
+	return "invalid input: " + e.Err.Error()
+}
+
+// Unwrap returns the description of the problem.
+func (e *InvalidInputError) Unwrap() error {
+	return e.Err
+}
+
+// Is tells whether target is ErrInvalidInput.
+func (e *InvalidInputError) Is(target error) bool {
+	return target == ErrInvalidInput
+}
+
+// EmptyQueryError is returned when a query has no item.
+type EmptyQueryError struct{}
+
+func (e *EmptyQueryError) Error() string {
+	return "empty query"
+}
+
+// ItemOutOfRangeError is returned when the item at position QueryIndex of a
+// query does not belong to the graph.
+type ItemOutOfRangeError struct {
+	QueryIndex int
+	Item       int
+}
+
+func (e *ItemOutOfRangeError) Error() string {
+	return fmt.Sprintf("the query item %d at index %d does not belong to the graph", e.Item, e.QueryIndex)
+}
+
+// TooManyDrawsError is returned when the Draws of the query items add up to
+// more than the Cfg.Draws walks.
+type TooManyDrawsError struct {
+	Draws int
+	Walks int
+}
+
+func (e *TooManyDrawsError) Error() string {
+	return fmt.Sprintf("the query items have %d draws but there are only %d walks", e.Draws, e.Walks)
+}
+
+// DeadEndError is returned when a walk cannot step from Item, with
+// Cfg.Dangling set to DanglingFail, because no one has interacted with it,
+// in which case User is -1, or because the collection of User, whom the
+// walk went through, is empty.
+type DeadEndError struct {
+	Item int
+	User int
+}
+
+func (e *DeadEndError) Error() string {
+	if e.User < 0 {
+		return fmt.Sprintf("no one has interacted with item %d", e.Item)
+	}
+	return fmt.Sprintf("user %d, reached from item %d, has an empty collection", e.User, e.Item)
+}
+
+// wrappedError annotates an error with a message, as errors.Wrap does, but
+// also implements Unwrap whatever the version of github.com/pkg/errors, so
+// that the typed errors above can be found with the As function of the
+// standard errors package.
+type wrappedError struct {
+	msg string
+	err error
+}
+
+// wrap annotates err with message, or returns nil if err is nil.
+func wrap(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	return &wrappedError{msg: message, err: err}
+}
+
+// wrapf is wrap with a formatted message.
+func wrapf(err error, format string, args ...interface{}) error {
+	return wrap(err, fmt.Sprintf(format, args...))
+}
+
+func (e *wrappedError) Error() string {
+	return e.msg + ": " + e.err.Error()
+}
+
+// Cause returns the annotated error, for errors.Cause.
+func (e *wrappedError) Cause() error {
+	return e.err
+}
+
+// Unwrap returns the annotated error.
+func (e *wrappedError) Unwrap() error {
+	return e.err
+}
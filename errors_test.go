@@ -0,0 +1,99 @@
+//go:build go1.13
+// +build go1.13
+
+package birdland
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTypedErrors(t *testing.T) {
+	// No one has interacted with item 3.
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 2}}
+	cfg := NewBirdCfg()
+	cfg.Depth = 4
+	cfg.Draws = 100
+
+	cfg.Depth = 0
+	_, err := NewBird(cfg, itemWeights, usersToItems)
+	var invalid *InvalidInputError
+	if !errors.As(err, &invalid) || !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("TypedErrors: expected NewBird to return an *InvalidInputError, got %v", err)
+	}
+	cfg.Depth = 4
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("TypedErrors: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	for _, parallelism := range []int{1, 2} {
+		cfg.Parallelism = parallelism
+
+		_, _, err = bird.Process(nil)
+		var empty *EmptyQueryError
+		if !errors.As(err, &empty) {
+			t.Errorf("TypedErrors: with a parallelism of %d, expected an *EmptyQueryError, got %v", parallelism, err)
+		}
+
+		_, _, err = bird.Process([]QueryItem{{Item: 0, Weight: 1}, {Item: 9, Weight: 1}})
+		var outOfRange *ItemOutOfRangeError
+		if !errors.As(err, &outOfRange) || *outOfRange != (ItemOutOfRangeError{QueryIndex: 1, Item: 9}) {
+			t.Errorf("TypedErrors: with a parallelism of %d, expected item 9 at index 1 to be out of range, got %v", parallelism, err)
+		}
+
+		_, _, err = bird.Process([]QueryItem{{Item: 3, Weight: 1}})
+		if !errors.Is(err, ErrNoItemsSampled) {
+			t.Errorf("TypedErrors: with a parallelism of %d, expected ErrNoItemsSampled, got %v", parallelism, err)
+		}
+	}
+	cfg.Parallelism = 0
+
+	_, err = bird.ProcessScores([]QueryItem{{Item: -1, Weight: -1}, {Item: 0, Weight: 1}})
+	var outOfRange *ItemOutOfRangeError
+	if !errors.As(err, &outOfRange) || outOfRange.QueryIndex != 0 {
+		t.Errorf("TypedErrors: expected the avoided item at index 0 to be out of range, got %v", err)
+	}
+
+	_, _, err = bird.Process([]QueryItem{{Item: 0, Weight: 1}, {Item: 1, Weight: 1, Draws: -1}})
+	var invalidQuery *InvalidQueryError
+	if !errors.As(err, &invalidQuery) || len(invalidQuery.Problems) != 1 || invalidQuery.Problems[0].Index != 1 {
+		t.Errorf("TypedErrors: expected the negative draws at index 1 to be reported, got %v", err)
+	}
+
+	_, _, err = bird.Process([]QueryItem{{Item: 0, Weight: 1, Draws: 60}, {Item: 1, Weight: 1, Draws: 60}})
+	var tooMany *TooManyDrawsError
+	if !errors.As(err, &tooMany) || *tooMany != (TooManyDrawsError{Draws: 120, Walks: 100}) {
+		t.Errorf("TypedErrors: expected 120 draws for 100 walks to be reported, got %v", err)
+	}
+
+	// The errors of the methods built on Process are annotated but can still
+	// be found.
+	_, err = bird.TopReferrers([]QueryItem{{Item: 9, Weight: 1}}, 2)
+	if !errors.As(err, &outOfRange) || *outOfRange != (ItemOutOfRangeError{QueryIndex: 0, Item: 9}) {
+		t.Errorf("TypedErrors: expected TopReferrers to report item 9 out of range, got %v", err)
+	}
+	_, err = bird.TopReferrers(nil, 2)
+	var empty *EmptyQueryError
+	if !errors.As(err, &empty) {
+		t.Errorf("TypedErrors: expected TopReferrers to return an *EmptyQueryError, got %v", err)
+	}
+	flock := Flock{Members: []FlockMember{{Bird: bird, Weight: 1}}}
+	if _, err := flock.RecommendItems(nil, 2); !errors.As(err, &empty) {
+		t.Errorf("TypedErrors: expected Flock.RecommendItems to return an *EmptyQueryError, got %v", err)
+	}
+
+	// Pretend item 2 was pruned from the item-user lists only, so that the
+	// walks reaching it are stuck.
+	bird.ItemsToUsers[2] = []int{}
+	for _, parallelism := range []int{1, 2} {
+		cfg.Parallelism = parallelism
+		_, _, err = bird.Process([]QueryItem{{Item: 0, Weight: 1}})
+		var deadEnd *DeadEndError
+		if !errors.As(err, &deadEnd) || *deadEnd != (DeadEndError{Item: 2, User: -1}) {
+			t.Errorf("TypedErrors: with a parallelism of %d, expected a dead end at item 2, got %v", parallelism, err)
+		}
+	}
+}
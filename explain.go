@@ -0,0 +1,92 @@
+package birdland
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// WalkResult holds the visits of the random walks performed by Walk and,
+// if Cfg.TraceWalks was set, the traces ExplainItem needs.
+type WalkResult struct {
+	Visits []Visit
+
+	starts  []int         // item each walk started from
+	byItems map[int][]int // positions in Visits of the visits of each item
+}
+
+// Explanation is a query item and a user through whose collection the walks
+// started from that item reached a recommended item, along with the number
+// of such visits.
+type Explanation struct {
+	Start    int
+	Referrer int
+	Count    int
+}
+
+// Walk performs the random walks of ProcessScores and returns their visits.
+// With Cfg.TraceWalks, the result also records the item each walk started
+// from; a walk restarted according to Cfg.Dangling keeps the item it first
+// started from.
+func (b *Bird) Walk(query []QueryItem) (WalkResult, error) {
+	visits, starts, err := b.walkVisitsFrom(query)
+	if err != nil {
+		return WalkResult{}, err
+	}
+
+	result := WalkResult{Visits: visits}
+	if b.Cfg.TraceWalks {
+		result.starts = starts
+		result.byItems = make(map[int][]int)
+		for i, v := range visits {
+			result.byItems[v.Item] = append(result.byItems[v.Item], i)
+		}
+	}
+
+	return result, nil
+}
+
+// ExplainItem returns the k (start item, referrer) pairs that led to the
+// most visits of item in result, in descending order of count, pairs with
+// the same count being sorted by ascending start item then referrer; all of
+// them are returned when there are fewer than k. They tell that users who
+// interacted with the start item also interacted with item. It only reads
+// the visits of item, but result must have been returned by Walk with
+// Cfg.TraceWalks set.
+func ExplainItem(result WalkResult, item int, k int) ([]Explanation, error) {
+	if result.byItems == nil {
+		return nil, errors.New("the walks were not traced, set Cfg.TraceWalks")
+	}
+	if k < 0 {
+		return nil, errors.Errorf("the number of explanations must not be negative, got %d", k)
+	}
+
+	type pair struct {
+		start, referrer int
+	}
+	counts := make(map[pair]int)
+	for _, i := range result.byItems[item] {
+		v := result.Visits[i]
+		counts[pair{result.starts[v.Walk], v.Referrer}]++
+	}
+
+	explanations := make([]Explanation, 0, len(counts))
+	for p, count := range counts {
+		explanations = append(explanations, Explanation{Start: p.start, Referrer: p.referrer, Count: count})
+	}
+	sort.Slice(explanations, func(i, j int) bool {
+		ei, ej := explanations[i], explanations[j]
+		if ei.Count != ej.Count {
+			return ei.Count > ej.Count
+		}
+		if ei.Start != ej.Start {
+			return ei.Start < ej.Start
+		}
+		return ei.Referrer < ej.Referrer
+	})
+	if k < len(explanations) {
+		explanations = explanations[:k]
+	}
+
+	return explanations, nil
+}
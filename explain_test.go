@@ -0,0 +1,73 @@
+package birdland
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExplainItem(t *testing.T) {
+	// Item 2 is reached from item 0 through users 0 and 1 and from item 1
+	// through user 1 only.
+	itemWeights := []float64{1, 1, 1}
+	usersToItems := [][]int{[]int{0, 2}, []int{0, 1, 2}}
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 1, Weight: 1}}
+	cfg := NewBirdCfg()
+	cfg.Draws = 1000
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("ExplainItem: Bird initialization should not have raised an error but did: %v", err)
+	}
+	result, err := bird.Walk(query)
+	if err != nil {
+		t.Fatalf("ExplainItem: Walk should not have raised an error but did: %v", err)
+	}
+	if _, err := ExplainItem(result, 2, 3); err == nil {
+		t.Errorf("ExplainItem: should have raised an error when the walks were not traced")
+	}
+
+	cfg.TraceWalks = true
+	bird.ReSeed(42)
+	result, err = bird.Walk(query)
+	if err != nil {
+		t.Fatalf("ExplainItem: Walk should not have raised an error but did: %v", err)
+	}
+	explanations, err := ExplainItem(result, 2, 5)
+	if err != nil {
+		t.Fatalf("ExplainItem: should not have raised an error but did: %v", err)
+	}
+	var visits int
+	for _, v := range result.Visits {
+		if v.Item == 2 {
+			visits++
+		}
+	}
+	var total int
+	for i, e := range explanations {
+		total += e.Count
+		if e.Start == 1 && e.Referrer == 0 {
+			t.Errorf("ExplainItem: user 0 cannot lead from item 1 to item 2, got %v", explanations)
+		}
+		if i > 0 && e.Count > explanations[i-1].Count {
+			t.Errorf("ExplainItem: explanations are not in descending order of count: %v", explanations)
+		}
+	}
+	if len(explanations) != 3 || total != visits {
+		t.Errorf("ExplainItem: expected the %d visits of item 2 to be explained by 3 pairs, got %v", visits, explanations)
+	}
+
+	if _, err := ExplainItem(result, 2, -1); err == nil {
+		t.Errorf("ExplainItem: a negative number of explanations should have been rejected")
+	}
+
+	top, err := ExplainItem(result, 2, 1)
+	if err != nil {
+		t.Fatalf("ExplainItem: should not have raised an error but did: %v", err)
+	}
+	if !reflect.DeepEqual(top, explanations[:1]) {
+		t.Errorf("ExplainItem: expected the first explanation %v, got %v", explanations[:1], top)
+	}
+	if none, _ := ExplainItem(result, 7, 1); len(none) != 0 {
+		t.Errorf("ExplainItem: expected no explanation for an unvisited item, got %v", none)
+	}
+}
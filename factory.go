@@ -0,0 +1,99 @@
+package birdland
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rlouf/birdland/sampler"
+)
+
+// SamplerFactory builds the sampler of a user's collection from the weights
+// with which its items are drawn. source is the Bird's RandSource when the
+// sampler is built; the Bird itself always draws with SampleWith and its
+// current RandSource.
+type SamplerFactory func(source sampler.Rand, weights []float64) (sampler.Sampler, error)
+
+// AliasSamplerFactory builds the AliasSamplers used by default.
+func AliasSamplerFactory(source sampler.Rand, weights []float64) (sampler.Sampler, error) {
+	return sampler.NewAliasSampler(source, weights)
+}
+
+// CompactAliasSamplerFactory builds CompactAliasSamplers, whose tables take
+// half the memory of those of AliasSamplers. It is used when
+// Cfg.CompactSamplers is set.
+func CompactAliasSamplerFactory(source sampler.Rand, weights []float64) (sampler.Sampler, error) {
+	return sampler.NewCompactAliasSampler(source, weights)
+}
+
+// DefaultLinearSamplerMaxDegree is the largest collection for which a
+// LinearSampler draws faster than an AliasSampler in BenchmarkSmallSamplers,
+// and a value to start from for Cfg.LinearSamplerMaxDegree.
+const DefaultLinearSamplerMaxDegree = 4
+
+// LinearSamplerFactory returns a factory that builds LinearSamplers for the
+// collections of at most maxDegree items, which they sample faster, with
+// less memory, than the other samplers, and leaves the larger ones to
+// factory. It is used with AliasSamplerFactory, or CompactAliasSamplerFactory
+// if Cfg.CompactSamplers is set, when Cfg.LinearSamplerMaxDegree is set.
+func LinearSamplerFactory(maxDegree int, factory SamplerFactory) SamplerFactory {
+	return func(source sampler.Rand, weights []float64) (sampler.Sampler, error) {
+		if len(weights) <= maxDegree {
+			return sampler.NewLinearSampler(source, weights)
+		}
+		return factory(source, weights)
+	}
+}
+
+// NewBirdWithSamplerFactory is like NewBirdWithEdgeWeights but the samplers of
+// the users' collections are built with factory, which makes it possible to
+// try other sampling methods. The samplers rebuilt by incremental updates are
+// built with factory as well. The Bird is saved with alias tables built from the graph
+// and the weights, so it is loaded back with AliasSamplers. A nil factory is
+// equivalent to NewBirdWithEdgeWeights.
+func NewBirdWithSamplerFactory(cfg *BirdCfg, itemWeights []float64, usersToItems [][]int,
+	edgeWeights [][]float64, factory SamplerFactory) (*Bird, error) {
+
+	return newBird(cfg, itemWeights, usersToItems, edgeWeights, factory)
+}
+
+// initCustomSamplers is like initUserItemsSamplers with samplers built by
+// factory. Users with an empty collection are left with a nil sampler.
+func initCustomSamplers(factory SamplerFactory,
+	randSource sampler.Rand,
+	itemWeights []float64,
+	userToItems [][]int,
+	edgeWeights [][]float64,
+	workers int) ([]sampler.Sampler, error) {
+
+	samplers := make([]sampler.Sampler, len(userToItems))
+	err := forEachUser(len(userToItems), workers, func(i int) error {
+		var userEdgeWeights []float64
+		if edgeWeights != nil {
+			userEdgeWeights = edgeWeights[i]
+		}
+		s, err := newCustomSampler(factory, randSource, userSamplingWeights(itemWeights, userToItems[i], userEdgeWeights))
+		if err != nil {
+			return err
+		}
+		samplers[i] = s
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return samplers, nil
+}
+
+// newCustomSampler builds a sampler with factory. An empty collection gets a
+// nil sampler.
+func newCustomSampler(factory SamplerFactory, randSource sampler.Rand, weights []float64) (sampler.Sampler, error) {
+	if len(weights) == 0 {
+		return nil, nil
+	}
+
+	s, err := factory(randSource, weights)
+	if err != nil {
+		return nil, errors.Wrap(err, "the sampler factory failed")
+	}
+
+	return s, nil
+}
@@ -0,0 +1,146 @@
+package birdland
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/rlouf/birdland/sampler"
+)
+
+func TestBirdSamplerFactory(t *testing.T) {
+	itemWeights := []float64{1, 2, 3, 4}
+	usersToItems := [][]int{[]int{0, 1, 2}, []int{1, 3}, []int{}, []int{0, 2, 3}}
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 3, Weight: 2}}
+	cfg := NewBirdCfg()
+	cfg.Depth = 3
+	cfg.Draws = 100
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("SamplerFactory: Bird initialization should not have raised an error but did: %v", err)
+	}
+	aliasBird, err := NewBirdWithSamplerFactory(cfg, itemWeights, usersToItems, nil, AliasSamplerFactory)
+	if err != nil {
+		t.Fatalf("SamplerFactory: Bird initialization should not have raised an error but did: %v", err)
+	}
+	bird.ReSeed(42)
+	aliasBird.ReSeed(42)
+	items, referrers, _ := bird.Process(query)
+	aliasItems, aliasReferrers, _ := aliasBird.Process(query)
+	if !reflect.DeepEqual(items, aliasItems) || !reflect.DeepEqual(referrers, aliasReferrers) {
+		t.Errorf("SamplerFactory: AliasSamplerFactory should perform the same walks as NewBird")
+	}
+
+	built := 0
+	fenwick := func(source sampler.Rand, weights []float64) (sampler.Sampler, error) {
+		built++
+		return sampler.NewFenwickSampler(source, weights)
+	}
+	fenwickBird, err := NewBirdWithSamplerFactory(cfg, itemWeights, usersToItems, nil, fenwick)
+	if err != nil {
+		t.Fatalf("SamplerFactory: Bird initialization should not have raised an error but did: %v", err)
+	}
+	if built != 3 {
+		t.Errorf("SamplerFactory: expected the factory to build 3 samplers, built %d", built)
+	}
+	if _, ok := bird.UserSampler(0).(*sampler.AliasSampler); !ok {
+		t.Errorf("SamplerFactory: expected user 0 of NewBird to draw from an alias sampler, got %T", bird.UserSampler(0))
+	}
+	if _, ok := fenwickBird.UserSampler(0).(*sampler.FenwickSampler); !ok {
+		t.Errorf("SamplerFactory: expected user 0 to draw from the sampler built by the factory, got %T", fenwickBird.UserSampler(0))
+	}
+	if _, _, err := fenwickBird.Process(query); err != nil {
+		t.Errorf("SamplerFactory: Process should not have raised an error but did: %v", err)
+	}
+	if err := fenwickBird.AddInteraction(2, 1); err != nil {
+		t.Fatalf("SamplerFactory: AddInteraction should not have raised an error but did: %v", err)
+	}
+	if built != 4 {
+		t.Errorf("SamplerFactory: the sampler of user 2 should have been built by the factory")
+	}
+	if item, err := fenwickBird.sampleItem(2); err != nil || item != 1 {
+		t.Errorf("SamplerFactory: expected user 2 to lead to item 1, got %d (error: %v)", item, err)
+	}
+	if err := fenwickBird.Warmup(); err != nil {
+		t.Errorf("SamplerFactory: Warmup should not have raised an error but did: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := fenwickBird.Save(&buf); err != nil {
+		t.Fatalf("SamplerFactory: Save should not have raised an error but did: %v", err)
+	}
+	if _, err := LoadBird(&buf); err != nil {
+		t.Errorf("SamplerFactory: LoadBird should not have raised an error but did: %v", err)
+	}
+}
+
+func TestBirdCompactSamplers(t *testing.T) {
+	itemWeights := []float64{1, 2, 3, 4}
+	usersToItems := [][]int{[]int{0, 1, 2}, []int{1, 3}, []int{}, []int{0, 2, 3}}
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 3, Weight: 2}}
+	cfg := NewBirdCfg()
+	cfg.Depth = 3
+	cfg.Draws = 100
+	cfg.CompactSamplers = true
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("CompactSamplers: Bird initialization should not have raised an error but did: %v", err)
+	}
+	for u, s := range bird.customSamplers {
+		if _, ok := s.(*sampler.CompactAliasSampler); !ok && len(usersToItems[u]) > 0 {
+			t.Errorf("CompactSamplers: expected user %d to have a compact sampler, got %T", u, s)
+		}
+	}
+	if _, _, err := bird.Process(query); err != nil {
+		t.Errorf("CompactSamplers: Process should not have raised an error but did: %v", err)
+	}
+	if err := bird.AddInteraction(2, 1); err != nil {
+		t.Fatalf("CompactSamplers: AddInteraction should not have raised an error but did: %v", err)
+	}
+	if _, ok := bird.customSamplers[2].(*sampler.CompactAliasSampler); !ok {
+		t.Errorf("CompactSamplers: the sampler rebuilt for user 2 should be compact")
+	}
+}
+
+func TestBirdLinearSamplers(t *testing.T) {
+	itemWeights := []float64{1, 2, 3, 4}
+	usersToItems := [][]int{[]int{0, 1, 2}, []int{1, 3}, []int{}, []int{0, 1, 2, 3}}
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 3, Weight: 2}}
+	cfg := NewBirdCfg()
+	cfg.Depth = 3
+	cfg.Draws = 100
+	cfg.LinearSamplerMaxDegree = 3
+
+	for _, compact := range []bool{false, true} {
+		cfg.CompactSamplers = compact
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("LinearSamplers: Bird initialization should not have raised an error but did: %v", err)
+		}
+		for u, s := range bird.customSamplers {
+			switch s.(type) {
+			case *sampler.LinearSampler:
+				if len(usersToItems[u]) > cfg.LinearSamplerMaxDegree {
+					t.Errorf("LinearSamplers: compact=%v: user %d has %d items but a linear sampler", compact, u, len(usersToItems[u]))
+				}
+			case *sampler.AliasSampler, *sampler.CompactAliasSampler:
+				if len(usersToItems[u]) <= cfg.LinearSamplerMaxDegree {
+					t.Errorf("LinearSamplers: compact=%v: user %d has %d items but a %T", compact, u, len(usersToItems[u]), s)
+				}
+			}
+		}
+		if _, ok := bird.customSamplers[3].(*sampler.CompactAliasSampler); ok != compact {
+			t.Errorf("LinearSamplers: compact=%v: the larger collections got a %T", compact, bird.customSamplers[3])
+		}
+		if _, _, err := bird.Process(query); err != nil {
+			t.Errorf("LinearSamplers: compact=%v: Process should not have raised an error but did: %v", compact, err)
+		}
+	}
+
+	cfg.LinearSamplerMaxDegree = -1
+	if _, err := NewBird(cfg, itemWeights, usersToItems); err == nil {
+		t.Errorf("LinearSamplers: a negative degree should have been rejected")
+	}
+}
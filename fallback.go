@@ -0,0 +1,107 @@
+package birdland
+
+// FallbackPolicy tells how RecommendItemsScored and Recommend complete the
+// recommendations when the walks do not visit enough items, for instance
+// because no one has interacted with the items of the query.
+type FallbackPolicy int
+
+const (
+	// FallbackNone returns the items visited by the walks only. This is the
+	// default.
+	FallbackNone FallbackPolicy = iota
+	// FallbackDegree completes the recommendations with the items with the
+	// most users.
+	FallbackDegree
+	// FallbackWeight completes the recommendations with the items with the
+	// highest global weight.
+	FallbackWeight
+)
+
+// numFallbacks is the number of fallback policies that rank items.
+const numFallbacks = 2
+
+// coldQuery tells whether no item of the query can start a walk because
+// each of them has a zero combined weight or has been interacted with by no
+// one. Invalid queries are not cold, so that the walks report their error.
+func (b *Bird) coldQuery(query []QueryItem) bool {
+	query, _ = splitAvoided(query)
+	weights, err := b.queryWeights(query)
+	if err != nil || len(query) == 0 {
+		return false
+	}
+	b.loadQueryItemUsers(query)
+	for i, q := range query {
+		if weights[i] > 0 && len(b.itemUsers(q.Item)) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// recommendFallback completes scored, the recommendations returned by the
+// walks along with err, as opts.Fallback requires. ErrNotEnoughItems is
+// cleared when the fallback makes up the missing items.
+func (b *Bird) recommendFallback(scored []ScoredItem, err error, n int, opts ScoreOptions) ([]ScoredItem, error) {
+	if opts.Fallback == FallbackNone || (err != nil && err != ErrNotEnoughItems) {
+		return scored, err
+	}
+	scored = b.withFallback(scored, n, opts.Fallback, opts.Seen)
+	if len(scored) < n {
+		return scored, ErrNotEnoughItems
+	}
+
+	return scored, nil
+}
+
+// withFallback appends to ranked, until there are n items, the most popular
+// items according to policy that are neither in ranked nor seen. They are
+// flagged as Fallback and have a zero score.
+func (b *Bird) withFallback(ranked []ScoredItem, n int, policy FallbackPolicy, seen SeenSet) []ScoredItem {
+	if len(ranked) >= n {
+		return ranked
+	}
+
+	recommended := make(map[int]bool, len(ranked))
+	for _, s := range ranked {
+		recommended[s.Item] = true
+	}
+	for _, item := range b.popularItems(policy) {
+		if len(ranked) >= n {
+			break
+		}
+		if recommended[item] || (seen != nil && seen.Seen(item)) {
+			continue
+		}
+		ranked = append(ranked, ScoredItem{Item: item, Fallback: true})
+	}
+
+	return ranked
+}
+
+// popularItems returns the items someone has interacted with in descending
+// order of popularity according to policy. The ranking is computed on the
+// first call and cached until the graph changes.
+func (b *Bird) popularItems(policy FallbackPolicy) []int {
+	k, c := int(policy)-1, b.caches
+	c.popularityOnce[k].Do(func() {
+		scores := make(map[int]float64)
+		for item, degree := range b.ItemDegrees() {
+			if degree == 0 {
+				continue
+			}
+			if policy == FallbackDegree {
+				scores[item] = float64(degree)
+			} else {
+				scores[item] = b.ItemWeights[item]
+			}
+		}
+		ranked := rankItems(scores, len(scores))
+		c.popularity[k] = make([]int, len(ranked))
+		for i, s := range ranked {
+			c.popularity[k][i] = s.Item
+		}
+	})
+
+	return c.popularity[k]
+}
@@ -0,0 +1,66 @@
+package birdland
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBirdFallback(t *testing.T) {
+	// Item 3 has the most users and item 4 the highest weight; no one has
+	// interacted with item 5.
+	itemWeights := []float64{1, 1, 1, 2, 3, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 3}, []int{2, 3}, []int{3, 4}}
+	cfg := NewBirdCfg()
+	cfg.Draws = 100
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("Fallback: Bird initialization should not have raised an error but did: %v", err)
+	}
+	cold := []QueryItem{{Item: 5, Weight: 1}}
+
+	scored, err := bird.RecommendItemsScored(cold, 3, ScoreOptions{Fallback: FallbackDegree})
+	if err != nil {
+		t.Fatalf("Fallback: should not have raised an error but did: %v", err)
+	}
+	expected := []ScoredItem{{Item: 3, Fallback: true}, {Item: 1, Fallback: true}, {Item: 0, Fallback: true}}
+	if !reflect.DeepEqual(scored, expected) {
+		t.Errorf("Fallback: expected the items with the most users %v, got %v", expected, scored)
+	}
+	scored, err = bird.RecommendItemsScored(cold, 2, ScoreOptions{Fallback: FallbackWeight, Seen: SeenItems(4)})
+	if err != nil {
+		t.Fatalf("Fallback: should not have raised an error but did: %v", err)
+	}
+	expected = []ScoredItem{{Item: 3, Fallback: true}, {Item: 0, Fallback: true}}
+	if !reflect.DeepEqual(scored, expected) {
+		t.Errorf("Fallback: expected the unseen items with the highest weights %v, got %v", expected, scored)
+	}
+
+	// The walks from item 0 only visit items 0 and 1.
+	query := []QueryItem{{Item: 0, Weight: 1}}
+	scored, err = bird.RecommendItemsScored(query, 10, ScoreOptions{Fallback: FallbackDegree})
+	if err != ErrNotEnoughItems {
+		t.Errorf("Fallback: expected ErrNotEnoughItems with fewer items than requested, got %v", err)
+	}
+	if len(scored) != 5 {
+		t.Fatalf("Fallback: expected the 5 items with users, got %v", scored)
+	}
+	for i, s := range scored {
+		if s.Fallback != (i >= 2) || (s.Fallback && (s.Score != 0 || s.Item < 2)) {
+			t.Errorf("Fallback: expected the 2 visited items followed by the fallback ones, got %v", scored)
+			break
+		}
+	}
+
+	r, err := bird.Recommend(cold, 2, 2, ScoreOptions{Fallback: FallbackDegree})
+	if err != nil {
+		t.Fatalf("Fallback: Recommend should not have raised an error but did: %v", err)
+	}
+	if !reflect.DeepEqual(r.Items, []int{3, 1}) || r.Fallbacks != 2 || len(r.Users) != 0 {
+		t.Errorf("Fallback: expected Recommend to return the fallback items only, got %+v", r)
+	}
+
+	if _, err := bird.RecommendItemsScored(query, 2, ScoreOptions{Fallback: FallbackPolicy(7)}); err == nil {
+		t.Errorf("Fallback: an unknown fallback policy should have been rejected")
+	}
+}
@@ -0,0 +1,91 @@
+package birdland
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rlouf/birdland/sampler"
+)
+
+// fenwickMinItems is the size from which the collection of a user modified
+// by an incremental update is sampled with a FenwickSampler. Rebuilding the
+// AliasSampler of such a user after every interaction costs O(n), while the
+// FenwickSampler is updated in place in O(log n), at the cost of slower
+// draws.
+const fenwickMinItems = 1024
+
+// fenwickSampler returns the FenwickSampler of user, or nil if the user is
+// sampled from userItemsSamplers.
+func (b *Bird) fenwickSampler(user int) *sampler.FenwickSampler {
+	if b.fenwickSamplers == nil {
+		return nil
+	}
+
+	return b.fenwickSamplers[user]
+}
+
+// edgeSamplingWeight returns the weight with which the j-th item of a user's
+// collection is sampled.
+func (b *Bird) edgeSamplingWeight(user, j int) float64 {
+	w := b.ItemWeights[b.UsersToItems[user][j]]
+	if b.EdgeWeights != nil {
+		w *= b.EdgeWeights[user][j]
+	}
+
+	return w
+}
+
+// commitFenwick applies a committed change to the FenwickSampler of user in
+// place. The Bird's graph and weights must already reflect the change. The
+// updates cannot fail since the indices and the weights were checked when
+// the change was staged.
+func (b *Bird) commitFenwick(f *sampler.FenwickSampler, user int, s *stagedChange) {
+	switch s.change.Kind {
+	case addInteraction:
+		_ = f.Append(b.edgeSamplingWeight(user, len(b.UsersToItems[user])-1))
+
+	case removeInteraction:
+		_ = f.Remove(s.removedAt)
+
+	case setItemWeight:
+		for j, item := range b.UsersToItems[user] {
+			if item == s.change.Item {
+				_ = f.UpdateWeight(j, b.edgeSamplingWeight(user, j))
+			}
+		}
+	}
+}
+
+// aliasSamplers returns the samplers of every user as AliasSamplers, as they
+// are saved. The samplers of the users sampled with a FenwickSampler are
+// built from its weights, and those of a Bird with a sampler factory or with
+// Cfg.UniformUserSampling from the graph and the weights.
+func (b *Bird) aliasSamplers() ([]sampler.AliasSampler, error) {
+	if b.customSamplers != nil || b.Cfg.UniformUserSampling {
+		samplers := make([]sampler.AliasSampler, len(b.UsersToItems))
+		for user, userItems := range b.UsersToItems {
+			var edgeWeights []float64
+			if b.EdgeWeights != nil {
+				edgeWeights = b.EdgeWeights[user]
+			}
+			s, err := newUserItemsSampler(b.RandSource, b.ItemWeights, userItems, edgeWeights)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot build the alias sampler of user %d", user)
+			}
+			samplers[user] = s
+		}
+		return samplers, nil
+	}
+	if len(b.fenwickSamplers) == 0 {
+		return b.userItemsSamplers, nil
+	}
+
+	samplers := append([]sampler.AliasSampler{}, b.userItemsSamplers...)
+	for user, f := range b.fenwickSamplers {
+		s, err := newSamplerFromWeights(b.RandSource, f.Weights())
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot build the alias sampler of user %d", user)
+		}
+		samplers[user] = s
+	}
+
+	return samplers, nil
+}
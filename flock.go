@@ -0,0 +1,135 @@
+package birdland
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// FlockMember is a Bird of a Flock along with the weight of its
+// recommendations. A Bird whose items are numbered differently from the
+// flock's, for instance one trained on a single country, translates them
+// with ToBird and FromBird.
+type FlockMember struct {
+	Bird   *Bird
+	Weight float64
+
+	// ToBird returns the item of the Bird corresponding to an item of the
+	// flock, and false if the Bird does not know it. nil means the items
+	// are numbered the same way.
+	ToBird func(item int) (int, bool)
+	// FromBird returns the item of the flock corresponding to an item of
+	// the Bird. nil means the items are numbered the same way.
+	FromBird func(item int) int
+}
+
+// Flock combines the recommendations of several Birds, for instance Birds
+// trained on different countries or interaction types.
+type Flock struct {
+	Members []FlockMember
+}
+
+// RecommendItems returns the n items with the highest combined score for
+// query, whose items are those of the flock. Each Bird processes the query
+// restricted to the items it knows, in its own goroutine, and scores the
+// visited items with ProcessScores. Its scores are divided by their sum so
+// that the weights alone decide how much each Bird counts, and an item's
+// combined score is the sum over the Birds of the Bird's weight times its
+// normalized score.
+//
+// A Bird that knows none of the query items, or fails to process the query,
+// is left out; an error is only returned if every Bird with a positive
+// weight is. Since the Birds are processed concurrently, a Bird must not be
+// a member twice nor be used elsewhere at the same time.
+func (f *Flock) RecommendItems(query []QueryItem, n int) ([]ScoredItem, error) {
+	if len(query) == 0 {
+		return nil, &EmptyQueryError{}
+	}
+	for m, member := range f.Members {
+		if member.Weight < 0 {
+			return nil, fmt.Errorf("negative weight %v for member %d", member.Weight, m)
+		}
+	}
+
+	results := make([][]ScoredItem, len(f.Members))
+	errs := make([]error, len(f.Members))
+	var wg sync.WaitGroup
+	for m, member := range f.Members {
+		if member.Weight == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(m int, member FlockMember) {
+			defer wg.Done()
+			results[m], errs[m] = member.scores(query)
+		}(m, member)
+	}
+	wg.Wait()
+
+	scores := make(map[int]float64)
+	var failures []string
+	succeeded := 0
+	for m, member := range f.Members {
+		if member.Weight == 0 {
+			continue
+		}
+		if errs[m] != nil {
+			failures = append(failures, fmt.Sprintf("member %d: %v", m, errs[m]))
+			continue
+		}
+		for _, s := range results[m] {
+			scores[s.Item] += member.Weight * s.Score
+		}
+		succeeded++
+	}
+	if succeeded == 0 {
+		if len(failures) == 0 {
+			return nil, errors.New("the flock has no member with a positive weight")
+		}
+		return nil, fmt.Errorf("no member of the flock could process the query: %s", strings.Join(failures, "; "))
+	}
+
+	return rankItems(scores, n), nil
+}
+
+// scores processes query with the member's Bird and returns the normalized
+// scores of the visited items, numbered as in the flock.
+func (m FlockMember) scores(query []QueryItem) ([]ScoredItem, error) {
+	local := query
+	if m.ToBird != nil {
+		local = make([]QueryItem, 0, len(query))
+		for _, q := range query {
+			if item, ok := m.ToBird(q.Item); ok {
+				q.Item = item
+				local = append(local, q)
+			}
+		}
+		if len(local) == 0 {
+			return nil, errors.New("none of the query items belongs to the Bird")
+		}
+	}
+
+	scored, err := m.Bird.ProcessScores(local)
+	if err != nil {
+		return nil, err
+	}
+	var total float64
+	for _, s := range scored {
+		total += s.Score
+	}
+	if !(total > 0) {
+		return nil, errors.New("the walks did not score any item")
+	}
+
+	for i := range scored {
+		scored[i].Score /= total
+		scored[i].StdErr /= total
+		if m.FromBird != nil {
+			scored[i].Item = m.FromBird(scored[i].Item)
+		}
+	}
+
+	return scored, nil
+}
@@ -0,0 +1,78 @@
+package birdland
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFlockRecommendItems(t *testing.T) {
+	cfg := NewBirdCfg()
+	cfg.Draws = 1000
+
+	// The first Bird knows the items 0 and 1 of the flock, the second one
+	// the items 1 and 2, which it numbers 0 and 1. Both reach each of their
+	// items half of the time.
+	first, err := NewBird(cfg, []float64{1, 1}, [][]int{[]int{0, 1}})
+	if err != nil {
+		t.Fatalf("Flock: Bird initialization should not have raised an error but did: %v", err)
+	}
+	second, err := NewBird(cfg, []float64{1, 1}, [][]int{[]int{0, 1}})
+	if err != nil {
+		t.Fatalf("Flock: Bird initialization should not have raised an error but did: %v", err)
+	}
+	flock := Flock{Members: []FlockMember{
+		{Bird: first, Weight: 1},
+		{
+			Bird:   second,
+			Weight: 3,
+			ToBird: func(item int) (int, bool) {
+				if item < 1 || item > 2 {
+					return 0, false
+				}
+				return item - 1, true
+			},
+			FromBird: func(item int) int { return item + 1 },
+		},
+	}}
+
+	cases := []struct {
+		Name        string
+		Query       []QueryItem
+		Expected    []int
+		TotalWeight float64
+	}{
+		{"Both Birds", []QueryItem{{Item: 1, Weight: 1}}, []int{1, 2, 0}, 4},
+		{"First Bird only", []QueryItem{{Item: 0, Weight: 1}}, nil, 1},
+	}
+	for _, ex := range cases {
+		recommended, err := flock.RecommendItems(ex.Query, 10)
+		if err != nil {
+			t.Fatalf("Flock: %s: should not have raised an error but did: %v", ex.Name, err)
+		}
+		var total float64
+		for _, s := range recommended {
+			total += s.Score
+		}
+		if math.Abs(total-ex.TotalWeight) > 1e-9 {
+			t.Errorf("Flock: %s: expected the scores to sum to %v, got %v", ex.Name, ex.TotalWeight, recommended)
+		}
+		for i, item := range ex.Expected {
+			if recommended[i].Item != item {
+				t.Errorf("Flock: %s: expected items %v, got %v", ex.Name, ex.Expected, recommended)
+				break
+			}
+		}
+	}
+
+	if recommended, _ := flock.RecommendItems([]QueryItem{{Item: 1, Weight: 1}}, 1); len(recommended) != 1 {
+		t.Errorf("Flock: expected a single item, got %v", recommended)
+	}
+	if _, err := flock.RecommendItems([]QueryItem{{Item: 7, Weight: 1}}, 10); err == nil {
+		t.Errorf("Flock: a query no Bird knows should have raised an error")
+	}
+
+	flock.Members[1].Weight = -1
+	if _, err := flock.RecommendItems([]QueryItem{{Item: 1, Weight: 1}}, 10); err == nil {
+		t.Errorf("Flock: a negative weight should have been rejected")
+	}
+}
@@ -0,0 +1,55 @@
+package birdland
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// WeightedQuery is the query of one member of a group along with the weight
+// of this member in the group's recommendations.
+type WeightedQuery struct {
+	Query  []QueryItem
+	Weight float64
+}
+
+// ProcessGroup recommends items to a group, for instance a household, by
+// processing the query of each member and combining the results. Each item is
+// scored by the sum over the members of the member's weight times the share
+// of the member's visits that went to the item, so that the weights alone
+// decide how much each member counts. Items are returned in descending order
+// of score.
+//
+// Members with an empty query or a zero weight are skipped; an error is
+// returned if no member is left.
+func (b *Bird) ProcessGroup(group []WeightedQuery) ([]ScoredItem, error) {
+	scores := make(map[int]float64)
+	processed := 0
+	for m, member := range group {
+		if member.Weight < 0 {
+			return nil, fmt.Errorf("negative weight %v for member %d", member.Weight, m)
+		}
+		if member.Weight == 0 || len(member.Query) == 0 {
+			continue
+		}
+
+		items, _, err := b.Process(member.Query)
+		if err != nil {
+			return nil, wrapf(err, "cannot process the query of member %d", m)
+		}
+		if len(items) == 0 {
+			continue
+		}
+		share := member.Weight / float64(len(items))
+		for _, item := range items {
+			scores[item] += share
+		}
+		processed++
+	}
+
+	if processed == 0 {
+		return nil, errors.New("the group has no member with a non-empty query and a positive weight")
+	}
+
+	return rankItems(scores, len(scores)), nil
+}
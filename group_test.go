@@ -0,0 +1,53 @@
+package birdland
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBirdProcessGroup(t *testing.T) {
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{
+		[]int{0, 1},
+		[]int{2, 3},
+	}
+	cfg := NewBirdCfg()
+	cfg.Draws = 1000
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("ProcessGroup: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	group := []WeightedQuery{
+		{Query: []QueryItem{{Item: 0, Weight: 1}}, Weight: 3},
+		{Query: []QueryItem{}, Weight: 5},
+		{Query: []QueryItem{{Item: 2, Weight: 1}}, Weight: 1},
+	}
+	scored, err := bird.ProcessGroup(group)
+	if err != nil {
+		t.Fatalf("ProcessGroup: should not have raised an error but did: %v", err)
+	}
+	if len(scored) != 4 {
+		t.Fatalf("ProcessGroup: expected the 4 items to be scored, got %v", scored)
+	}
+	var total float64
+	for i, s := range scored {
+		if i < 2 && s.Item > 1 || i >= 2 && s.Item < 2 {
+			t.Errorf("ProcessGroup: the items of the member with the largest weight should come first, got %v", scored)
+		}
+		total += s.Score
+	}
+	if math.Abs(total-4) > 1e-9 {
+		t.Errorf("ProcessGroup: the scores should add up to the sum of the weights 4, got %v", total)
+	}
+
+	for _, invalid := range [][]WeightedQuery{
+		nil,
+		{{Query: []QueryItem{}, Weight: 1}},
+		{{Query: []QueryItem{{Item: 0, Weight: 1}}, Weight: -1}},
+	} {
+		if _, err := bird.ProcessGroup(invalid); err == nil {
+			t.Errorf("ProcessGroup: group %v should have raised an error but did not", invalid)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package birdland
+
+import (
+	"sync/atomic"
+)
+
+// BirdHolder holds the Bird currently used to serve recommendations and lets
+// it be replaced, for instance by a retrained one, without interrupting the
+// callers: Get always returns a complete Bird, and calls that obtained the
+// previous one finish on it.
+//
+// The holder only makes the replacement safe. A Bird is not modified once
+// built unless one of its mutating methods (AddInteraction, ApplyDelta,
+// ReSeed, ...) is called, which must not happen while it is shared. Process
+// draws from the Bird's RandSource, which is not safe for concurrent use;
+// concurrent callers should use ProcessSeeded, which does not touch it.
+type BirdHolder struct {
+	// atomic.Pointer would spare the type assertion in Get, but it needs
+	// Go 1.19 and the package still builds with Go 1.10.
+	bird atomic.Value // *Bird
+}
+
+// NewBirdHolder returns a holder serving b.
+func NewBirdHolder(b *Bird) *BirdHolder {
+	h := &BirdHolder{}
+	h.bird.Store(b)
+
+	return h
+}
+
+// Get returns the current Bird.
+func (h *BirdHolder) Get() *Bird {
+	return h.bird.Load().(*Bird)
+}
+
+// Swap replaces the current Bird with newBird.
+func (h *BirdHolder) Swap(newBird *Bird) {
+	h.bird.Store(newBird)
+}
+
+// RebuildFrom builds a new Bird from the given data in the background and
+// swaps it in once it is ready. The returned channel receives nil once the new
+// Bird is served, or the construction error, in which case the current Bird
+// is kept.
+func (h *BirdHolder) RebuildFrom(cfg *BirdCfg, itemWeights []float64, usersToItems [][]int) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		b, err := NewBird(cfg, itemWeights, usersToItems)
+		if err == nil {
+			h.Swap(b)
+		}
+		done <- err
+	}()
+
+	return done
+}
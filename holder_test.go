@@ -0,0 +1,54 @@
+package birdland
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestBirdHolder(t *testing.T) {
+	first, err := NewBird(NewBirdCfg(), []float64{1, 1}, [][]int{[]int{0, 1}})
+	if err != nil {
+		t.Fatalf("BirdHolder: Bird initialization should not have raised an error but did: %v", err)
+	}
+	holder := NewBirdHolder(first)
+
+	// Serve queries while the model is rebuilt.
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if _, _, err := holder.Get().ProcessSeeded([]QueryItem{{Item: 0, Weight: 1}}, int64(w), 1); err != nil {
+					t.Errorf("BirdHolder: Process should not have raised an error but did: %v", err)
+					return
+				}
+			}
+		}(w)
+	}
+
+	err = <-holder.RebuildFrom(NewBirdCfg(), []float64{1, 1, 1}, [][]int{[]int{0, 2}, []int{1}})
+	if err != nil {
+		t.Errorf("BirdHolder: RebuildFrom should not have raised an error but did: %v", err)
+	}
+	if holder.Get() == first || len(holder.Get().ItemWeights) != 3 {
+		t.Errorf("BirdHolder: the rebuilt Bird should be served after RebuildFrom")
+	}
+
+	current := holder.Get()
+	if err := <-holder.RebuildFrom(NewBirdCfg(), []float64{}, [][]int{}); err == nil {
+		t.Errorf("BirdHolder: RebuildFrom with invalid data should have raised an error but did not")
+	}
+	if holder.Get() != current {
+		t.Errorf("BirdHolder: a failed rebuild should keep the current Bird")
+	}
+
+	close(stop)
+	wg.Wait()
+}
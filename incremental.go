@@ -0,0 +1,440 @@
+package birdland
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/rlouf/birdland/sampler"
+)
+
+// Version identifies a state of a Bird's graph. It starts at 0 when the Bird
+// is created and is incremented by every change to the graph or the weights.
+type Version uint64
+
+type changeKind int
+
+const (
+	addInteraction changeKind = iota
+	removeInteraction
+	setItemWeight
+)
+
+// change is a single modification of the graph or of the weights. Applying
+// the change brings the Bird to the version that follows.
+type change struct {
+	Kind   changeKind
+	User   int
+	Item   int
+	Weight float64
+}
+
+// Version returns the current version of the Bird.
+func (b *Bird) Version() Version {
+	return b.version
+}
+
+// AddInteraction adds the item to the user's collection and rebuilds the
+// user's sampler. A user index equal to the number of users adds a new user.
+// Collections of fenwickMinItems items or more get a sampler that is updated
+// in place instead of being rebuilt.
+// If the Bird has edge weights, the interaction is given a weight of 1.
+//
+// The sampler is rebuilt from the global item weights and the edge weights, so
+// incremental updates do not preserve the user-item weights of a Bird created
+// with NewEmu. Bird is not safe for concurrent use while it is being
+// modified, see ConcurrentBird.
+func (b *Bird) AddInteraction(user, item int) error {
+	return b.update(change{Kind: addInteraction, User: user, Item: item, Weight: 1})
+}
+
+// RemoveInteraction removes one occurrence of the item from the user's
+// collection and rebuilds the user's sampler.
+func (b *Bird) RemoveInteraction(user, item int) error {
+	return b.update(change{Kind: removeInteraction, User: user, Item: item})
+}
+
+// SetItemWeight changes the global weight of an item and rebuilds the
+// samplers of every user who interacted with it.
+func (b *Bird) SetItemWeight(item int, weight float64) error {
+	return b.update(change{Kind: setItemWeight, Item: item, Weight: weight})
+}
+
+// update applies a single change along with the new samplers of the affected
+// users.
+func (b *Bird) update(c change) error {
+	staged, err := b.stageChange(c, true)
+	if err != nil {
+		return err
+	}
+	b.commitChange(staged)
+
+	return nil
+}
+
+// stagedChange holds what a change replaces in a Bird. It is computed by
+// stageChange without modifying the Bird, so that the expensive part of a
+// change can run while the Bird is being read.
+type stagedChange struct {
+	change      change
+	userItems   []int                     // new collection of the user, for interactions
+	userWeights []float64                 // new edge weights of the user, if the Bird has edge weights
+	itemUsers   []int                     // new users of the item, for interactions
+	removedAt   int                       // index of the removed item in the user's collection, for removals
+	affected    []int                     // users whose sampler must be rebuilt
+	samplers    []sampler.AliasSampler    // new samplers of the affected users, if they were built
+	fenwick     []*sampler.FenwickSampler // new Fenwick samplers of the affected users, if they get one
+	custom      []sampler.Sampler         // new samplers of the affected users, for a Bird with a sampler factory
+
+	referrerWeights []float64            // new referrer weights of the item, if the Bird has referrer weights
+	referrerSampler sampler.AliasSampler // new referrer sampler of the item, if the Bird has referrer weights
+
+	withSamplers bool     // whether the samplers were staged along with the change
+	replaced     replaced // what commitChange replaced, for revertChange
+}
+
+// replaced holds what a committed change replaced in a Bird.
+type replaced struct {
+	newUser         bool
+	userItems       []int
+	userWeights     []float64
+	itemUsers       []int
+	itemWeights     []float64
+	referrerWeights []float64
+	referrerSampler sampler.AliasSampler
+}
+
+// stageChange validates the change and computes the new adjacency lists of
+// the user and the item in new slices. The new samplers of the affected users
+// are built when withSamplers is true; otherwise commitChange leaves the
+// samplers as they are, Fenwick samplers included, and they must be staged
+// with stageSamplers once the changes are committed.
+func (b *Bird) stageChange(c change, withSamplers bool) (*stagedChange, error) {
+	if b.unmap != nil {
+		return nil, errors.New("mapped birds are immutable")
+	}
+	if b.externalItemsToUsers != nil {
+		return nil, errors.New("the item-user lists are stored externally")
+	}
+	if c.Item < 0 || c.Item >= len(b.ItemWeights) {
+		return nil, fmt.Errorf("item %d does not belong to the graph", c.Item)
+	}
+
+	s := &stagedChange{change: c, withSamplers: withSamplers}
+	switch c.Kind {
+	case addInteraction:
+		if c.User < 0 || c.User > len(b.UsersToItems) {
+			return nil, fmt.Errorf("user %d does not belong to the graph", c.User)
+		}
+		var userItems []int
+		var userWeights []float64
+		if c.User < len(b.UsersToItems) {
+			userItems = b.UsersToItems[c.User]
+			if b.EdgeWeights != nil {
+				userWeights = b.EdgeWeights[c.User]
+			}
+		}
+		s.userItems = append(append([]int{}, userItems...), c.Item)
+		if b.EdgeWeights != nil {
+			s.userWeights = append(append([]float64{}, userWeights...), c.Weight)
+		}
+		s.itemUsers = append(append([]int{}, b.itemUsers(c.Item)...), c.User)
+		s.affected = []int{c.User}
+
+	case removeInteraction:
+		if c.User < 0 || c.User >= len(b.UsersToItems) {
+			return nil, fmt.Errorf("user %d does not belong to the graph", c.User)
+		}
+		i := indexOf(b.UsersToItems[c.User], c.Item)
+		if i < 0 {
+			return nil, fmt.Errorf("user %d has not interacted with item %d", c.User, c.Item)
+		}
+		s.userItems = removeAt(b.UsersToItems[c.User], i)
+		s.removedAt = i
+		if b.EdgeWeights != nil {
+			userWeights := b.EdgeWeights[c.User]
+			s.userWeights = append(append([]float64{}, userWeights[:i]...), userWeights[i+1:]...)
+		}
+		itemUsers := b.itemUsers(c.Item)
+		s.itemUsers = removeAt(itemUsers, indexOf(itemUsers, c.User))
+		s.affected = []int{c.User}
+
+	case setItemWeight:
+		if c.Weight < 0 || math.IsNaN(c.Weight) || math.IsInf(c.Weight, 0) {
+			return nil, fmt.Errorf("invalid weight %v for item %d", c.Weight, c.Item)
+		}
+		s.affected = append([]int{}, b.itemUsers(c.Item)...)
+
+	default:
+		return nil, fmt.Errorf("unknown change %d", c.Kind)
+	}
+
+	if err := b.stageReferrers(s); err != nil {
+		return nil, err
+	}
+
+	if withSamplers && !b.Cfg.UniformUserSampling {
+		s.samplers = make([]sampler.AliasSampler, len(s.affected))
+		s.fenwick = make([]*sampler.FenwickSampler, len(s.affected))
+		if b.samplerFactory != nil {
+			s.custom = make([]sampler.Sampler, len(s.affected))
+		}
+		for k, user := range s.affected {
+			if b.samplerFactory != nil {
+				custom, err := newCustomSampler(b.samplerFactory, b.RandSource, b.stagedWeights(s, user))
+				if err != nil {
+					return nil, errors.Wrapf(err, "cannot rebuild the sampler of user %d", user)
+				}
+				s.custom[k] = custom
+				continue
+			}
+			if b.fenwickSampler(user) != nil {
+				// updated in place by commitChange
+				continue
+			}
+			if c.Kind != setItemWeight && len(s.userItems) >= fenwickMinItems {
+				f, err := sampler.NewFenwickSampler(b.RandSource,
+					userSamplingWeights(b.ItemWeights, s.userItems, s.userWeights))
+				if err != nil {
+					return nil, errors.Wrapf(err, "cannot build the sampler of user %d", user)
+				}
+				s.fenwick[k] = f
+				continue
+			}
+			userSampler, err := b.stageSampler(s, user)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot rebuild the sampler of user %d", user)
+			}
+			s.samplers[k] = userSampler
+		}
+	}
+
+	return s, nil
+}
+
+// stageSampler builds the sampler of a user as it will be once the staged
+// change is committed.
+func (b *Bird) stageSampler(s *stagedChange, user int) (sampler.AliasSampler, error) {
+	return newSamplerFromWeights(b.RandSource, b.stagedWeights(s, user))
+}
+
+// stagedWeights returns the sampling weights of a user's collection as they
+// will be once the staged change is committed.
+func (b *Bird) stagedWeights(s *stagedChange, user int) []float64 {
+	if s.change.Kind != setItemWeight {
+		return userSamplingWeights(b.ItemWeights, s.userItems, s.userWeights)
+	}
+
+	// The item weights cannot be changed before the commit, so the new
+	// weight is applied to the user's sampling weights directly.
+	userItems := b.UsersToItems[user]
+	var edgeWeights []float64
+	if b.EdgeWeights != nil {
+		edgeWeights = b.EdgeWeights[user]
+	}
+	weights := userSamplingWeights(b.ItemWeights, userItems, edgeWeights)
+	for j, item := range userItems {
+		if item == s.change.Item {
+			weights[j] = s.change.Weight
+			if edgeWeights != nil {
+				weights[j] *= edgeWeights[j]
+			}
+		}
+	}
+
+	return weights
+}
+
+// commitChange installs a staged change, records it in the log and bumps the
+// version. Slices that readers may hold are replaced, never modified, and so
+// are the caches derived from the graph. ConcurrentBird commits under its
+// write lock, so that no reader sees the Bird while it changes.
+func (b *Bird) commitChange(s *stagedChange) {
+	c := s.change
+	r := &s.replaced
+	switch c.Kind {
+	case addInteraction, removeInteraction:
+		r.newUser = c.User == len(b.UsersToItems)
+		if !r.newUser {
+			r.userItems = b.UsersToItems[c.User]
+			if b.EdgeWeights != nil {
+				r.userWeights = b.EdgeWeights[c.User]
+			}
+		}
+		r.itemUsers = b.itemUsers(c.Item)
+		if b.referrerWeights != nil {
+			r.referrerWeights = b.referrerWeights[c.Item]
+			r.referrerSampler = b.referrerSamplers[c.Item]
+		}
+		if r.newUser {
+			b.UsersToItems = append(b.UsersToItems, nil)
+			b.userItemsSamplers = append(b.userItemsSamplers, sampler.AliasSampler{})
+			if b.customSamplers != nil {
+				b.customSamplers = append(b.customSamplers, nil)
+			}
+			if b.EdgeWeights != nil {
+				b.EdgeWeights = append(b.EdgeWeights, nil)
+			}
+		}
+		b.UsersToItems[c.User] = s.userItems
+		if b.EdgeWeights != nil {
+			b.EdgeWeights[c.User] = s.userWeights
+		}
+		b.setItemUsers(c.Item, s.itemUsers)
+		if b.referrerWeights != nil {
+			b.referrerWeights[c.Item] = s.referrerWeights
+			b.referrerSamplers[c.Item] = s.referrerSampler
+		}
+
+	case setItemWeight:
+		r.itemWeights = b.ItemWeights
+		b.ItemWeights = append([]float64(nil), b.ItemWeights...)
+		b.ItemWeights[c.Item] = c.Weight
+	}
+
+	if s.withSamplers {
+		for _, user := range s.affected {
+			if f := b.fenwickSampler(user); f != nil {
+				b.commitFenwick(f, user, s)
+			}
+		}
+		b.commitSamplers(s)
+	}
+
+	b.changes = append(b.changes, c)
+	b.version++
+	b.caches = &graphCaches{}
+}
+
+// commitSamplers installs the samplers staged for the affected users.
+func (b *Bird) commitSamplers(s *stagedChange) {
+	for k := range s.samplers {
+		b.userItemsSamplers[s.affected[k]] = s.samplers[k]
+	}
+	for k, custom := range s.custom {
+		b.customSamplers[s.affected[k]] = custom
+	}
+	for k, f := range s.fenwick {
+		if f == nil {
+			continue
+		}
+		if b.fenwickSamplers == nil {
+			b.fenwickSamplers = make(map[int]*sampler.FenwickSampler)
+		}
+		b.fenwickSamplers[s.affected[k]] = f
+	}
+}
+
+// revertChange undoes the last committed change, which must have been staged
+// without samplers, and removes it from the log.
+func (b *Bird) revertChange(s *stagedChange) {
+	c, r := s.change, s.replaced
+	switch c.Kind {
+	case addInteraction, removeInteraction:
+		b.setItemUsers(c.Item, r.itemUsers)
+		if b.referrerWeights != nil {
+			b.referrerWeights[c.Item] = r.referrerWeights
+			b.referrerSamplers[c.Item] = r.referrerSampler
+		}
+		if !r.newUser {
+			b.UsersToItems[c.User] = r.userItems
+			if b.EdgeWeights != nil {
+				b.EdgeWeights[c.User] = r.userWeights
+			}
+			break
+		}
+		numUsers := len(b.UsersToItems) - 1
+		b.UsersToItems = b.UsersToItems[:numUsers]
+		b.userItemsSamplers = b.userItemsSamplers[:numUsers]
+		if b.customSamplers != nil {
+			b.customSamplers = b.customSamplers[:numUsers]
+		}
+		if b.EdgeWeights != nil {
+			b.EdgeWeights = b.EdgeWeights[:numUsers]
+		}
+
+	case setItemWeight:
+		b.ItemWeights = r.itemWeights
+	}
+
+	b.changes = b.changes[:len(b.changes)-1]
+	b.version--
+	b.caches = &graphCaches{}
+}
+
+// stageSamplers builds the samplers of the given users from the graph and the
+// weights as they are, without installing them. The users sampled with a
+// FenwickSampler get a new one, so that the current one is left untouched
+// until commitSamplers.
+func (b *Bird) stageSamplers(users map[int]bool) (*stagedChange, error) {
+	s := &stagedChange{}
+	if b.Cfg.UniformUserSampling {
+		return s, nil
+	}
+
+	for user := range users {
+		s.affected = append(s.affected, user)
+	}
+	sort.Ints(s.affected)
+	s.samplers = make([]sampler.AliasSampler, len(s.affected))
+	s.fenwick = make([]*sampler.FenwickSampler, len(s.affected))
+	if b.samplerFactory != nil {
+		s.custom = make([]sampler.Sampler, len(s.affected))
+	}
+	for k, user := range s.affected {
+		var edgeWeights []float64
+		if b.EdgeWeights != nil {
+			edgeWeights = b.EdgeWeights[user]
+		}
+		weights := userSamplingWeights(b.ItemWeights, b.UsersToItems[user], edgeWeights)
+
+		var err error
+		switch {
+		case b.samplerFactory != nil:
+			s.custom[k], err = newCustomSampler(b.samplerFactory, b.RandSource, weights)
+		case b.fenwickSampler(user) != nil && len(weights) == 0:
+			s.fenwick[k] = &sampler.FenwickSampler{Source: b.RandSource}
+		case b.fenwickSampler(user) != nil:
+			s.fenwick[k], err = sampler.NewFenwickSampler(b.RandSource, weights)
+		default:
+			s.samplers[k], err = newSamplerFromWeights(b.RandSource, weights)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot rebuild the sampler of user %d", user)
+		}
+	}
+
+	return s, nil
+}
+
+// removeAt returns a copy of list without the element at index i.
+func removeAt(list []int, i int) []int {
+	return append(append([]int{}, list[:i]...), list[i+1:]...)
+}
+
+// DiscardChanges forgets the changes up to version v included; they can no
+// longer be saved with SaveDelta. The changes are otherwise kept in memory
+// indefinitely.
+func (b *Bird) DiscardChanges(v Version) {
+	if v > b.version {
+		v = b.version
+	}
+	if v <= b.changesFrom {
+		return
+	}
+	b.changes = append(b.changes[:0:0], b.changes[v-b.changesFrom:]...)
+	b.changesFrom = v
+}
+
+// indexOf returns the index of the first occurrence of value in the list, or
+// -1 if it is absent.
+func indexOf(list []int, value int) int {
+	for i, v := range list {
+		if v == value {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,129 @@
+package birdland
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestBirdIncrementalUpdates(t *testing.T) {
+	itemWeights := []float64{1, 1, 1}
+	bird, err := NewBird(NewBirdCfg(), itemWeights, [][]int{[]int{0, 1}, []int{1}})
+	if err != nil {
+		t.Fatalf("Incremental: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	if err := bird.AddInteraction(1, 2); err != nil {
+		t.Errorf("Incremental: AddInteraction should not have raised an error but did: %v", err)
+	}
+	if err := bird.AddInteraction(2, 0); err != nil {
+		t.Errorf("Incremental: adding a new user should not have raised an error but did: %v", err)
+	}
+	if err := bird.RemoveInteraction(0, 1); err != nil {
+		t.Errorf("Incremental: RemoveInteraction should not have raised an error but did: %v", err)
+	}
+	if err := bird.SetItemWeight(0, 5); err != nil {
+		t.Errorf("Incremental: SetItemWeight should not have raised an error but did: %v", err)
+	}
+	if bird.ItemWeights[0] != 5 || itemWeights[0] != 1 {
+		t.Errorf("Incremental: expected the weights of the Bird to be replaced and not those it was created with, got %v and %v",
+			bird.ItemWeights, itemWeights)
+	}
+
+	expectedUsersToItems := [][]int{[]int{0}, []int{1, 2}, []int{0}}
+	if !reflect.DeepEqual(bird.UsersToItems, expectedUsersToItems) {
+		t.Errorf("Incremental: expected users to items %v, got %v", expectedUsersToItems, bird.UsersToItems)
+	}
+	expectedItemsToUsers := [][]int{[]int{0, 2}, []int{1}, []int{1}}
+	if !reflect.DeepEqual(bird.ItemsToUsers, expectedItemsToUsers) {
+		t.Errorf("Incremental: expected items to users %v, got %v", expectedItemsToUsers, bird.ItemsToUsers)
+	}
+	for u, userItems := range bird.UsersToItems {
+		if len(bird.userItemsSamplers[u].AliasTable) != len(userItems) {
+			t.Errorf("Incremental: the sampler of user %d was not rebuilt", u)
+		}
+	}
+	if bird.Version() != 4 {
+		t.Errorf("Incremental: expected version 4, got %d", bird.Version())
+	}
+
+	invalid := map[string]error{
+		"Unknown item":           bird.AddInteraction(0, 3),
+		"Unknown user":           bird.AddInteraction(5, 0),
+		"Missing interaction":    bird.RemoveInteraction(0, 2),
+		"Negative weight":        bird.SetItemWeight(1, -1),
+		"NaN weight":             bird.SetItemWeight(1, math.NaN()),
+		"Infinite weight":        bird.SetItemWeight(1, math.Inf(1)),
+		"Weight of unknown item": bird.SetItemWeight(3, 1),
+	}
+	for name, err := range invalid {
+		if err == nil {
+			t.Errorf("Incremental: %s: should have raised an error but did not", name)
+		}
+	}
+	if bird.Version() != 4 {
+		t.Errorf("Incremental: failed updates should not change the version, got %d", bird.Version())
+	}
+}
+
+func TestBirdIncrementalFenwick(t *testing.T) {
+	// user 0 collects fenwickMinItems - 1 copies of item 0, user 1 has item 1.
+	itemWeights := []float64{1, 1, 1}
+	collection := make([]int, fenwickMinItems-1)
+	bird, err := NewBird(NewBirdCfg(), itemWeights, [][]int{collection, []int{1}})
+	if err != nil {
+		t.Fatalf("Fenwick: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	if err := bird.AddInteraction(0, 1); err != nil {
+		t.Fatalf("Fenwick: AddInteraction should not have raised an error but did: %v", err)
+	}
+	if bird.fenwickSampler(0) == nil || bird.fenwickSampler(1) != nil {
+		t.Fatalf("Fenwick: only the collection of user 0 should have reached the threshold")
+	}
+	if err := bird.AddInteraction(0, 2); err != nil {
+		t.Fatalf("Fenwick: AddInteraction should not have raised an error but did: %v", err)
+	}
+	if err := bird.RemoveInteraction(0, 0); err != nil {
+		t.Fatalf("Fenwick: RemoveInteraction should not have raised an error but did: %v", err)
+	}
+	// items 1 and 2 now weigh as much as the fenwickMinItems - 2 copies of
+	// item 0 together.
+	if err := bird.SetItemWeight(1, float64(fenwickMinItems-2)); err != nil {
+		t.Fatalf("Fenwick: SetItemWeight should not have raised an error but did: %v", err)
+	}
+	if err := bird.SetItemWeight(2, float64(fenwickMinItems-2)); err != nil {
+		t.Fatalf("Fenwick: SetItemWeight should not have raised an error but did: %v", err)
+	}
+
+	const numSamples = 30000
+	counts := make([]int, len(itemWeights))
+	for i := 0; i < numSamples; i++ {
+		item, err := bird.sampleItem(0)
+		if err != nil {
+			t.Fatalf("Fenwick: sampleItem should not have raised an error but did: %v", err)
+		}
+		counts[item]++
+	}
+	for item, count := range counts {
+		if math.Abs(float64(count)/numSamples-1.0/3) > 0.02 {
+			t.Errorf("Fenwick: expected item %d to be drawn a third of the time, got %d out of %d", item, count, numSamples)
+		}
+	}
+
+	if err := bird.Warmup(); err != nil {
+		t.Errorf("Fenwick: Warmup should not have raised an error but did: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := bird.Save(&buf); err != nil {
+		t.Fatalf("Fenwick: Save should not have raised an error but did: %v", err)
+	}
+	loaded, err := LoadBird(&buf)
+	if err != nil {
+		t.Fatalf("Fenwick: LoadBird should not have raised an error but did: %v", err)
+	}
+	if len(loaded.userItemsSamplers[0].AliasTable) != len(bird.UsersToItems[0]) {
+		t.Errorf("Fenwick: the sampler of user 0 should have been saved as an alias sampler")
+	}
+}
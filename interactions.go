@@ -0,0 +1,79 @@
+package birdland
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// NewBirdWithInteractionTypes creates a new recommender where each user-item
+// interaction has a type, for instance "view", "click" or "purchase", so that
+// the interactions that carry a stronger signal count more. types is aligned
+// with usersToItems: types[u][j] is the type of the interaction of user u with
+// the item usersToItems[u][j]. Each interaction is weighted by the weight of
+// its type in typeWeights, 1 if the type has none, and this weight is used
+// twice:
+//
+//   - as edge weight, as in NewBirdWithEdgeWeights: an item is drawn from a
+//     user's collection with a probability proportional to its global weight
+//     times the weight of the interaction's type;
+//   - as referrer weight, as in SetReferrerWeights: a walk leaves an item
+//     through one of its users with a probability proportional to the
+//     weight of the type of their interaction, the global weights playing
+//     no part.
+//
+// The referrer weights are only set when a type has a weight other than 1,
+// in which case cfg.LazyItemsToUsers cannot be set; like them, the types are
+// not saved with the Bird. The weights are not part of BirdCfg so that
+// configurations can still be compared with ==. A nil types is equivalent to
+// NewBird.
+func NewBirdWithInteractionTypes(cfg *BirdCfg, itemWeights []float64, usersToItems [][]int,
+	types [][]string, typeWeights map[string]float64) (*Bird, error) {
+
+	if types == nil {
+		return NewBird(cfg, itemWeights, usersToItems)
+	}
+
+	weighted := false
+	for name, w := range typeWeights {
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return nil, &InvalidInputError{Err: fmt.Errorf("invalid weight %v for the interaction type %q", w, name)}
+		}
+		weighted = weighted || w != 1
+	}
+
+	if len(types) != len(usersToItems) {
+		return nil, &InvalidInputError{Err: fmt.Errorf("there are %d users in the interaction types but %d in UsersToItems",
+			len(types), len(usersToItems))}
+	}
+	edgeWeights := make([][]float64, len(types))
+	for u, userTypes := range types {
+		if len(userTypes) != len(usersToItems[u]) {
+			return nil, &InvalidInputError{Err: fmt.Errorf("user %d has %d interaction types but %d items",
+				u, len(userTypes), len(usersToItems[u]))}
+		}
+		edgeWeights[u] = make([]float64, len(userTypes))
+		for j, t := range userTypes {
+			w, ok := typeWeights[t]
+			if !ok {
+				w = 1
+			}
+			edgeWeights[u][j] = w
+		}
+	}
+
+	b, err := NewBirdWithEdgeWeights(cfg, itemWeights, usersToItems, edgeWeights)
+	if err != nil {
+		return nil, err
+	}
+	if weighted {
+		// The edge weights are those of the collections as capped by
+		// Cfg.MaxUserItems.
+		if err := b.SetReferrerWeights(b.EdgeWeights); err != nil {
+			return nil, errors.Wrap(err, "cannot weight the referrers by interaction type")
+		}
+	}
+
+	return b, nil
+}
@@ -0,0 +1,64 @@
+package birdland
+
+import (
+	"testing"
+)
+
+func TestNewBirdWithInteractionTypes(t *testing.T) {
+	itemWeights := []float64{1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{0, 2}}
+	types := [][]string{[]string{"view", "purchase"}, []string{"purchase", "view"}}
+	typeWeights := map[string]float64{"purchase": 9}
+
+	bird, err := NewBirdWithInteractionTypes(NewBirdCfg(), itemWeights, usersToItems, types, typeWeights)
+	if err != nil {
+		t.Fatalf("InteractionTypes: Bird initialization should not have raised an error but did: %v", err)
+	}
+	bird.ReSeed(42)
+
+	// User 0 viewed item 0 and bought item 1, and the walks leave item 0
+	// through user 1, who bought it, rather than user 0.
+	items := make([]int, 1000)
+	visits, referrers, err := bird.step(items)
+	if err != nil {
+		t.Fatalf("InteractionTypes: step should not have raised an error but did: %v", err)
+	}
+	counts := make(map[int]int)
+	for _, user := range referrers {
+		counts[user]++
+	}
+	if counts[1] < 850 || counts[1] > 950 {
+		t.Errorf("InteractionTypes: expected about 900 walks through user 1, got %d", counts[1])
+	}
+	counts = make(map[int]int)
+	for i, item := range visits {
+		if referrers[i] == 0 {
+			counts[item]++
+		}
+	}
+	if counts[1] < 5*counts[0] {
+		t.Errorf("InteractionTypes: expected user 0 to lead to item 1 about 9 times as often as to item 0, got %v", counts)
+	}
+
+	plain, err := NewBirdWithInteractionTypes(NewBirdCfg(), itemWeights, usersToItems, types, nil)
+	if err != nil {
+		t.Fatalf("InteractionTypes: Bird initialization should not have raised an error but did: %v", err)
+	}
+	if plain.referrerWeights != nil {
+		t.Errorf("InteractionTypes: types without weights should not weight the referrers")
+	}
+
+	invalid := map[string]struct {
+		Types   [][]string
+		Weights map[string]float64
+	}{
+		"Negative weight": {types, map[string]float64{"view": -1}},
+		"Missing user":    {types[:1], nil},
+		"Missing type":    {[][]string{[]string{"view"}, []string{"view", "view"}}, nil},
+	}
+	for name, ex := range invalid {
+		if _, err := NewBirdWithInteractionTypes(NewBirdCfg(), itemWeights, usersToItems, ex.Types, ex.Weights); err == nil {
+			t.Errorf("InteractionTypes: %s: should have raised an error but did not", name)
+		}
+	}
+}
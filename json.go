@@ -0,0 +1,77 @@
+package birdland
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+const birdJSONVersion = 1
+
+// birdJSON is the JSON representation of a Bird:
+//
+//	{
+//	  "version": 1,
+//	  "config": {"depth": 1, "draws": 1000, "max_visits": 0},
+//	  "item_weights": [1.0, 0.5, ...],
+//	  "users_to_items": [[0, 2], [1], [], ...],
+//	  "edge_weights": [[1.0, 3.0], [2.0], [], ...]
+//	}
+//
+// "item_weights" holds the global weight of each item and "users_to_items"
+// the collection of each user, both indexed by the items' and users' integer
+// ids. "edge_weights" is optional and holds the weight of each interaction,
+// aligned with "users_to_items". The samplers are not part of the format
+// since they can be derived from the weights and the graph.
+type birdJSON struct {
+	Version      int         `json:"version"`
+	Cfg          *BirdCfg    `json:"config"`
+	ItemWeights  []float64   `json:"item_weights"`
+	UsersToItems [][]int     `json:"users_to_items"`
+	EdgeWeights  [][]float64 `json:"edge_weights,omitempty"`
+}
+
+// SaveJSON writes the configuration, item weights and adjacency list of the
+// Bird to w in the JSON format described in birdJSON. The per-user samplers
+// are rebuilt from the item weights when loading, so the user-item weights of
+// a Bird created with NewEmu are not preserved; use Save for those.
+func (b *Bird) SaveJSON(w io.Writer) error {
+	doc := birdJSON{
+		Version:      birdJSONVersion,
+		Cfg:          b.Cfg,
+		ItemWeights:  b.ItemWeights,
+		UsersToItems: b.UsersToItems,
+		EdgeWeights:  b.EdgeWeights,
+	}
+
+	err := json.NewEncoder(w).Encode(doc)
+	if err != nil {
+		return errors.Wrap(err, "cannot encode bird")
+	}
+
+	return nil
+}
+
+// LoadJSON reads a Bird written by SaveJSON and rebuilds its samplers.
+func LoadJSON(r io.Reader) (*Bird, error) {
+	var doc birdJSON
+	err := json.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decode bird")
+	}
+
+	if doc.Version != birdJSONVersion {
+		return nil, errors.Errorf("unsupported format version %d", doc.Version)
+	}
+	if doc.Cfg == nil {
+		return nil, errors.New("missing configuration")
+	}
+
+	b, err := NewBirdWithEdgeWeights(doc.Cfg, doc.ItemWeights, doc.UsersToItems, doc.EdgeWeights)
+	if err != nil {
+		return nil, wrap(err, "invalid bird")
+	}
+
+	return b, nil
+}
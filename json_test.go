@@ -0,0 +1,60 @@
+package birdland
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBirdJSONRoundTrip(t *testing.T) {
+	bird := newPersistTestBird(t)
+
+	var buf bytes.Buffer
+	if err := bird.SaveJSON(&buf); err != nil {
+		t.Fatalf("JSON: SaveJSON should not have raised an error but did: %v", err)
+	}
+
+	loaded, err := LoadJSON(&buf)
+	if err != nil {
+		t.Fatalf("JSON: LoadJSON should not have raised an error but did: %v", err)
+	}
+	if *loaded.Cfg != *bird.Cfg {
+		t.Errorf("JSON: expected config %+v, got %+v", bird.Cfg, loaded.Cfg)
+	}
+
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 2, Weight: 2}}
+	state, err := bird.RandState()
+	if err != nil {
+		t.Fatalf("JSON: RandState should not have raised an error but did: %v", err)
+	}
+	expectedItems, expectedReferrers, err := bird.Process(query)
+	if err != nil {
+		t.Fatalf("JSON: Process should not have raised an error but did: %v", err)
+	}
+	if err := loaded.SetRandState(state); err != nil {
+		t.Fatalf("JSON: SetRandState should not have raised an error but did: %v", err)
+	}
+	items, referrers, err := loaded.Process(query)
+	if err != nil {
+		t.Fatalf("JSON: Process on the loaded Bird should not have raised an error but did: %v", err)
+	}
+	if !reflect.DeepEqual(items, expectedItems) || !reflect.DeepEqual(referrers, expectedReferrers) {
+		t.Errorf("JSON: the loaded Bird does not produce the same walks as the original")
+	}
+}
+
+func TestBirdLoadJSONInvalid(t *testing.T) {
+	documents := map[string]string{
+		"Not JSON":        `bird`,
+		"Unknown version": `{"version": 2, "config": {"depth": 1, "draws": 10}, "item_weights": [1], "users_to_items": [[0]]}`,
+		"Missing config":  `{"version": 1, "item_weights": [1], "users_to_items": [[0]]}`,
+		"Invalid config":  `{"version": 1, "config": {"depth": 0, "draws": 10}, "item_weights": [1], "users_to_items": [[0]]}`,
+		"Unknown item":    `{"version": 1, "config": {"depth": 1, "draws": 10}, "item_weights": [1], "users_to_items": [[0, 1]]}`,
+	}
+	for name, doc := range documents {
+		if _, err := LoadJSON(strings.NewReader(doc)); err == nil {
+			t.Errorf("JSON: %s: LoadJSON should have raised an error but did not", name)
+		}
+	}
+}
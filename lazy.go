@@ -0,0 +1,146 @@
+package birdland
+
+import "sync"
+
+// lazyItemsToUsers holds the users who interacted with each item for a Bird
+// created with Cfg.LazyItemsToUsers. An item's list is only built the first
+// time the item is looked up, so that the items the walks never reach cost
+// nothing.
+type lazyItemsToUsers struct {
+	mu    sync.RWMutex
+	users map[int][]int
+}
+
+func newLazyItemsToUsers() *lazyItemsToUsers {
+	return &lazyItemsToUsers{users: make(map[int][]int)}
+}
+
+// get returns the users of item, and false if they have not been built yet.
+func (l *lazyItemsToUsers) get(item int) ([]int, bool) {
+	l.mu.RLock()
+	users, ok := l.users[item]
+	l.mu.RUnlock()
+
+	return users, ok
+}
+
+// set replaces the users of item.
+func (l *lazyItemsToUsers) set(item int, users []int) {
+	l.mu.Lock()
+	l.users[item] = users
+	l.mu.Unlock()
+}
+
+// load builds the users of the items that have not been built yet. Building
+// them requires a pass over all the users' collections, which is shared by
+// all the items that are loaded together.
+func (l *lazyItemsToUsers) load(usersToItems [][]int, items []int) {
+	missing := make(map[int][]int)
+	l.mu.RLock()
+	for _, item := range items {
+		if _, ok := l.users[item]; !ok {
+			missing[item] = []int{}
+		}
+	}
+	l.mu.RUnlock()
+	if len(missing) == 0 {
+		return
+	}
+
+	// the users are appended in the same order as in permuteAdjacencyList,
+	// so that lazy and eager Birds perform the same walks.
+	for uid, userItems := range usersToItems {
+		for _, iid := range userItems {
+			if users, ok := missing[iid]; ok {
+				missing[iid] = append(users, uid)
+			}
+		}
+	}
+
+	l.mu.Lock()
+	for item, users := range missing {
+		if _, ok := l.users[item]; !ok {
+			l.users[item] = users
+		}
+	}
+	l.mu.Unlock()
+}
+
+// indexItemsToUsers builds ItemsToUsers from UsersToItems, or prepares its
+// lazy counterpart when Cfg.LazyItemsToUsers is set.
+func (b *Bird) indexItemsToUsers() {
+	if b.Cfg.LazyItemsToUsers {
+		b.ItemsToUsers = nil
+		b.lazyItemsToUsers = newLazyItemsToUsers()
+		return
+	}
+
+	// we sacrifice memory for speed by storing the two complementary adjacency lists.
+	b.ItemsToUsers = permuteAdjacencyList(len(b.ItemWeights), b.UsersToItems)
+}
+
+// itemUsers returns the users who interacted with item.
+func (b *Bird) itemUsers(item int) []int {
+	if b.externalItemsToUsers != nil {
+		return b.externalItemsToUsers.Neighbors(item)
+	}
+	if b.lazyItemsToUsers == nil {
+		return b.ItemsToUsers[item]
+	}
+
+	users, ok := b.lazyItemsToUsers.get(item)
+	if !ok {
+		b.lazyItemsToUsers.load(b.UsersToItems, []int{item})
+		users, _ = b.lazyItemsToUsers.get(item)
+	}
+
+	return users
+}
+
+// loadItemUsers builds the users of all the given items at once, which spares
+// a pass over the graph per item when the lists are built lazily.
+func (b *Bird) loadItemUsers(items []int) {
+	if b.lazyItemsToUsers != nil {
+		b.lazyItemsToUsers.load(b.UsersToItems, items)
+	}
+}
+
+// loadQueryItemUsers is like loadItemUsers for the items of a query.
+func (b *Bird) loadQueryItemUsers(query []QueryItem) {
+	if b.lazyItemsToUsers == nil {
+		return
+	}
+
+	items := make([]int, len(query))
+	for i, q := range query {
+		items[i] = q.Item
+	}
+	b.lazyItemsToUsers.load(b.UsersToItems, items)
+}
+
+// allItemsToUsers returns the users who interacted with each item. When the
+// lists are built lazily they are computed from scratch and not kept.
+func (b *Bird) allItemsToUsers() [][]int {
+	if b.externalItemsToUsers != nil {
+		itemsToUsers := make([][]int, len(b.ItemWeights))
+		for item := range itemsToUsers {
+			itemsToUsers[item] = b.externalItemsToUsers.Neighbors(item)
+		}
+		return itemsToUsers
+	}
+	if b.lazyItemsToUsers == nil {
+		return b.ItemsToUsers
+	}
+
+	return permuteAdjacencyList(len(b.ItemWeights), b.UsersToItems)
+}
+
+// setItemUsers replaces the users who interacted with item.
+func (b *Bird) setItemUsers(item int, users []int) {
+	if b.lazyItemsToUsers == nil {
+		b.ItemsToUsers[item] = users
+		return
+	}
+
+	b.lazyItemsToUsers.set(item, users)
+}
@@ -0,0 +1,126 @@
+package birdland
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBirdLazyItemsToUsers(t *testing.T) {
+	itemWeights := []float64{1, 2, 3, 4, 5}
+	usersToItems := [][]int{[]int{0, 1, 2}, []int{1, 3}, []int{0, 2, 3}, []int{}}
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 3, Weight: 2}}
+
+	eagerCfg := NewBirdCfg()
+	eagerCfg.Depth = 3
+	eagerCfg.Draws = 100
+	eager, err := NewBird(eagerCfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("LazyItemsToUsers: Bird initialization should not have raised an error but did: %v", err)
+	}
+	lazyCfg := *eagerCfg
+	lazyCfg.LazyItemsToUsers = true
+	// the updates below replace the users' collections in place.
+	lazy, err := NewBird(&lazyCfg, append([]float64{}, itemWeights...), append([][]int{}, usersToItems...))
+	if err != nil {
+		t.Fatalf("LazyItemsToUsers: Bird initialization should not have raised an error but did: %v", err)
+	}
+	if lazy.ItemsToUsers != nil {
+		t.Errorf("LazyItemsToUsers: ItemsToUsers should not be built, got %v", lazy.ItemsToUsers)
+	}
+
+	eager.ReSeed(42)
+	lazy.ReSeed(42)
+	eagerItems, eagerReferrers, err := eager.Process(query)
+	if err != nil {
+		t.Fatalf("LazyItemsToUsers: Process should not have raised an error but did: %v", err)
+	}
+	lazyItems, lazyReferrers, err := lazy.Process(query)
+	if err != nil {
+		t.Fatalf("LazyItemsToUsers: Process should not have raised an error but did: %v", err)
+	}
+	if !reflect.DeepEqual(eagerItems, lazyItems) || !reflect.DeepEqual(eagerReferrers, lazyReferrers) {
+		t.Errorf("LazyItemsToUsers: lazy and eager Birds should perform the same walks")
+	}
+
+	if !reflect.DeepEqual(eager.Stats(), lazy.Stats()) {
+		t.Errorf("LazyItemsToUsers: expected stats %+v, got %+v", eager.Stats(), lazy.Stats())
+	}
+	if !reflect.DeepEqual(eager.CoOccurrence(3), lazy.CoOccurrence(3)) {
+		t.Errorf("LazyItemsToUsers: expected co-occurrences %v, got %v", eager.CoOccurrence(3), lazy.CoOccurrence(3))
+	}
+	if _, err := lazy.SimilarItems(4, 1); err == nil {
+		t.Errorf("LazyItemsToUsers: item 4 has no users but SimilarItems did not raise an error")
+	}
+
+	for _, b := range []*Bird{eager, lazy} {
+		if err := b.AddInteraction(3, 4); err != nil {
+			t.Fatalf("LazyItemsToUsers: AddInteraction should not have raised an error but did: %v", err)
+		}
+		if err := b.RemoveInteraction(1, 1); err != nil {
+			t.Fatalf("LazyItemsToUsers: RemoveInteraction should not have raised an error but did: %v", err)
+		}
+	}
+	for item := range itemWeights {
+		if !reflect.DeepEqual(eager.itemUsers(item), lazy.itemUsers(item)) {
+			t.Errorf("LazyItemsToUsers: expected users %v for item %d after the updates, got %v",
+				eager.itemUsers(item), item, lazy.itemUsers(item))
+		}
+	}
+}
+
+// benchmarkBirdItemsToUsersMemory builds a Bird and processes a query that
+// only reaches a small part of the catalog; the bytes allocated per operation
+// show the memory saved by building ItemsToUsers lazily.
+func benchmarkBirdItemsToUsersMemory(lazy bool, b *testing.B) {
+	itemWeights, usersToItems := newBenchmarkGraph(100000, 100000)
+	cfg := NewBirdCfg()
+	cfg.LazyItemsToUsers = lazy
+
+	query := make([]QueryItem, 10)
+	for i := range query {
+		query[i] = QueryItem{Item: usersToItems[i][0], Weight: 1}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, _, err := bird.Process(query); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBirdEagerItemsToUsersMemory(b *testing.B) { benchmarkBirdItemsToUsersMemory(false, b) }
+func BenchmarkBirdLazyItemsToUsersMemory(b *testing.B)  { benchmarkBirdItemsToUsersMemory(true, b) }
+
+// benchmarkBirdProcessSeededCold builds a Bird and performs the walks of a
+// query with ProcessSeeded; with lazy set, every new item a walk reaches
+// costs a pass over the graph.
+func benchmarkBirdProcessSeededCold(lazy bool, b *testing.B) {
+	itemWeights, usersToItems := newBenchmarkGraph(10000, 10000)
+	cfg := NewBirdCfg()
+	cfg.LazyItemsToUsers = lazy
+
+	query := make([]QueryItem, 10)
+	for i := range query {
+		query[i] = QueryItem{Item: usersToItems[i][0], Weight: 1}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, _, err := bird.ProcessSeeded(query, 42, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBirdEagerProcessSeededCold(b *testing.B) { benchmarkBirdProcessSeededCold(false, b) }
+func BenchmarkBirdLazyProcessSeededCold(b *testing.B)  { benchmarkBirdProcessSeededCold(true, b) }
@@ -0,0 +1,353 @@
+package birdland
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"reflect"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"github.com/rlouf/birdland/sampler"
+)
+
+// Layout of a mapped Bird file. Everything is stored as flat little-endian
+// 8-byte arrays so that the file can be mapped in memory and used in place:
+//
+//	magic         "BIRM", followed by the version byte and 3 bytes of padding
+//	header        length of the configuration, number of items, number of
+//	              users, number of edges; before format version 2, depth,
+//	              draws, max visits, number of items, number of users, number
+//	              of edges
+//	config        (since format version 2) the JSON encoding of the BirdCfg,
+//	              as in SaveJSON, padded with spaces to a multiple of 8 bytes
+//	item weights  one float64 per item
+//	user offsets  number of users + 1 offsets into the arrays below
+//	user items    the collection of each user, concatenated
+//	probabilities the probability table of each user's sampler, concatenated
+//	aliases       the alias table of each user's sampler, concatenated
+//	item offsets  number of items + 1 offsets into the array below
+//	item users    the users who interacted with each item, concatenated
+//
+// This is the compressed sparse row (CSR) representation of both adjacency
+// lists and of the samplers' tables.
+var mappedMagic = []byte("BIRM")
+
+const mappedFormatVersion byte = 2
+
+// mappedHeaderSize is the size of the magic and of the header, which has 4
+// integers since format version 2 and 6 before.
+func mappedHeaderSize(version byte) int {
+	if version < 2 {
+		return 8 + 6*8
+	}
+	return 8 + 4*8
+}
+
+// SaveMapped writes the Bird to w in a format that can be memory-mapped with
+// OpenMapped. Edge weights are not saved; they are already reflected in the
+// samplers' tables. The whole configuration is saved except Cfg.Aggregator,
+// as with SaveJSON.
+func (b *Bird) SaveMapped(w io.Writer) error {
+	samplers, err := b.aliasSamplers()
+	if err != nil {
+		return err
+	}
+	cfg, err := json.Marshal(b.Cfg)
+	if err != nil {
+		return errors.Wrap(err, "cannot encode the configuration")
+	}
+	for len(cfg)%8 != 0 {
+		cfg = append(cfg, ' ')
+	}
+
+	var numEdges int
+	for _, userItems := range b.UsersToItems {
+		numEdges += len(userItems)
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := &encoder{w: bw}
+
+	enc.writeBytes(mappedMagic)
+	enc.writeBytes([]byte{mappedFormatVersion, 0, 0, 0})
+	enc.writeInt(len(cfg))
+	enc.writeInt(len(b.ItemWeights))
+	enc.writeInt(len(b.UsersToItems))
+	enc.writeInt(numEdges)
+	enc.writeBytes(cfg)
+
+	enc.writeRawFloats(b.ItemWeights)
+	writeOffsets(enc, b.UsersToItems)
+	for _, userItems := range b.UsersToItems {
+		enc.writeRawInts(userItems)
+	}
+	for u, userItems := range b.UsersToItems {
+		if len(samplers[u].ProbabilityTable) != len(userItems) {
+			return errors.Errorf("the sampler of user %d does not match their collection", u)
+		}
+		enc.writeRawFloats(samplers[u].ProbabilityTable)
+	}
+	for u, userItems := range b.UsersToItems {
+		if len(samplers[u].AliasTable) != len(userItems) {
+			return errors.Errorf("the sampler of user %d does not match their collection", u)
+		}
+		enc.writeRawInts(samplers[u].AliasTable)
+	}
+	itemsToUsers := b.allItemsToUsers()
+	writeOffsets(enc, itemsToUsers)
+	for _, itemUsers := range itemsToUsers {
+		enc.writeRawInts(itemUsers)
+	}
+	if enc.err != nil {
+		return errors.Wrap(enc.err, "cannot write bird")
+	}
+
+	return bw.Flush()
+}
+
+// writeOffsets writes the offsets of each list in the concatenation of all
+// lists.
+func writeOffsets(enc *encoder, lists [][]int) {
+	var offset int
+	enc.writeInt(offset)
+	for _, l := range lists {
+		offset += len(l)
+		enc.writeInt(offset)
+	}
+}
+
+// OpenMapped maps a file written by SaveMapped in memory and returns a Bird
+// that works directly off the mapped data: only the slice headers are
+// allocated, not the graph or the samplers' tables. Such a Bird is immutable;
+// modifying its adjacency lists, weights or samplers will crash the program.
+// Close must be called to unmap the file once the Bird is no longer used.
+// The layout of the file is checked, as well as the items, users and alias
+// indices it holds, so that a corrupted file fails to open rather than makes
+// the walks panic; the weights and probabilities are not checked. The Bird
+// always draws from the saved alias tables, whatever Cfg.CompactSamplers and
+// Cfg.LinearSamplerMaxDegree.
+//
+// Mapped files can only be used on 64-bit little-endian platforms.
+func OpenMapped(path string) (*Bird, error) {
+	if !nativeLittleEndian64() {
+		return nil, errors.New("mapped birds require a 64-bit little-endian platform")
+	}
+
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot map %s", path)
+	}
+
+	b, err := newMappedBird(data)
+	if err != nil {
+		unmap()
+		return nil, wrapf(err, "invalid mapped bird %s", path)
+	}
+	b.unmap = unmap
+
+	return b, nil
+}
+
+// Close releases the memory mapping of a Bird opened with OpenMapped. The
+// Bird must not be used afterwards. Close does nothing on other Birds.
+func (b *Bird) Close() error {
+	if b.unmap == nil {
+		return nil
+	}
+	err := b.unmap()
+	b.unmap = nil
+
+	return err
+}
+
+// newMappedBird builds a Bird whose slices point into data.
+func newMappedBird(data []byte) (*Bird, error) {
+	if len(data) < len(mappedMagic)+1 {
+		return nil, errors.New("file is too short")
+	}
+	if !bytes.Equal(data[:len(mappedMagic)], mappedMagic) {
+		return nil, errors.New("not a mapped bird file")
+	}
+	version := data[len(mappedMagic)]
+	if version < 1 || version > mappedFormatVersion {
+		return nil, errors.Errorf("unsupported format version %d", version)
+	}
+	headerSize := mappedHeaderSize(version)
+	if len(data) < headerSize {
+		return nil, errors.New("file is too short")
+	}
+
+	header := make([]int, (headerSize-8)/8)
+	for i := range header {
+		header[i] = int(int64(binary.LittleEndian.Uint64(data[8+8*i:])))
+		if header[i] < 0 {
+			return nil, errors.New("invalid header")
+		}
+	}
+	cfg := &BirdCfg{}
+	cfgSize := 0
+	if version < 2 {
+		cfg.Depth, cfg.Draws, cfg.MaxVisits = header[0], header[1], header[2]
+		header = header[3:]
+	} else {
+		cfgSize = header[0]
+		header = header[1:]
+		if cfgSize%8 != 0 || cfgSize > len(data)-headerSize {
+			return nil, errors.Errorf("invalid configuration length %d", cfgSize)
+		}
+		if err := json.Unmarshal(data[headerSize:headerSize+cfgSize], cfg); err != nil {
+			return nil, errors.Wrap(err, "cannot decode the configuration")
+		}
+	}
+	numItems, numUsers, numEdges := header[0], header[1], header[2]
+	if err := validateBirdCfg(cfg); err != nil {
+		return nil, &InvalidInputError{Err: err}
+	}
+
+	// Each count is bounded by the size of the file first, so that a
+	// corrupted one cannot make the expected size overflow.
+	size := len(data) - headerSize - cfgSize
+	if numItems > size/8 || numUsers > size/8 || numEdges > size/8 {
+		return nil, errors.New("invalid header")
+	}
+	expectedSize := 8 * (numItems + (numUsers + 1) + 3*numEdges + (numItems + 1) + numEdges)
+	if size != expectedSize {
+		return nil, errors.Errorf("expected %d bytes, got %d", headerSize+cfgSize+expectedSize, len(data))
+	}
+
+	body := data[headerSize+cfgSize:]
+	next := func(n int) []byte {
+		chunk := body[:8*n]
+		body = body[8*n:]
+		return chunk
+	}
+	itemWeights := bytesToFloats(next(numItems))
+	userOffsets := bytesToInts(next(numUsers + 1))
+	userItems := bytesToInts(next(numEdges))
+	probabilities := bytesToFloats(next(numEdges))
+	aliases := bytesToInts(next(numEdges))
+	itemOffsets := bytesToInts(next(numItems + 1))
+	itemUsers := bytesToInts(next(numEdges))
+
+	usersToItems, err := splitOffsets(userItems, userOffsets)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid user offsets")
+	}
+	itemsToUsers, err := splitOffsets(itemUsers, itemOffsets)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid item offsets")
+	}
+	if err := checkMappedIndices(usersToItems, itemsToUsers, aliases, userOffsets); err != nil {
+		return nil, err
+	}
+
+	randSource := newRandSource()
+	samplers := make([]sampler.AliasSampler, numUsers)
+	for u := range samplers {
+		if userOffsets[u] == userOffsets[u+1] {
+			continue
+		}
+		samplers[u] = sampler.AliasSampler{
+			ProbabilityTable: probabilities[userOffsets[u]:userOffsets[u+1]:userOffsets[u+1]],
+			AliasTable:       aliases[userOffsets[u]:userOffsets[u+1]:userOffsets[u+1]],
+			Source:           randSource,
+		}
+	}
+
+	b := Bird{
+		Cfg:               cfg,
+		RandSource:        randSource,
+		ItemWeights:       itemWeights,
+		UsersToItems:      usersToItems,
+		ItemsToUsers:      itemsToUsers,
+		userItemsSamplers: samplers,
+		caches:            &graphCaches{},
+	}
+
+	return &b, nil
+}
+
+// checkMappedIndices checks that the items of the users' collections, the
+// users of the items and the alias tables of the samplers index existing
+// items, users and collection elements.
+func checkMappedIndices(usersToItems, itemsToUsers [][]int, aliases, userOffsets []int) error {
+	for u, userItems := range usersToItems {
+		for _, item := range userItems {
+			if item < 0 || item >= len(itemsToUsers) {
+				return errors.Errorf("user %d refers to item %d, which does not exist", u, item)
+			}
+		}
+		for _, alias := range aliases[userOffsets[u]:userOffsets[u+1]] {
+			if alias < 0 || alias >= len(userItems) {
+				return errors.Errorf("the alias table of user %d is out of range", u)
+			}
+		}
+	}
+	for i, itemUsers := range itemsToUsers {
+		for _, user := range itemUsers {
+			if user < 0 || user >= len(usersToItems) {
+				return errors.Errorf("item %d refers to user %d, which does not exist", i, user)
+			}
+		}
+	}
+
+	return nil
+}
+
+// splitOffsets slices values into lists according to the offsets, without
+// copying. The capacity of each list is clamped so appending to it cannot
+// overwrite its neighbour.
+func splitOffsets(values, offsets []int) ([][]int, error) {
+	if offsets[0] != 0 || offsets[len(offsets)-1] != len(values) {
+		return nil, errors.New("offsets do not cover the values")
+	}
+
+	lists := make([][]int, len(offsets)-1)
+	for i := range lists {
+		start, end := offsets[i], offsets[i+1]
+		if end < start || end > len(values) {
+			return nil, errors.Errorf("offsets are not sorted at index %d", i)
+		}
+		lists[i] = values[start:end:end]
+	}
+
+	return lists, nil
+}
+
+// nativeLittleEndian64 tells whether the in-memory representation of an int
+// is an 8-byte little-endian integer, which is required to use the mapped
+// arrays in place.
+func nativeLittleEndian64() bool {
+	var x int = 1
+	return unsafe.Sizeof(x) == 8 && *(*byte)(unsafe.Pointer(&x)) == 1
+}
+
+// bytesToInts reinterprets a byte slice as a slice of ints without copying.
+func bytesToInts(b []byte) []int {
+	values := []int{}
+	if len(b) > 0 {
+		h := (*reflect.SliceHeader)(unsafe.Pointer(&values))
+		h.Data = uintptr(unsafe.Pointer(&b[0]))
+		h.Len = len(b) / 8
+		h.Cap = len(b) / 8
+	}
+
+	return values
+}
+
+// bytesToFloats reinterprets a byte slice as a slice of float64 without
+// copying.
+func bytesToFloats(b []byte) []float64 {
+	values := []float64{}
+	if len(b) > 0 {
+		h := (*reflect.SliceHeader)(unsafe.Pointer(&values))
+		h.Data = uintptr(unsafe.Pointer(&b[0]))
+		h.Len = len(b) / 8
+		h.Cap = len(b) / 8
+	}
+
+	return values
+}
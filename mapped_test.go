@@ -0,0 +1,152 @@
+package birdland
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func saveMappedTestBird(t *testing.T, bird *Bird) string {
+	dir, err := ioutil.TempDir("", "birdland")
+	if err != nil {
+		t.Fatalf("Mapped: cannot create temporary directory: %v", err)
+	}
+	path := filepath.Join(dir, "bird.bin")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Mapped: cannot create file: %v", err)
+	}
+	defer f.Close()
+
+	if err := bird.SaveMapped(f); err != nil {
+		t.Fatalf("Mapped: SaveMapped should not have raised an error but did: %v", err)
+	}
+
+	return path
+}
+
+func TestBirdOpenMapped(t *testing.T) {
+	bird := newPersistTestBird(t)
+	path := saveMappedTestBird(t, bird)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	mapped, err := OpenMapped(path)
+	if err != nil {
+		t.Fatalf("Mapped: OpenMapped should not have raised an error but did: %v", err)
+	}
+	defer mapped.Close()
+
+	if *mapped.Cfg != *bird.Cfg {
+		t.Errorf("Mapped: expected config %+v, got %+v", bird.Cfg, mapped.Cfg)
+	}
+	if !reflect.DeepEqual(mapped.ItemWeights, bird.ItemWeights) {
+		t.Errorf("Mapped: expected item weights %v, got %v", bird.ItemWeights, mapped.ItemWeights)
+	}
+	for u := range bird.UsersToItems {
+		if len(bird.UsersToItems[u]) == 0 && len(mapped.UsersToItems[u]) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(mapped.UsersToItems[u], bird.UsersToItems[u]) {
+			t.Errorf("Mapped: expected the collection of user %d to be %v, got %v",
+				u, bird.UsersToItems[u], mapped.UsersToItems[u])
+		}
+		if !reflect.DeepEqual(mapped.userItemsSamplers[u].ProbabilityTable, bird.userItemsSamplers[u].ProbabilityTable) ||
+			!reflect.DeepEqual(mapped.userItemsSamplers[u].AliasTable, bird.userItemsSamplers[u].AliasTable) {
+			t.Errorf("Mapped: the sampler of user %d was not restored", u)
+		}
+	}
+	for i := range bird.ItemsToUsers {
+		if len(bird.ItemsToUsers[i]) == 0 && len(mapped.ItemsToUsers[i]) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(mapped.ItemsToUsers[i], bird.ItemsToUsers[i]) {
+			t.Errorf("Mapped: expected the users of item %d to be %v, got %v",
+				i, bird.ItemsToUsers[i], mapped.ItemsToUsers[i])
+		}
+	}
+
+	if _, _, err := mapped.Process([]QueryItem{{Item: 1, Weight: 1}}); err != nil {
+		t.Errorf("Mapped: Process should not have raised an error but did: %v", err)
+	}
+
+	if err := mapped.Close(); err != nil {
+		t.Errorf("Mapped: Close should not have raised an error but did: %v", err)
+	}
+}
+
+func TestBirdOpenMappedTruncated(t *testing.T) {
+	bird := newPersistTestBird(t)
+	path := saveMappedTestBird(t, bird)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Mapped: cannot stat file: %v", err)
+	}
+	if err := os.Truncate(path, info.Size()-8); err != nil {
+		t.Fatalf("Mapped: cannot truncate file: %v", err)
+	}
+
+	if _, err := OpenMapped(path); err == nil {
+		t.Errorf("Mapped: OpenMapped should have raised an error on a truncated file but did not")
+	}
+}
+
+func TestBirdOpenMappedConfig(t *testing.T) {
+	bird := newPersistTestBird(t)
+	bird.Cfg.Dangling = DanglingRestart
+	bird.Cfg.QueryTopK = 2
+	bird.Cfg.ExcludeSelfLoops = true
+	bird.Cfg.DepthMode = DepthGeometric
+	bird.Cfg.Continuation = 0.5
+	path := saveMappedTestBird(t, bird)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	mapped, err := OpenMapped(path)
+	if err != nil {
+		t.Fatalf("Mapped: OpenMapped should not have raised an error but did: %v", err)
+	}
+	defer mapped.Close()
+	if *mapped.Cfg != *bird.Cfg {
+		t.Errorf("Mapped: expected config %+v, got %+v", bird.Cfg, mapped.Cfg)
+	}
+}
+
+func TestBirdOpenMappedCorrupted(t *testing.T) {
+	bird := newPersistTestBird(t)
+	path := saveMappedTestBird(t, bird)
+	defer os.RemoveAll(filepath.Dir(path))
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Mapped: cannot read file: %v", err)
+	}
+
+	// The arrays follow the header and the configuration; the test Bird has
+	// 4 items, 4 users and 6 edges.
+	headerSize := mappedHeaderSize(mappedFormatVersion)
+	arrays := headerSize + int(binary.LittleEndian.Uint64(data[8:]))
+	userItems := arrays + 8*(4+5)
+	aliases := userItems + 8*2*6
+	itemUsers := aliases + 8*(6+5)
+	corruptions := map[string]int{
+		"Item out of range":    userItems,
+		"Alias out of range":   aliases,
+		"User out of range":    itemUsers,
+		"Offset past the end":  arrays + 8*(4+1),
+		"Huge number of items": 16,
+	}
+	for name, offset := range corruptions {
+		corrupted := append([]byte{}, data...)
+		binary.LittleEndian.PutUint64(corrupted[offset:], 1<<40)
+		if err := ioutil.WriteFile(path, corrupted, 0644); err != nil {
+			t.Fatalf("Mapped: cannot write file: %v", err)
+		}
+		if b, err := OpenMapped(path); err == nil {
+			b.Close()
+			t.Errorf("Mapped: %s: OpenMapped should have raised an error but did not", name)
+		}
+	}
+}
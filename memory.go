@@ -0,0 +1,40 @@
+package birdland
+
+import "strconv"
+
+// Sizes, in bytes, of the values held by a Bird on the current platform.
+const (
+	intSize       = strconv.IntSize / 8
+	float64Size   = 8
+	sliceSize     = 3 * intSize // pointer, length and capacity
+	interfaceSize = 2 * intSize // type and pointer
+	samplerSize   = 2*sliceSize + interfaceSize
+)
+
+// EstimateMemory returns the approximate number of bytes taken by a Bird
+// built by NewBird from itemWeights and usersToItems with the default
+// configuration: the weights, both adjacency lists and the alias tables of
+// the users' samplers, which hold a float64 and an int per item of their
+// collection. It can be computed before building the Bird, to check that it
+// fits in memory.
+//
+// The estimate leaves out the slack of the slices grown while building
+// ItemsToUsers and the memory used temporarily while building the samplers,
+// so the actual usage is somewhat higher. Cfg.CompactSamplers halves the
+// size of the alias tables, and Cfg.LazyItemsToUsers saves up to the size of
+// ItemsToUsers.
+func EstimateMemory(itemWeights []float64, usersToItems [][]int) (bytes int64) {
+	var edges int64
+	for _, userItems := range usersToItems {
+		edges += int64(len(userItems))
+	}
+	numItems, numUsers := int64(len(itemWeights)), int64(len(usersToItems))
+
+	bytes += numItems * float64Size             // ItemWeights
+	bytes += numUsers*sliceSize + edges*intSize // UsersToItems
+	bytes += numItems*sliceSize + edges*intSize // ItemsToUsers
+	bytes += numUsers * samplerSize             // userItemsSamplers
+	bytes += edges * (float64Size + intSize)    // alias tables
+
+	return bytes
+}
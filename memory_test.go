@@ -0,0 +1,39 @@
+package birdland
+
+import (
+	"math/rand"
+	"runtime"
+	"testing"
+)
+
+func TestEstimateMemory(t *testing.T) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	rng := rand.New(rand.NewSource(42))
+	itemWeights := make([]float64, 5000)
+	for i := range itemWeights {
+		itemWeights[i] = rng.Float64() + 0.1
+	}
+	usersToItems := make([][]int, 2000)
+	for u := range usersToItems {
+		usersToItems[u] = make([]int, 1+rng.Intn(100))
+		for j := range usersToItems[u] {
+			usersToItems[u][j] = rng.Intn(len(itemWeights))
+		}
+	}
+	bird, err := NewBird(NewBirdCfg(), itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("EstimateMemory: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(bird)
+	actual := float64(after.HeapAlloc) - float64(before.HeapAlloc)
+	estimate := float64(EstimateMemory(itemWeights, usersToItems))
+	if estimate < actual/2 || estimate > actual*2 {
+		t.Errorf("EstimateMemory: expected an estimate within a factor 2 of the %v bytes used, got %v", actual, estimate)
+	}
+}
@@ -0,0 +1,80 @@
+package birdland
+
+import (
+	"github.com/pkg/errors"
+)
+
+// MergeBird returns a new Bird built from the interactions of both a and b,
+// for instance trained on separate partitions of the data. Both must use the
+// same indices for the same items and users; a shard whose indices differ
+// must be remapped first. The weight of an item is the sum of its weights in
+// a and b, a missing item counting as 0, and the collection of a user is
+// their collection in a followed by that in b, so an interaction present in
+// both counts twice. Edge weights are kept if either Bird has some, the
+// interactions of the other one getting a weight of 1.
+//
+// The configurations of a and b must be identical, except for their
+// Aggregator; the merged Bird gets a copy of a's, and a's sampler factory.
+// Its samplers are rebuilt and its version starts from 0.
+func MergeBird(a, b *Bird) (*Bird, error) {
+	if err := checkMergeableCfgs(a.Cfg, b.Cfg); err != nil {
+		return nil, errors.Wrap(err, "cannot merge birds")
+	}
+
+	numItems := len(a.ItemWeights)
+	if len(b.ItemWeights) > numItems {
+		numItems = len(b.ItemWeights)
+	}
+	itemWeights := make([]float64, numItems)
+	for item, w := range a.ItemWeights {
+		itemWeights[item] += w
+	}
+	for item, w := range b.ItemWeights {
+		itemWeights[item] += w
+	}
+
+	numUsers := len(a.UsersToItems)
+	if len(b.UsersToItems) > numUsers {
+		numUsers = len(b.UsersToItems)
+	}
+	usersToItems := make([][]int, numUsers)
+	var edgeWeights [][]float64
+	if a.EdgeWeights != nil || b.EdgeWeights != nil {
+		edgeWeights = make([][]float64, numUsers)
+	}
+	for _, shard := range []*Bird{a, b} {
+		for u, userItems := range shard.UsersToItems {
+			usersToItems[u] = append(usersToItems[u], userItems...)
+			if edgeWeights == nil {
+				continue
+			}
+			for j := range userItems {
+				w := 1.0
+				if shard.EdgeWeights != nil {
+					w = shard.EdgeWeights[u][j]
+				}
+				edgeWeights[u] = append(edgeWeights[u], w)
+			}
+		}
+	}
+
+	cfg := *a.Cfg
+	merged, err := newBird(&cfg, itemWeights, usersToItems, edgeWeights, a.samplerFactory)
+	if err != nil {
+		return nil, wrap(err, "cannot build the merged bird")
+	}
+
+	return merged, nil
+}
+
+// checkMergeableCfgs returns an error if the configurations differ in any
+// field but Aggregator.
+func checkMergeableCfgs(a, b *BirdCfg) error {
+	ca, cb := *a, *b
+	ca.Aggregator, cb.Aggregator = nil, nil
+	if ca != cb {
+		return errors.Errorf("the configurations differ: %+v and %+v", ca, cb)
+	}
+
+	return nil
+}
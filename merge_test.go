@@ -0,0 +1,47 @@
+package birdland
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeBird(t *testing.T) {
+	cfg := NewBirdCfg()
+	a, err := NewBird(cfg, []float64{1, 2, 3}, [][]int{[]int{0, 1}, []int{2}})
+	if err != nil {
+		t.Fatalf("MergeBird: Bird initialization should not have raised an error but did: %v", err)
+	}
+	b, err := NewBirdWithEdgeWeights(NewBirdCfg(), []float64{1, 1, 1, 4}, [][]int{[]int{3}, []int{}, []int{0, 2}},
+		[][]float64{{2}, {}, {3, 4}})
+	if err != nil {
+		t.Fatalf("MergeBird: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	merged, err := MergeBird(a, b)
+	if err != nil {
+		t.Fatalf("MergeBird: should not have raised an error but did: %v", err)
+	}
+	if expected := []float64{2, 3, 4, 4}; !reflect.DeepEqual(merged.ItemWeights, expected) {
+		t.Errorf("MergeBird: expected item weights %v, got %v", expected, merged.ItemWeights)
+	}
+	if expected := [][]int{{0, 1, 3}, {2}, {0, 2}}; !reflect.DeepEqual(merged.UsersToItems, expected) {
+		t.Errorf("MergeBird: expected collections %v, got %v", expected, merged.UsersToItems)
+	}
+	if expected := [][]float64{{1, 1, 2}, {1}, {3, 4}}; !reflect.DeepEqual(merged.EdgeWeights, expected) {
+		t.Errorf("MergeBird: expected edge weights %v, got %v", expected, merged.EdgeWeights)
+	}
+	if len(merged.userItemsSamplers[0].AliasTable) != 3 {
+		t.Errorf("MergeBird: the samplers should have been rebuilt for the merged collections")
+	}
+	if merged.Cfg == a.Cfg {
+		t.Errorf("MergeBird: the merged bird should not share the configuration of the first one")
+	}
+	if _, _, err := merged.Process([]QueryItem{{Item: 3, Weight: 1}}); err != nil {
+		t.Errorf("MergeBird: Process should not have raised an error but did: %v", err)
+	}
+
+	b.Cfg.Depth = 2
+	if _, err := MergeBird(a, b); err == nil {
+		t.Errorf("MergeBird: birds with different configurations should not have been merged")
+	}
+}
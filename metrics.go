@@ -0,0 +1,58 @@
+package birdland
+
+import (
+	"time"
+)
+
+// Metrics receives measures of the random walks, to be forwarded to a
+// monitoring system. Its methods are called from the goroutines performing
+// the walks, possibly concurrently, and must be fast and safe for concurrent
+// use. A nil Metrics on a Bird discards the measures.
+type Metrics interface {
+	// ObserveWalk is given the time taken by a call to Process or
+	// ProcessSeeded, from the sampling of the query to the returned visits.
+	ObserveWalk(d time.Duration)
+	// ObserveDraws is given the number of walks performed by the call.
+	ObserveDraws(n int)
+	// ObserveDistinctItems is given the number of distinct items returned by
+	// the call.
+	ObserveDistinctItems(n int)
+	// IncDeadEnd is called each time a walk reaches a dead end and is
+	// dropped or restarted according to Cfg.Dangling, whatever the method
+	// performing the walks.
+	IncDeadEnd()
+}
+
+// noMetrics discards the measures.
+type noMetrics struct{}
+
+func (noMetrics) ObserveWalk(time.Duration) {}
+func (noMetrics) ObserveDraws(int)          {}
+func (noMetrics) ObserveDistinctItems(int)  {}
+func (noMetrics) IncDeadEnd()               {}
+
+// metrics returns the Metrics of the Bird, which discards the measures when
+// none was set.
+func (b *Bird) metrics() Metrics {
+	if b.Metrics == nil {
+		return noMetrics{}
+	}
+
+	return b.Metrics
+}
+
+// observeProcess reports the measures of a call to Process that started at
+// start and returned items.
+func (b *Bird) observeProcess(start time.Time, items []int) {
+	if b.Metrics == nil {
+		return
+	}
+
+	distinct := make(map[int]struct{})
+	for _, item := range items {
+		distinct[item] = struct{}{}
+	}
+	b.Metrics.ObserveWalk(time.Since(start))
+	b.Metrics.ObserveDraws(b.Cfg.Draws)
+	b.Metrics.ObserveDistinctItems(len(distinct))
+}
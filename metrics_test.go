@@ -0,0 +1,92 @@
+package birdland
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordedMetrics keeps the measures it receives.
+type recordedMetrics struct {
+	sync.Mutex
+	walks    int
+	draws    int
+	distinct int
+	deadEnds int
+}
+
+func (m *recordedMetrics) ObserveWalk(d time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	m.walks++
+}
+
+func (m *recordedMetrics) ObserveDraws(n int) {
+	m.Lock()
+	defer m.Unlock()
+	m.draws += n
+}
+
+func (m *recordedMetrics) ObserveDistinctItems(n int) {
+	m.Lock()
+	defer m.Unlock()
+	m.distinct = n
+}
+
+func (m *recordedMetrics) IncDeadEnd() {
+	m.Lock()
+	defer m.Unlock()
+	m.deadEnds++
+}
+
+func TestBirdMetrics(t *testing.T) {
+	itemWeights := []float64{1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 2}}
+	query := []QueryItem{{Item: 0, Weight: 1}}
+
+	for _, parallelism := range []int{1, 2} {
+		cfg := NewBirdCfg()
+		cfg.Depth = 3
+		cfg.Draws = 100
+		cfg.Dangling = DanglingDrop
+		cfg.Parallelism = parallelism
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("Metrics: Bird initialization should not have raised an error but did: %v", err)
+		}
+		// Walks can only reach item 2 at their second step, and are stuck
+		// there.
+		bird.ItemsToUsers[2] = []int{}
+
+		if _, _, err := bird.Process(query); err != nil {
+			t.Fatalf("Metrics: Process without metrics should not have raised an error but did: %v", err)
+		}
+
+		m := &recordedMetrics{}
+		bird.Metrics = m
+		items, _, err := bird.Process(query)
+		if err != nil {
+			t.Fatalf("Metrics: Process should not have raised an error but did: %v", err)
+		}
+
+		distinct := make(map[int]bool)
+		for _, item := range items {
+			distinct[item] = true
+		}
+		var stuck int
+		for _, item := range items[cfg.Draws : 2*cfg.Draws] {
+			if item == 2 {
+				stuck++
+			}
+		}
+		if m.walks != 1 || m.draws != cfg.Draws {
+			t.Errorf("Metrics: parallelism %d: expected 1 walk of %d draws, got %d walks and %d draws", parallelism, cfg.Draws, m.walks, m.draws)
+		}
+		if m.distinct != len(distinct) {
+			t.Errorf("Metrics: parallelism %d: expected %d distinct items, got %d", parallelism, len(distinct), m.distinct)
+		}
+		if m.deadEnds != stuck {
+			t.Errorf("Metrics: parallelism %d: expected %d dead ends, got %d", parallelism, stuck, m.deadEnds)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package birdland
+
+import "fmt"
+
+// ProcessWithScoreMultipliers processes the query and scores each visited
+// item by its number of visits times its multiplier in mult, items without a
+// multiplier keeping a multiplier of 1. Items are returned in descending
+// order of score, so that a multiplier below 1 demotes an item and a
+// multiplier above 1 promotes it. The multipliers are only applied to the
+// scores and do not change the random walks.
+func (b *Bird) ProcessWithScoreMultipliers(query []QueryItem, mult map[int]float64) ([]ScoredItem, error) {
+	for item, m := range mult {
+		if m < 0 {
+			return nil, fmt.Errorf("negative score multiplier %v for item %d", m, item)
+		}
+	}
+
+	items, _, err := b.Process(query)
+	if err != nil {
+		return nil, wrap(err, "cannot process query")
+	}
+
+	scores := make(map[int]float64)
+	for _, item := range items {
+		scores[item]++
+	}
+	for item := range scores {
+		if m, ok := mult[item]; ok {
+			scores[item] *= m
+		}
+	}
+
+	return rankItems(scores, len(scores)), nil
+}
@@ -0,0 +1,45 @@
+package birdland
+
+import (
+	"testing"
+)
+
+func TestBirdProcessWithScoreMultipliers(t *testing.T) {
+	itemWeights := []float64{1, 10, 1}
+	usersToItems := [][]int{
+		[]int{0, 1},
+		[]int{0, 1, 2},
+	}
+	cfg := NewBirdCfg()
+	cfg.Draws = 1000
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("ProcessWithScoreMultipliers: Bird initialization should not have raised an error but did: %v", err)
+	}
+	query := []QueryItem{{Item: 0, Weight: 1}}
+
+	scored, err := bird.ProcessWithScoreMultipliers(query, nil)
+	if err != nil {
+		t.Fatalf("ProcessWithScoreMultipliers: should not have raised an error but did: %v", err)
+	}
+	if len(scored) == 0 || scored[0].Item != 1 {
+		t.Fatalf("ProcessWithScoreMultipliers: expected item 1 to rank first without multipliers, got %v", scored)
+	}
+
+	scored, err = bird.ProcessWithScoreMultipliers(query, map[int]float64{1: 0.01})
+	if err != nil {
+		t.Fatalf("ProcessWithScoreMultipliers: should not have raised an error but did: %v", err)
+	}
+	if scored[0].Item == 1 {
+		t.Errorf("ProcessWithScoreMultipliers: item 1 was down-weighted but still ranks first: %v", scored)
+	}
+	for i := 1; i < len(scored); i++ {
+		if scored[i-1].Score < scored[i].Score {
+			t.Errorf("ProcessWithScoreMultipliers: items are not sorted by descending score: %v", scored)
+		}
+	}
+
+	if _, err := bird.ProcessWithScoreMultipliers(query, map[int]float64{1: -1}); err == nil {
+		t.Errorf("ProcessWithScoreMultipliers: a negative multiplier should have raised an error but did not")
+	}
+}
@@ -0,0 +1,143 @@
+package birdland
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ScoreNormalization tells how RecommendItemsScored normalizes the scores of
+// the recommended items, which otherwise grow with Cfg.Draws and Cfg.Depth.
+type ScoreNormalization int
+
+const (
+	// ScoresRaw leaves the scores as the aggregator gave them. This is the
+	// default.
+	ScoresRaw ScoreNormalization = iota
+	// ScoresProbability divides the scores by their sum over every visited
+	// item, as ProbabilityScores does. With the number of visits as score,
+	// an item's score is the fraction of the visits that went to it,
+	// whatever the configuration.
+	ScoresProbability
+	// ScoresSoftmax replaces the scores by their softmax over every visited
+	// item at ScoreOptions.Temperature, as SoftmaxScores does.
+	ScoresSoftmax
+	// ScoresMinMax maps the scores of the recommended items to [0, 1], as
+	// MinMaxScores does.
+	ScoresMinMax
+)
+
+// normalize normalizes the scores of every visited item, ranked, according
+// to opts and returns the n first ones that were not seen. The error is
+// ErrNotEnoughItems if opts.Seen leaves fewer than n items.
+func (opts ScoreOptions) normalize(ranked []ScoredItem, n int) ([]ScoredItem, error) {
+	switch opts.Normalization {
+	case ScoresProbability:
+		ProbabilityScores(ranked)
+	case ScoresSoftmax:
+		SoftmaxScores(ranked, opts.Temperature)
+	}
+	if opts.Normalization != ScoresRaw {
+		// Rounding can make different scores equal, whose items must then
+		// be sorted by ascending index as any other ties.
+		sort.SliceStable(ranked, func(i, j int) bool { return ranksBefore(ranked[i], ranked[j]) })
+	}
+	var err error
+	if opts.Seen != nil {
+		ranked = dropSeen(ranked, opts.Seen)
+		if len(ranked) < n {
+			err = ErrNotEnoughItems
+		}
+	}
+	if n < len(ranked) {
+		ranked = ranked[:n]
+	}
+	if opts.Normalization == ScoresMinMax {
+		MinMaxScores(ranked)
+	}
+
+	return ranked, err
+}
+
+// validate checks the normalization and fallback options.
+func (opts ScoreOptions) validate() error {
+	if opts.Normalization < ScoresRaw || opts.Normalization > ScoresMinMax {
+		return fmt.Errorf("unknown score normalization %d", opts.Normalization)
+	}
+	if opts.Normalization == ScoresSoftmax && !(opts.Temperature > 0) {
+		return fmt.Errorf("the softmax temperature must be positive, got %v", opts.Temperature)
+	}
+	if opts.Fallback < FallbackNone || opts.Fallback > FallbackWeight {
+		return fmt.Errorf("unknown fallback policy %d", opts.Fallback)
+	}
+
+	return nil
+}
+
+// ProbabilityScores divides, in place, the scores and their standard errors
+// by the sum of the scores, so that they sum to 1. The scores are left
+// unchanged if their sum is not positive.
+func ProbabilityScores(scored []ScoredItem) {
+	var total float64
+	for _, s := range scored {
+		total += s.Score
+	}
+	if !(total > 0) {
+		return
+	}
+
+	for i := range scored {
+		scored[i].Score /= total
+		scored[i].StdErr /= total
+	}
+}
+
+// SoftmaxScores replaces, in place, each score s by exp(s/temperature)
+// divided by the sum of these exponentials; temperature must be positive. A
+// low temperature concentrates the scores on the best items, a high one evens
+// them out. The standard errors are set to 0 since they cannot be carried
+// over.
+func SoftmaxScores(scored []ScoredItem, temperature float64) {
+	if len(scored) == 0 {
+		return
+	}
+
+	// The exponentials are taken relative to the largest score so that they
+	// do not overflow.
+	largest := math.Inf(-1)
+	for _, s := range scored {
+		largest = math.Max(largest, s.Score)
+	}
+	var total float64
+	for i, s := range scored {
+		scored[i].Score = math.Exp((s.Score - largest) / temperature)
+		scored[i].StdErr = 0
+		total += scored[i].Score
+	}
+	for i := range scored {
+		scored[i].Score /= total
+	}
+}
+
+// MinMaxScores maps, in place, the scores linearly to [0, 1], the lowest
+// score becoming 0 and the highest 1, and scales their standard errors
+// alike. When all the scores are equal they all become 1.
+func MinMaxScores(scored []ScoredItem) {
+	if len(scored) == 0 {
+		return
+	}
+
+	lowest, highest := scored[0].Score, scored[0].Score
+	for _, s := range scored {
+		lowest = math.Min(lowest, s.Score)
+		highest = math.Max(highest, s.Score)
+	}
+	for i, s := range scored {
+		if highest == lowest {
+			scored[i].Score = 1
+			continue
+		}
+		scored[i].Score = (s.Score - lowest) / (highest - lowest)
+		scored[i].StdErr /= highest - lowest
+	}
+}
@@ -0,0 +1,82 @@
+package birdland
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScoreNormalizers(t *testing.T) {
+	scores := func() []ScoredItem {
+		return []ScoredItem{{Item: 0, Score: 6, StdErr: 2}, {Item: 1, Score: 3, StdErr: 1}, {Item: 2, Score: 1, StdErr: 1}}
+	}
+	e := math.E
+
+	cases := []struct {
+		Name      string
+		Normalize func([]ScoredItem)
+		Scores    []float64
+		StdErrs   []float64
+	}{
+		{"Probability", ProbabilityScores, []float64{0.6, 0.3, 0.1}, []float64{0.2, 0.1, 0.1}},
+		{"Softmax", func(s []ScoredItem) { SoftmaxScores(s, 1) }, []float64{e * e * e / (e*e*e + 1 + math.Exp(-2)), 1 / (e*e*e + 1 + math.Exp(-2)), math.Exp(-2) / (e*e*e + 1 + math.Exp(-2))}, []float64{0, 0, 0}},
+		{"Hot softmax", func(s []ScoredItem) { SoftmaxScores(s, 1e9) }, []float64{1. / 3, 1. / 3, 1. / 3}, []float64{0, 0, 0}},
+		{"Min-max", MinMaxScores, []float64{1, 0.4, 0}, []float64{0.4, 0.2, 0.2}},
+	}
+	for _, ex := range cases {
+		scored := scores()
+		ex.Normalize(scored)
+		for i, s := range scored {
+			if math.Abs(s.Score-ex.Scores[i]) > 1e-6 || math.Abs(s.StdErr-ex.StdErrs[i]) > 1e-6 {
+				t.Errorf("ScoreNormalizers: %s: expected scores %v and standard errors %v, got %v", ex.Name, ex.Scores, ex.StdErrs, scored)
+				break
+			}
+		}
+	}
+
+	equal := []ScoredItem{{Item: 0, Score: 2}, {Item: 1, Score: 2}}
+	MinMaxScores(equal)
+	if equal[0].Score != 1 || equal[1].Score != 1 {
+		t.Errorf("ScoreNormalizers: Min-max: expected equal scores to become 1, got %v", equal)
+	}
+}
+
+func TestBirdRecommendItemsScoredNormalization(t *testing.T) {
+	// With a depth of 1 every walk visits item 0 or item 1 with the same
+	// probability, whatever the number of draws.
+	itemWeights := []float64{1, 1}
+	usersToItems := [][]int{[]int{0, 1}}
+	query := []QueryItem{{Item: 0, Weight: 1}}
+
+	for _, draws := range []int{1000, 4000} {
+		cfg := NewBirdCfg()
+		cfg.Draws = draws
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("ScoreNormalization: Bird initialization should not have raised an error but did: %v", err)
+		}
+		bird.ReSeed(42)
+
+		scored, err := bird.RecommendItemsScored(query, 1, ScoreOptions{Normalization: ScoresProbability})
+		if err != nil {
+			t.Fatalf("ScoreNormalization: %d: should not have raised an error but did: %v", draws, err)
+		}
+		if len(scored) != 1 || math.Abs(scored[0].Score-0.5) > 0.1 {
+			t.Errorf("ScoreNormalization: %d: expected the top item to get about half of the visits, got %v", draws, scored)
+		}
+
+		scored, _ = bird.RecommendItemsScored(query, 2, ScoreOptions{Normalization: ScoresMinMax})
+		if len(scored) != 2 || scored[0].Score != 1 || scored[1].Score != 0 {
+			t.Errorf("ScoreNormalization: %d: expected min-max scores of 1 and 0, got %v", draws, scored)
+		}
+
+		invalid := []ScoreOptions{
+			{Normalization: ScoresSoftmax},
+			{Normalization: ScoresMinMax + 1},
+		}
+		for _, opts := range invalid {
+			if _, err := bird.RecommendItemsScored(query, 2, opts); err == nil {
+				t.Errorf("ScoreNormalization: %d: the options %+v should have been rejected", draws, opts)
+			}
+		}
+	}
+}
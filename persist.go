@@ -0,0 +1,385 @@
+package birdland
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+	"github.com/rlouf/birdland/sampler"
+)
+
+// Binary format of a saved Bird. All numbers are little-endian; integers are
+// stored on 8 bytes.
+//
+//	magic     "BIRD"
+//	version   1 byte
+//	config    (since format version 4) the length of the JSON encoding of the
+//	          BirdCfg, as in SaveJSON, followed by it; before, depth, draws
+//	          and max visits only
+//	version   the Version of the Bird (since format version 2)
+//	weights   number of items, then one float64 per item
+//	graph     number of users, then for each user the length of their
+//	          collection followed by the items
+//	edges     (since format version 3) 1 if the Bird has edge weights, 0
+//	          otherwise, then for each user the weights of their interactions
+//	samplers  for each user, the probability table followed by the alias
+//	          table (both have the length of the user's collection)
+//	checksum  CRC-32 (IEEE) of everything above, 4 bytes
+var birdMagic = []byte("BIRD")
+
+const birdFormatVersion byte = 4
+
+// chunkSize bounds the number of elements we allocate ahead of reading them
+// so that a corrupted length cannot trigger a huge allocation.
+const chunkSize = 1 << 16
+
+// Save writes a binary representation of the Bird to w. The ItemsToUsers
+// adjacency list is not saved since it can be derived from UsersToItems, and
+// neither is the state of the random source. The whole configuration is
+// saved except Cfg.Aggregator, as with SaveJSON.
+func (b *Bird) Save(w io.Writer) error {
+	cfg, err := json.Marshal(b.Cfg)
+	if err != nil {
+		return errors.Wrap(err, "cannot encode the configuration")
+	}
+
+	bw := bufio.NewWriter(w)
+	crc := crc32.NewIEEE()
+	enc := &encoder{w: io.MultiWriter(bw, crc)}
+
+	enc.writeBytes(birdMagic)
+	enc.writeBytes([]byte{birdFormatVersion})
+
+	enc.writeInt(len(cfg))
+	enc.writeBytes(cfg)
+	enc.writeUint64(uint64(b.version))
+
+	enc.writeFloats(b.ItemWeights)
+
+	enc.writeInt(len(b.UsersToItems))
+	for _, userItems := range b.UsersToItems {
+		enc.writeInts(userItems)
+	}
+
+	if b.EdgeWeights == nil {
+		enc.writeInt(0)
+	} else {
+		enc.writeInt(1)
+		for _, userWeights := range b.EdgeWeights {
+			enc.writeRawFloats(userWeights)
+		}
+	}
+
+	samplers, err := b.aliasSamplers()
+	if err != nil {
+		return err
+	}
+	for u, userItems := range b.UsersToItems {
+		s := samplers[u]
+		if len(s.ProbabilityTable) != len(userItems) || len(s.AliasTable) != len(userItems) {
+			return errors.Errorf("the sampler of user %d does not match their collection", u)
+		}
+		enc.writeRawFloats(s.ProbabilityTable)
+		enc.writeRawInts(s.AliasTable)
+	}
+	if enc.err != nil {
+		return errors.Wrap(enc.err, "cannot write bird")
+	}
+
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc.Sum32())
+	if _, err := bw.Write(sum[:]); err != nil {
+		return errors.Wrap(err, "cannot write checksum")
+	}
+
+	return bw.Flush()
+}
+
+// LoadBird reads a Bird written by Save. The samplers are restored from their
+// saved tables rather than rebuilt, unless Cfg.CompactSamplers or
+// Cfg.LinearSamplerMaxDegree requires others, and a fresh random source is
+// created. The
+// loaded Bird has the version of the saved one, so deltas saved since then
+// can be applied to it.
+// Truncated or corrupted input results in an error.
+func LoadBird(r io.Reader) (*Bird, error) {
+	crc := crc32.NewIEEE()
+	dec := &decoder{r: io.TeeReader(bufio.NewReader(r), crc)}
+
+	header := dec.readBytes(len(birdMagic) + 1)
+	if dec.err != nil {
+		return nil, errors.Wrap(dec.err, "cannot read header")
+	}
+	if !bytes.Equal(header[:len(birdMagic)], birdMagic) {
+		return nil, errors.New("not a bird file")
+	}
+	formatVersion := header[len(birdMagic)]
+	if formatVersion < 1 || formatVersion > birdFormatVersion {
+		return nil, errors.Errorf("unsupported format version %d", formatVersion)
+	}
+
+	// The configuration is decoded once the checksum has been verified.
+	cfg := &BirdCfg{}
+	var cfgData []byte
+	if formatVersion >= 4 {
+		n := dec.readLength()
+		if n > chunkSize && dec.err == nil {
+			dec.err = errors.Errorf("invalid configuration length %d", n)
+		}
+		cfgData = dec.readBytes(n)
+	} else {
+		cfg.Depth = dec.readInt()
+		cfg.Draws = dec.readInt()
+		cfg.MaxVisits = dec.readInt()
+	}
+	var version Version
+	if formatVersion >= 2 {
+		version = Version(dec.readUint64())
+	}
+
+	itemWeights := dec.readFloats()
+
+	numUsers := dec.readLength()
+	usersToItems := make([][]int, 0, minInt(numUsers, chunkSize))
+	for u := 0; u < numUsers && dec.err == nil; u++ {
+		usersToItems = append(usersToItems, dec.readInts())
+	}
+
+	var edgeWeights [][]float64
+	if formatVersion >= 3 && dec.readInt() == 1 {
+		edgeWeights = make([][]float64, len(usersToItems))
+		for u, userItems := range usersToItems {
+			edgeWeights[u] = dec.readRawFloats(len(userItems))
+		}
+	}
+
+	tables := make([]sampler.AliasSampler, len(usersToItems))
+	for u, userItems := range usersToItems {
+		tables[u].ProbabilityTable = dec.readRawFloats(len(userItems))
+		tables[u].AliasTable = dec.readRawInts(len(userItems))
+	}
+	if dec.err != nil {
+		return nil, errors.Wrap(dec.err, "cannot read bird")
+	}
+
+	expected := crc.Sum32()
+	sum := dec.readBytes(4)
+	if dec.err != nil {
+		return nil, errors.Wrap(dec.err, "cannot read checksum")
+	}
+	if binary.LittleEndian.Uint32(sum) != expected {
+		return nil, errors.New("checksum mismatch, the file is corrupted")
+	}
+	if cfgData != nil {
+		if err := json.Unmarshal(cfgData, cfg); err != nil {
+			return nil, errors.Wrap(err, "cannot decode the configuration")
+		}
+	}
+
+	b, err := restoreBird(cfg, itemWeights, usersToItems, edgeWeights, tables)
+	if err != nil {
+		return nil, err
+	}
+	b.version = version
+	b.changesFrom = version
+
+	return b, nil
+}
+
+// restoreBird assembles a Bird from saved data, checking that the samplers'
+// tables are consistent with the graph. When cfg.CompactSamplers or
+// cfg.LinearSamplerMaxDegree is set, the samplers they require are built from
+// the weights instead, as NewBird would. As with NewBird, the problems of the
+// configuration or of the data are returned as an *InvalidInputError.
+func restoreBird(cfg *BirdCfg, itemWeights []float64, usersToItems [][]int,
+	edgeWeights [][]float64, samplers []sampler.AliasSampler) (*Bird, error) {
+
+	if err := validateBirdCfg(cfg); err != nil {
+		return nil, &InvalidInputError{Err: err}
+	}
+
+	err := validateBirdInputs(itemWeights, usersToItems)
+	if err != nil {
+		return nil, &InvalidInputError{Err: err}
+	}
+
+	err = validateEdgeWeights(usersToItems, edgeWeights)
+	if err != nil {
+		return nil, &InvalidInputError{Err: errors.Wrap(err, "invalid edge weights")}
+	}
+
+	randSource := newRandSource()
+	for u, userItems := range usersToItems {
+		for _, alias := range samplers[u].AliasTable {
+			if alias < 0 || alias >= len(userItems) {
+				return nil, &InvalidInputError{Err: errors.Errorf("the alias table of user %d is out of range", u)}
+			}
+		}
+		if len(userItems) == 0 {
+			samplers[u] = sampler.AliasSampler{}
+			continue
+		}
+		samplers[u].Source = randSource
+	}
+
+	factory := cfgSamplerFactory(cfg)
+	var customSamplers []sampler.Sampler
+	if factory != nil {
+		customSamplers, err = initCustomSamplers(factory, randSource, itemWeights, usersToItems, edgeWeights, cfg.InitWorkers)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot initialize samplers")
+		}
+		samplers = make([]sampler.AliasSampler, len(usersToItems))
+	}
+
+	b := Bird{
+		Cfg:               cfg,
+		RandSource:        randSource,
+		ItemWeights:       itemWeights,
+		UsersToItems:      usersToItems,
+		EdgeWeights:       edgeWeights,
+		userItemsSamplers: samplers,
+		caches:            &graphCaches{},
+		samplerFactory:    factory,
+		customSamplers:    customSamplers,
+	}
+	b.indexItemsToUsers()
+
+	return &b, nil
+}
+
+// encoder writes little-endian numbers and remembers the first error.
+type encoder struct {
+	w   io.Writer
+	buf [8]byte
+	err error
+}
+
+func (e *encoder) writeBytes(p []byte) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.Write(p)
+}
+
+func (e *encoder) writeUint64(v uint64) {
+	binary.LittleEndian.PutUint64(e.buf[:], v)
+	e.writeBytes(e.buf[:])
+}
+
+func (e *encoder) writeInt(v int)       { e.writeUint64(uint64(int64(v))) }
+func (e *encoder) writeFloat(v float64) { e.writeUint64(math.Float64bits(v)) }
+
+// writeInts writes the length of the slice followed by its elements.
+func (e *encoder) writeInts(values []int) {
+	e.writeInt(len(values))
+	e.writeRawInts(values)
+}
+
+func (e *encoder) writeRawInts(values []int) {
+	for _, v := range values {
+		e.writeInt(v)
+	}
+}
+
+// writeFloats writes the length of the slice followed by its elements.
+func (e *encoder) writeFloats(values []float64) {
+	e.writeInt(len(values))
+	e.writeRawFloats(values)
+}
+
+func (e *encoder) writeRawFloats(values []float64) {
+	for _, v := range values {
+		e.writeFloat(v)
+	}
+}
+
+// decoder reads little-endian numbers and remembers the first error. Once an
+// error occurred every read returns a zero value.
+type decoder struct {
+	r   io.Reader
+	buf [8]byte
+	err error
+}
+
+func (d *decoder) readBytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	p := make([]byte, n)
+	_, d.err = io.ReadFull(d.r, p)
+	if d.err == io.EOF {
+		d.err = io.ErrUnexpectedEOF
+	}
+
+	return p
+}
+
+func (d *decoder) readUint64() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	_, d.err = io.ReadFull(d.r, d.buf[:])
+	if d.err != nil {
+		if d.err == io.EOF {
+			d.err = io.ErrUnexpectedEOF
+		}
+		return 0
+	}
+
+	return binary.LittleEndian.Uint64(d.buf[:])
+}
+
+func (d *decoder) readInt() int       { return int(int64(d.readUint64())) }
+func (d *decoder) readFloat() float64 { return math.Float64frombits(d.readUint64()) }
+
+// readLength reads a slice length and checks that it is not negative.
+func (d *decoder) readLength() int {
+	n := d.readInt()
+	if n < 0 && d.err == nil {
+		d.err = errors.Errorf("invalid length %d", n)
+	}
+	if d.err != nil {
+		return 0
+	}
+
+	return n
+}
+
+func (d *decoder) readInts() []int { return d.readRawInts(d.readLength()) }
+
+// readRawInts reads n integers. Memory is allocated in chunks as the data is
+// read so that a corrupted length fails on a short read instead of
+// allocating.
+func (d *decoder) readRawInts(n int) []int {
+	values := make([]int, 0, minInt(n, chunkSize))
+	for i := 0; i < n && d.err == nil; i++ {
+		values = append(values, d.readInt())
+	}
+
+	return values
+}
+
+func (d *decoder) readFloats() []float64 { return d.readRawFloats(d.readLength()) }
+
+// readRawFloats reads n floats, see readRawInts.
+func (d *decoder) readRawFloats(n int) []float64 {
+	values := make([]float64, 0, minInt(n, chunkSize))
+	for i := 0; i < n && d.err == nil; i++ {
+		values = append(values, d.readFloat())
+	}
+
+	return values
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
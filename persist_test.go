@@ -0,0 +1,172 @@
+package birdland
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func newPersistTestBird(t *testing.T) *Bird {
+	cfg := NewBirdCfg()
+	cfg.Depth = 2
+	cfg.Draws = 50
+	cfg.MaxVisits = 80
+
+	itemWeights := []float64{1, 2, 3, 0.5}
+	usersToItems := [][]int{[]int{0, 1}, []int{}, []int{1, 2, 3}, []int{3}}
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("Persist: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	return bird
+}
+
+func TestBirdSaveLoad(t *testing.T) {
+	bird := newPersistTestBird(t)
+
+	var buf bytes.Buffer
+	if err := bird.Save(&buf); err != nil {
+		t.Fatalf("Persist: Save should not have raised an error but did: %v", err)
+	}
+
+	loaded, err := LoadBird(&buf)
+	if err != nil {
+		t.Fatalf("Persist: LoadBird should not have raised an error but did: %v", err)
+	}
+
+	if *loaded.Cfg != *bird.Cfg {
+		t.Errorf("Persist: expected config %+v, got %+v", bird.Cfg, loaded.Cfg)
+	}
+	if !reflect.DeepEqual(loaded.ItemWeights, bird.ItemWeights) {
+		t.Errorf("Persist: expected item weights %v, got %v", bird.ItemWeights, loaded.ItemWeights)
+	}
+	if !reflect.DeepEqual(loaded.UsersToItems, bird.UsersToItems) {
+		t.Errorf("Persist: expected users to items %v, got %v", bird.UsersToItems, loaded.UsersToItems)
+	}
+	if !reflect.DeepEqual(loaded.ItemsToUsers, bird.ItemsToUsers) {
+		t.Errorf("Persist: expected items to users %v, got %v", bird.ItemsToUsers, loaded.ItemsToUsers)
+	}
+	for u := range bird.userItemsSamplers {
+		expected, got := bird.userItemsSamplers[u], loaded.userItemsSamplers[u]
+		if !reflect.DeepEqual(expected.ProbabilityTable, got.ProbabilityTable) ||
+			!reflect.DeepEqual(expected.AliasTable, got.AliasTable) {
+			t.Errorf("Persist: the sampler of user %d was not restored", u)
+		}
+	}
+
+	if _, _, err := loaded.Process([]QueryItem{{Item: 1, Weight: 1}}); err != nil {
+		t.Errorf("Persist: Process on the loaded Bird should not have raised an error but did: %v", err)
+	}
+}
+
+func TestBirdSaveLoadConfig(t *testing.T) {
+	cfg := NewBirdCfg()
+	cfg.Depth = 3
+	cfg.Draws = 50
+	cfg.MaxUserItems = 2
+	cfg.Dangling = DanglingRestart
+	cfg.QueryTopK = 1
+	cfg.QueryNormalization = QueryWeightsSoftmax
+	cfg.ExcludeSelfLoops = true
+	cfg.CompactSamplers = true
+	cfg.LinearSamplerMaxDegree = 1
+	cfg.DepthMode = DepthGeometric
+	cfg.Continuation = 0.5
+	cfg.QuerySamplerCache = 4
+	bird, err := NewBird(cfg, []float64{1, 2, 3, 0.5}, [][]int{[]int{0, 1, 3}, []int{}, []int{1, 2, 3}, []int{3}})
+	if err != nil {
+		t.Fatalf("Persist: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := bird.Save(&buf); err != nil {
+		t.Fatalf("Persist: Save should not have raised an error but did: %v", err)
+	}
+	loaded, err := LoadBird(&buf)
+	if err != nil {
+		t.Fatalf("Persist: LoadBird should not have raised an error but did: %v", err)
+	}
+
+	if *loaded.Cfg != *bird.Cfg {
+		t.Errorf("Persist: expected config %+v, got %+v", bird.Cfg, loaded.Cfg)
+	}
+	for u := range bird.UsersToItems {
+		if reflect.TypeOf(loaded.UserSampler(u)) != reflect.TypeOf(bird.UserSampler(u)) {
+			t.Errorf("Persist: expected user %d to draw from a %T, got %T", u, bird.UserSampler(u), loaded.UserSampler(u))
+		}
+	}
+
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 2, Weight: 1}}
+	bird.ReSeed(42)
+	expected, _, err := bird.Process(query)
+	if err != nil {
+		t.Fatalf("Persist: Process should not have raised an error but did: %v", err)
+	}
+	loaded.ReSeed(42)
+	if got, _, _ := loaded.Process(query); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Persist: expected the loaded Bird to visit %v, got %v", expected, got)
+	}
+}
+
+func TestBirdSaveLoadEdgeWeights(t *testing.T) {
+	itemWeights := []float64{1, 2, 3}
+	usersToItems := [][]int{[]int{0, 1}, []int{}, []int{2}}
+	edgeWeights := [][]float64{[]float64{3, 0.5}, []float64{}, []float64{1}}
+	bird, err := NewBirdWithEdgeWeights(NewBirdCfg(), itemWeights, usersToItems, edgeWeights)
+	if err != nil {
+		t.Fatalf("Persist: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	var binary, jsonDoc, proto bytes.Buffer
+	if err := bird.Save(&binary); err != nil {
+		t.Fatalf("Persist: Save should not have raised an error but did: %v", err)
+	}
+	if err := bird.SaveJSON(&jsonDoc); err != nil {
+		t.Fatalf("Persist: SaveJSON should not have raised an error but did: %v", err)
+	}
+	if err := bird.ExportProto(&proto); err != nil {
+		t.Fatalf("Persist: ExportProto should not have raised an error but did: %v", err)
+	}
+
+	loaders := map[string]func() (*Bird, error){
+		"binary": func() (*Bird, error) { return LoadBird(&binary) },
+		"JSON":   func() (*Bird, error) { return LoadJSON(&jsonDoc) },
+		"proto":  func() (*Bird, error) { return ImportProto(&proto) },
+	}
+	for name, load := range loaders {
+		loaded, err := load()
+		if err != nil {
+			t.Errorf("Persist: %s: loading should not have raised an error but did: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(loaded.EdgeWeights, edgeWeights) {
+			t.Errorf("Persist: %s: expected edge weights %v, got %v", name, edgeWeights, loaded.EdgeWeights)
+		}
+	}
+}
+
+func TestBirdLoadCorrupted(t *testing.T) {
+	bird := newPersistTestBird(t)
+
+	var buf bytes.Buffer
+	if err := bird.Save(&buf); err != nil {
+		t.Fatalf("Persist: Save should not have raised an error but did: %v", err)
+	}
+	data := buf.Bytes()
+
+	corruptions := map[string]func([]byte) []byte{
+		"Empty input":        func(d []byte) []byte { return nil },
+		"Truncated input":    func(d []byte) []byte { return d[:len(d)/2] },
+		"Missing checksum":   func(d []byte) []byte { return d[:len(d)-2] },
+		"Wrong magic":        func(d []byte) []byte { d[0] = 'X'; return d },
+		"Unknown version":    func(d []byte) []byte { d[4] = 42; return d },
+		"Flipped weight bit": func(d []byte) []byte { d[len(d)/2] ^= 1; return d },
+	}
+	for name, corrupt := range corruptions {
+		corrupted := corrupt(append([]byte{}, data...))
+		if _, err := LoadBird(bytes.NewReader(corrupted)); err == nil {
+			t.Errorf("Persist: %s: LoadBird should have raised an error but did not", name)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package birdland
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// PrecomputeSkippedError is returned by Precompute, once every other user has
+// been processed, when the walks of some users could not be performed, for
+// instance because their collection is empty. It should be treated as a
+// warning.
+type PrecomputeSkippedError struct {
+	Skipped int // number of users whose recommendations were not emitted
+}
+
+func (e *PrecomputeSkippedError) Error() string {
+	return fmt.Sprintf("%d users were skipped", e.Skipped)
+}
+
+// Precompute computes the n items most visited by random walks starting from
+// the collection of each of the given users, and passes them to emit along
+// with their number of visits, in descending order. It is meant for batch
+// jobs that compute the recommendations of every user: the users are spread
+// over workers goroutines, each of which reuses its buffers from one user to
+// the next, and the results are streamed to emit instead of being kept.
+//
+// The query of a user contains every item of their collection with a weight
+// of 1. The walks of each user are seeded from a seed drawn once from
+// RandSource and from the user, so that a user's recommendations do not
+// depend on the number of workers. Items already in the user's collection are
+// not left out.
+//
+// emit is never called concurrently, but the users are emitted in no
+// particular order and the slices passed to emit are reused afterwards. An
+// error returned by emit stops Precompute, which returns it. Users whose walks
+// fail are skipped and counted in a *PrecomputeSkippedError.
+func (b *Bird) Precompute(users []int, n int, workers int,
+	emit func(user int, items []int, scores []float64) error) error {
+
+	if n < 1 {
+		return errors.New("the number of items must be greater than or equal to 1")
+	}
+	if workers < 1 {
+		return errors.New("the number of workers must be at least 1")
+	}
+	for _, user := range users {
+		if user < 0 || user >= len(b.UsersToItems) {
+			return fmt.Errorf("user %d does not belong to the graph", user)
+		}
+	}
+
+	seed := b.drawSeed()
+
+	var next int64
+	var stopped int32
+	var skipped int64
+	var emitMu sync.Mutex
+	var emitErr error
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := newPrecomputer(b, n)
+			for atomic.LoadInt32(&stopped) == 0 {
+				i := int(atomic.AddInt64(&next, 1)) - 1
+				if i >= len(users) {
+					return
+				}
+				user := users[i]
+				if err := p.compute(user, seed); err != nil {
+					atomic.AddInt64(&skipped, 1)
+					continue
+				}
+
+				emitMu.Lock()
+				if atomic.LoadInt32(&stopped) == 0 {
+					if err := emit(user, p.items, p.scores); err != nil {
+						emitErr = errors.Wrapf(err, "cannot emit the items of user %d", user)
+						atomic.StoreInt32(&stopped, 1)
+					}
+				}
+				emitMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if emitErr != nil {
+		return emitErr
+	}
+	if skipped > 0 {
+		return &PrecomputeSkippedError{Skipped: int(skipped)}
+	}
+
+	return nil
+}
+
+// precomputer holds the buffers a Precompute worker reuses from one user to
+// the next.
+type precomputer struct {
+	b      *Bird
+	n      int
+	query  []QueryItem
+	walks  []int // item each walk starts from
+	walker *walker
+	counts map[int]float64
+	items  []int
+	scores []float64
+}
+
+func newPrecomputer(b *Bird, n int) *precomputer {
+	return &precomputer{
+		b:      b,
+		n:      n,
+		walks:  make([]int, 0, b.Cfg.Draws),
+		walker: b.newWalker(nil, nil),
+		counts: make(map[int]float64),
+		items:  make([]int, 0, n),
+		scores: make([]float64, 0, n),
+	}
+}
+
+// compute performs the walks of user and leaves the n most visited items and
+// their number of visits in items and scores. The walks advance in lockstep
+// like those of Process, and dead ends are handled according to Cfg.Dangling.
+func (p *precomputer) compute(user int, seed int64) error {
+	b := p.b
+
+	p.query = p.query[:0]
+	for _, item := range b.UsersToItems[user] {
+		p.query = append(p.query, QueryItem{Item: item, Weight: 1})
+	}
+	if len(p.query) == 0 {
+		return fmt.Errorf("user %d has an empty collection", user)
+	}
+	s, err := b.newQuerySampler(p.query)
+	if err != nil {
+		return errors.Wrapf(err, "cannot sample the collection of user %d", user)
+	}
+	b.loadQueryItemUsers(p.query)
+
+	rng := NewSplitMix64(subSeed(seed, user))
+	p.walks = p.walks[:0]
+	for i := 0; i < b.Cfg.Draws; i++ {
+		if item := s.sample(rng); len(b.itemUsers(item)) > 0 {
+			p.walks = append(p.walks, item)
+		}
+	}
+	if len(p.walks) == 0 {
+		return fmt.Errorf("no one has interacted with the items of user %d", user)
+	}
+
+	for item := range p.counts {
+		delete(p.counts, item)
+	}
+	visits := 0
+	p.walker.rng, p.walker.restart = rng, &s
+	err = p.walker.run(p.walks, b.walkDepth(len(p.walks)), func(d int, items, referrers []int) bool {
+		for _, item := range items {
+			if b.Cfg.MaxVisits > 0 && visits == b.Cfg.MaxVisits {
+				return false
+			}
+			if item == deadEnd {
+				continue
+			}
+			p.counts[item]++
+			visits++
+		}
+		return true
+	})
+	if err != nil {
+		return wrapf(err, "cannot perform the walks of user %d", user)
+	}
+
+	p.items, p.scores = p.items[:0], p.scores[:0]
+	for _, scored := range rankItems(p.counts, p.n) {
+		p.items = append(p.items, scored.Item)
+		p.scores = append(p.scores, scored.Score)
+	}
+
+	return nil
+}
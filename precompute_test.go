@@ -0,0 +1,84 @@
+package birdland
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBirdPrecompute(t *testing.T) {
+	itemWeights := []float64{1, 2, 3, 4, 5}
+	usersToItems := [][]int{
+		[]int{0, 1, 2},
+		[]int{1, 3},
+		[]int{0, 2, 3, 4},
+		[]int{},
+		[]int{4},
+	}
+	cfg := NewBirdCfg()
+	cfg.Depth = 2
+	cfg.Draws = 200
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("Precompute: Bird initialization should not have raised an error but did: %v", err)
+	}
+	users := []int{0, 1, 2, 3, 4}
+
+	// scores of every item for every user, which do not depend on how ties
+	// are broken.
+	precomputeAll := func(workers int) map[int]map[int]float64 {
+		results := make(map[int]map[int]float64)
+		bird.ReSeed(42)
+		err := bird.Precompute(users, len(itemWeights), workers, func(user int, items []int, scores []float64) error {
+			if _, ok := results[user]; ok {
+				t.Errorf("Precompute: user %d was emitted twice", user)
+			}
+			results[user] = make(map[int]float64)
+			for i, item := range items {
+				if i > 0 && scores[i-1] < scores[i] {
+					t.Errorf("Precompute: the items of user %d are not sorted by descending score: %v", user, scores)
+				}
+				results[user][item] = scores[i]
+			}
+			return nil
+		})
+		skipped, ok := err.(*PrecomputeSkippedError)
+		if !ok || skipped.Skipped != 1 {
+			t.Errorf("Precompute: expected user 3 to be skipped, got error %v", err)
+		}
+		return results
+	}
+
+	serial := precomputeAll(1)
+	if len(serial) != 4 {
+		t.Errorf("Precompute: expected the items of 4 users, got %v", serial)
+	}
+	if _, ok := serial[3]; ok {
+		t.Errorf("Precompute: user 3 has an empty collection and should not have been emitted")
+	}
+	if parallel := precomputeAll(4); !reflect.DeepEqual(serial, parallel) {
+		t.Errorf("Precompute: the results should not depend on the number of workers, got %v and %v", serial, parallel)
+	}
+
+	calls := 0
+	err = bird.Precompute(users, 2, 4, func(user int, items []int, scores []float64) error {
+		calls++
+		if len(items) > 2 || len(items) != len(scores) {
+			t.Errorf("Precompute: expected at most 2 items and as many scores, got %v and %v", items, scores)
+		}
+		return errors.New("disk full")
+	})
+	if err == nil {
+		t.Errorf("Precompute: the error returned by emit should have been returned")
+	}
+	if calls != 1 {
+		t.Errorf("Precompute: an error returned by emit should stop the job, got %d calls", calls)
+	}
+
+	if err := bird.Precompute([]int{5}, 1, 1, nil); err == nil {
+		t.Errorf("Precompute: user 5 is out of range but no error was raised")
+	}
+	if err := bird.Precompute(users, 0, 1, nil); err == nil {
+		t.Errorf("Precompute: asking for 0 items should have raised an error but did not")
+	}
+}
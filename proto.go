@@ -0,0 +1,391 @@
+package birdland
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"github.com/pkg/errors"
+	"github.com/rlouf/birdland/sampler"
+)
+
+// The model is exported with the protocol buffers wire format following the
+// schema in birdland.proto, so it can be read from any language. We encode it
+// by hand rather than depend on generated code.
+const protoVersion = 1
+
+// Wire types of the protocol buffers encoding.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// Field numbers, see birdland.proto.
+const (
+	birdVersionField     = 1
+	birdConfigField      = 2
+	birdItemWeightsField = 3
+	birdUsersField       = 4
+	birdNumUsersField    = 5
+
+	configDepthField     = 1
+	configDrawsField     = 2
+	configMaxVisitsField = 3
+
+	userItemsField         = 1
+	userProbabilitiesField = 2
+	userAliasesField       = 3
+	userEdgeWeightsField   = 4
+)
+
+// ExportProto writes the Bird to w as a Bird message of birdland.proto. The
+// samplers' tables are included so that the model is reproduced exactly,
+// including the user-item weights of a Bird created with NewEmu.
+func (b *Bird) ExportProto(w io.Writer) error {
+	samplers, err := b.aliasSamplers()
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	var msg protoBuffer
+
+	msg.varintField(birdVersionField, protoVersion)
+	var cfg protoBuffer
+	cfg.varintField(configDepthField, uint64(b.Cfg.Depth))
+	cfg.varintField(configDrawsField, uint64(b.Cfg.Draws))
+	cfg.varintField(configMaxVisitsField, uint64(b.Cfg.MaxVisits))
+	msg.bytesField(birdConfigField, cfg.Bytes())
+	msg.packedDoubles(birdItemWeightsField, b.ItemWeights)
+	msg.varintField(birdNumUsersField, uint64(len(b.UsersToItems)))
+	if _, err := bw.Write(msg.Bytes()); err != nil {
+		return errors.Wrap(err, "cannot write bird")
+	}
+
+	for u, userItems := range b.UsersToItems {
+		var user, field protoBuffer
+		user.packedInts(userItemsField, userItems)
+		user.packedDoubles(userProbabilitiesField, samplers[u].ProbabilityTable)
+		user.packedInts(userAliasesField, samplers[u].AliasTable)
+		if b.EdgeWeights != nil {
+			user.packedDoubles(userEdgeWeightsField, b.EdgeWeights[u])
+		}
+		field.bytesField(birdUsersField, user.Bytes())
+		if _, err := bw.Write(field.Bytes()); err != nil {
+			return errors.Wrapf(err, "cannot write user %d", u)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// ImportProto reads a Bird written by ExportProto, or by any protocol buffers
+// implementation following birdland.proto. Samplers are restored from their
+// tables when present, and rebuilt from the item and edge weights otherwise.
+func ImportProto(r io.Reader) (*Bird, error) {
+	br := bufio.NewReader(r)
+
+	var version, numUsers uint64
+	var cfg *BirdCfg
+	var itemWeights []float64
+	var usersToItems [][]int
+	var edgeWeights [][]float64
+	var tables []sampler.AliasSampler
+	hasTables := true
+	hasEdgeWeights := false
+
+	for {
+		field, wire, err := readTag(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read field")
+		}
+
+		switch {
+		case field == birdVersionField && wire == wireVarint:
+			version, err = binary.ReadUvarint(br)
+		case field == birdNumUsersField && wire == wireVarint:
+			numUsers, err = binary.ReadUvarint(br)
+		case field == birdConfigField && wire == wireBytes:
+			var payload []byte
+			payload, err = readPayload(br)
+			if err == nil {
+				cfg, err = parseProtoConfig(payload)
+			}
+		case field == birdItemWeightsField:
+			itemWeights, err = readDoubles(br, wire, itemWeights)
+		case field == birdUsersField && wire == wireBytes:
+			var payload []byte
+			payload, err = readPayload(br)
+			if err == nil {
+				var userItems []int
+				var userWeights []float64
+				var table sampler.AliasSampler
+				userItems, userWeights, table, err = parseProtoUser(payload)
+				usersToItems = append(usersToItems, userItems)
+				edgeWeights = append(edgeWeights, userWeights)
+				tables = append(tables, table)
+				hasTables = hasTables && (len(userItems) == 0 || len(table.ProbabilityTable) > 0)
+				hasEdgeWeights = hasEdgeWeights || len(userWeights) > 0
+			}
+		default:
+			err = skipField(br, wire)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read field %d", field)
+		}
+	}
+
+	if version == 0 || version > protoVersion {
+		return nil, errors.Errorf("unsupported schema version %d", version)
+	}
+	if cfg == nil {
+		return nil, errors.New("missing configuration")
+	}
+	if numUsers != 0 && numUsers != uint64(len(usersToItems)) {
+		return nil, errors.Errorf("expected %d users, got %d: the model is truncated", numUsers, len(usersToItems))
+	}
+
+	if !hasEdgeWeights {
+		edgeWeights = nil
+	}
+
+	if !hasTables {
+		return NewBirdWithEdgeWeights(cfg, itemWeights, usersToItems, edgeWeights)
+	}
+
+	for u := range tables {
+		if len(tables[u].ProbabilityTable) != len(usersToItems[u]) ||
+			len(tables[u].AliasTable) != len(usersToItems[u]) {
+			return nil, errors.Errorf("the sampler of user %d does not match their collection", u)
+		}
+	}
+	return restoreBird(cfg, itemWeights, usersToItems, edgeWeights, tables)
+}
+
+// parseProtoConfig parses a Config message.
+func parseProtoConfig(payload []byte) (*BirdCfg, error) {
+	cfg := &BirdCfg{}
+	r := bytes.NewReader(payload)
+	for r.Len() > 0 {
+		field, wire, err := readTag(r)
+		if err != nil {
+			return nil, err
+		}
+		var v uint64
+		switch {
+		case wire == wireVarint:
+			v, err = binary.ReadUvarint(r)
+			switch field {
+			case configDepthField:
+				cfg.Depth = int(int64(v))
+			case configDrawsField:
+				cfg.Draws = int(int64(v))
+			case configMaxVisitsField:
+				cfg.MaxVisits = int(int64(v))
+			}
+		default:
+			err = skipField(r, wire)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// parseProtoUser parses a User message.
+func parseProtoUser(payload []byte) ([]int, []float64, sampler.AliasSampler, error) {
+	userItems := []int{}
+	userWeights := []float64{}
+	var table sampler.AliasSampler
+	r := bytes.NewReader(payload)
+	for r.Len() > 0 {
+		field, wire, err := readTag(r)
+		if err != nil {
+			return nil, nil, table, err
+		}
+		switch field {
+		case userItemsField:
+			userItems, err = readInts(r, wire, userItems)
+		case userProbabilitiesField:
+			table.ProbabilityTable, err = readDoubles(r, wire, table.ProbabilityTable)
+		case userAliasesField:
+			table.AliasTable, err = readInts(r, wire, table.AliasTable)
+		case userEdgeWeightsField:
+			userWeights, err = readDoubles(r, wire, userWeights)
+		default:
+			err = skipField(r, wire)
+		}
+		if err != nil {
+			return nil, nil, table, err
+		}
+	}
+
+	return userItems, userWeights, table, nil
+}
+
+// protoBuffer accumulates an encoded message.
+type protoBuffer struct {
+	bytes.Buffer
+}
+
+func (p *protoBuffer) varint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	p.Write(buf[:n])
+}
+
+func (p *protoBuffer) tag(field, wire int) {
+	p.varint(uint64(field<<3 | wire))
+}
+
+func (p *protoBuffer) varintField(field int, v uint64) {
+	p.tag(field, wireVarint)
+	p.varint(v)
+}
+
+func (p *protoBuffer) bytesField(field int, payload []byte) {
+	p.tag(field, wireBytes)
+	p.varint(uint64(len(payload)))
+	p.Write(payload)
+}
+
+// packedInts writes a packed repeated int64 field. Empty fields are omitted.
+func (p *protoBuffer) packedInts(field int, values []int) {
+	if len(values) == 0 {
+		return
+	}
+	var payload protoBuffer
+	for _, v := range values {
+		payload.varint(uint64(int64(v)))
+	}
+	p.bytesField(field, payload.Bytes())
+}
+
+// packedDoubles writes a packed repeated double field. Empty fields are
+// omitted.
+func (p *protoBuffer) packedDoubles(field int, values []float64) {
+	if len(values) == 0 {
+		return
+	}
+	payload := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(payload[8*i:], math.Float64bits(v))
+	}
+	p.bytesField(field, payload)
+}
+
+// protoReader is what we need to decode messages, implemented by both
+// bufio.Reader and bytes.Reader.
+type protoReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+func readTag(r protoReader) (int, int, error) {
+	tag, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return int(tag >> 3), int(tag & 7), nil
+}
+
+// readPayload reads a length-delimited payload. The buffer grows as data is
+// read so that a corrupted length fails on a short read instead of
+// allocating.
+func readPayload(r protoReader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	var payload bytes.Buffer
+	copied, err := io.CopyN(&payload, r, int64(n))
+	if err != nil || uint64(copied) != n {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return payload.Bytes(), nil
+}
+
+// readInts reads a repeated int64 field, packed or not, and appends its
+// values to values.
+func readInts(r protoReader, wire int, values []int) ([]int, error) {
+	switch wire {
+	case wireVarint:
+		v, err := binary.ReadUvarint(r)
+		return append(values, int(int64(v))), err
+	case wireBytes:
+		payload, err := readPayload(r)
+		if err != nil {
+			return nil, err
+		}
+		pr := bytes.NewReader(payload)
+		for pr.Len() > 0 {
+			v, err := binary.ReadUvarint(pr)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, int(int64(v)))
+		}
+		return values, nil
+	}
+
+	return nil, errors.Errorf("unexpected wire type %d for an integer", wire)
+}
+
+// readDoubles reads a repeated double field, packed or not, and appends its
+// values to values.
+func readDoubles(r protoReader, wire int, values []float64) ([]float64, error) {
+	var payload []byte
+	var err error
+	switch wire {
+	case wireFixed64:
+		payload = make([]byte, 8)
+		_, err = io.ReadFull(r, payload)
+	case wireBytes:
+		payload, err = readPayload(r)
+	default:
+		return nil, errors.Errorf("unexpected wire type %d for a double", wire)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(payload)%8 != 0 {
+		return nil, errors.New("truncated packed doubles")
+	}
+
+	for i := 0; i < len(payload); i += 8 {
+		values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(payload[i:])))
+	}
+
+	return values, nil
+}
+
+// skipField skips the value of a field we do not know about.
+func skipField(r protoReader, wire int) error {
+	var err error
+	switch wire {
+	case wireVarint:
+		_, err = binary.ReadUvarint(r)
+	case wireFixed64:
+		_, err = io.CopyN(ioutil.Discard, r, 8)
+	case wireFixed32:
+		_, err = io.CopyN(ioutil.Discard, r, 4)
+	case wireBytes:
+		_, err = readPayload(r)
+	default:
+		err = errors.Errorf("unsupported wire type %d", wire)
+	}
+
+	return err
+}
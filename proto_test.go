@@ -0,0 +1,94 @@
+package birdland
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestBirdProtoRoundTrip(t *testing.T) {
+	birds := map[string]*Bird{"Bird": newPersistTestBird(t)}
+	emu, err := NewEmu(NewBirdCfg(), []float64{1, 1, 1}, []map[int]float64{{0: 1, 1: 10}, {}, {1: 2, 2: 3}})
+	if err != nil {
+		t.Fatalf("Proto: Emu initialization should not have raised an error but did: %v", err)
+	}
+	birds["Emu"] = emu
+
+	for name, bird := range birds {
+		var buf bytes.Buffer
+		if err := bird.ExportProto(&buf); err != nil {
+			t.Fatalf("Proto: %s: ExportProto should not have raised an error but did: %v", name, err)
+		}
+
+		imported, err := ImportProto(&buf)
+		if err != nil {
+			t.Fatalf("Proto: %s: ImportProto should not have raised an error but did: %v", name, err)
+		}
+		if *imported.Cfg != *bird.Cfg {
+			t.Errorf("Proto: %s: expected config %+v, got %+v", name, bird.Cfg, imported.Cfg)
+		}
+		if !reflect.DeepEqual(imported.ItemWeights, bird.ItemWeights) {
+			t.Errorf("Proto: %s: expected weights %v, got %v", name, bird.ItemWeights, imported.ItemWeights)
+		}
+		if !reflect.DeepEqual(imported.UsersToItems, bird.UsersToItems) {
+			t.Errorf("Proto: %s: expected users to items %v, got %v", name, bird.UsersToItems, imported.UsersToItems)
+		}
+
+		query := []QueryItem{{Item: 1, Weight: 1}}
+		state, _ := bird.RandState()
+		expectedItems, expectedReferrers, err := bird.Process(query)
+		if err != nil {
+			t.Fatalf("Proto: %s: Process should not have raised an error but did: %v", name, err)
+		}
+		imported.SetRandState(state)
+		items, referrers, err := imported.Process(query)
+		if err != nil {
+			t.Fatalf("Proto: %s: Process on the imported model should not have raised an error but did: %v", name, err)
+		}
+		if !reflect.DeepEqual(items, expectedItems) || !reflect.DeepEqual(referrers, expectedReferrers) {
+			t.Errorf("Proto: %s: the imported model does not produce the same walks as the original", name)
+		}
+	}
+}
+
+func TestBirdImportProtoWithoutTables(t *testing.T) {
+	var msg, cfg, user protoBuffer
+	msg.varintField(birdVersionField, protoVersion)
+	cfg.varintField(configDepthField, 1)
+	cfg.varintField(configDrawsField, 10)
+	msg.bytesField(birdConfigField, cfg.Bytes())
+	msg.packedDoubles(birdItemWeightsField, []float64{1, 2})
+	user.packedInts(userItemsField, []int{0, 1})
+	msg.bytesField(birdUsersField, user.Bytes())
+
+	bird, err := ImportProto(bytes.NewReader(msg.Bytes()))
+	if err != nil {
+		t.Fatalf("Proto: ImportProto should not have raised an error but did: %v", err)
+	}
+	if len(bird.userItemsSamplers[0].AliasTable) != 2 {
+		t.Errorf("Proto: the sampler of user 0 should have been rebuilt")
+	}
+}
+
+func TestBirdImportProtoInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newPersistTestBird(t).ExportProto(&buf); err != nil {
+		t.Fatalf("Proto: ExportProto should not have raised an error but did: %v", err)
+	}
+	data := buf.Bytes()
+
+	var future protoBuffer
+	future.varintField(birdVersionField, protoVersion+1)
+
+	inputs := map[string][]byte{
+		"Empty input":     {},
+		"Truncated input": data[:len(data)-3],
+		"Missing users":   data[:len(data)-len(data)/4],
+		"Future version":  future.Bytes(),
+	}
+	for name, input := range inputs {
+		if _, err := ImportProto(bytes.NewReader(input)); err == nil {
+			t.Errorf("Proto: %s: ImportProto should have raised an error but did not", name)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package birdland
+
+// Remapping maps the indices of the items and users of a pruned Bird to the
+// indices of the original Bird, and back. Nodes that were removed are mapped
+// to -1 in the OldToNew tables.
+type Remapping struct {
+	OldToNewItems []int
+	NewToOldItems []int
+	OldToNewUsers []int
+	NewToOldUsers []int
+}
+
+// Prune returns a new Bird where the items that fewer than minItemDegree
+// users interacted with, and the users with fewer than minUserDegree items,
+// have been removed. Since removing users lowers the degree of items and vice
+// versa, nodes are removed until every remaining node satisfies both
+// thresholds. Indices are remapped to consecutive integers; the returned
+// Remapping translates between the old and new indices.
+//
+// The samplers of the new Bird are rebuilt from the item and edge weights, so
+// the user-item weights of a Bird created with NewEmu are not preserved.
+func (b *Bird) Prune(minItemDegree, minUserDegree int) (*Bird, *Remapping, error) {
+	itemsToUsers := b.allItemsToUsers()
+	keepItems := make([]bool, len(itemsToUsers))
+	for i := range keepItems {
+		keepItems[i] = true
+	}
+	keepUsers := make([]bool, len(b.UsersToItems))
+	for u := range keepUsers {
+		keepUsers[u] = true
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i, itemUsers := range itemsToUsers {
+			if keepItems[i] && countKept(itemUsers, keepUsers) < minItemDegree {
+				keepItems[i] = false
+				changed = true
+			}
+		}
+		for u, userItems := range b.UsersToItems {
+			if keepUsers[u] && countKept(userItems, keepItems) < minUserDegree {
+				keepUsers[u] = false
+				changed = true
+			}
+		}
+	}
+
+	m := Remapping{}
+	m.OldToNewItems, m.NewToOldItems = remap(keepItems)
+	m.OldToNewUsers, m.NewToOldUsers = remap(keepUsers)
+
+	itemWeights := make([]float64, len(m.NewToOldItems))
+	for newItem, oldItem := range m.NewToOldItems {
+		itemWeights[newItem] = b.ItemWeights[oldItem]
+	}
+
+	usersToItems := make([][]int, len(m.NewToOldUsers))
+	var edgeWeights [][]float64
+	if b.EdgeWeights != nil {
+		edgeWeights = make([][]float64, len(m.NewToOldUsers))
+	}
+	for newUser, oldUser := range m.NewToOldUsers {
+		userItems := make([]int, 0, len(b.UsersToItems[oldUser]))
+		var userWeights []float64
+		for j, oldItem := range b.UsersToItems[oldUser] {
+			if keepItems[oldItem] {
+				userItems = append(userItems, m.OldToNewItems[oldItem])
+				if edgeWeights != nil {
+					userWeights = append(userWeights, b.EdgeWeights[oldUser][j])
+				}
+			}
+		}
+		usersToItems[newUser] = userItems
+		if edgeWeights != nil {
+			edgeWeights[newUser] = userWeights
+		}
+	}
+
+	cfg := *b.Cfg
+	pruned, err := newBird(&cfg, itemWeights, usersToItems, edgeWeights, b.samplerFactory)
+	if err != nil {
+		return nil, nil, wrap(err, "cannot create pruned bird")
+	}
+
+	return pruned, &m, nil
+}
+
+// countKept counts the nodes of the list that are kept.
+func countKept(nodes []int, keep []bool) int {
+	var n int
+	for _, node := range nodes {
+		if keep[node] {
+			n++
+		}
+	}
+
+	return n
+}
+
+// remap assigns consecutive indices to the nodes that are kept.
+func remap(keep []bool) ([]int, []int) {
+	oldToNew := make([]int, len(keep))
+	newToOld := make([]int, 0, len(keep))
+	for old, k := range keep {
+		if !k {
+			oldToNew[old] = -1
+			continue
+		}
+		oldToNew[old] = len(newToOld)
+		newToOld = append(newToOld, old)
+	}
+
+	return oldToNew, newToOld
+}
@@ -0,0 +1,58 @@
+package birdland
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBirdPrune(t *testing.T) {
+	itemWeights := []float64{1, 2, 3, 4, 5}
+	usersToItems := [][]int{
+		[]int{0, 1, 2},
+		[]int{0, 1},
+		[]int{1, 3},
+		[]int{4},
+		[]int{},
+	}
+	bird, err := NewBird(NewBirdCfg(), itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("Prune: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	// Items 2, 3 and 4 have a single user; once they are removed users 2 and
+	// 3 are left with fewer than 2 items, and user 4 never had any.
+	pruned, m, err := bird.Prune(2, 2)
+	if err != nil {
+		t.Fatalf("Prune: should not have raised an error but did: %v", err)
+	}
+
+	if !reflect.DeepEqual(m.NewToOldItems, []int{0, 1}) {
+		t.Errorf("Prune: expected items [0 1] to be kept, got %v", m.NewToOldItems)
+	}
+	if !reflect.DeepEqual(m.OldToNewItems, []int{0, 1, -1, -1, -1}) {
+		t.Errorf("Prune: unexpected item remapping %v", m.OldToNewItems)
+	}
+	if !reflect.DeepEqual(m.NewToOldUsers, []int{0, 1}) {
+		t.Errorf("Prune: expected users [0 1] to be kept, got %v", m.NewToOldUsers)
+	}
+	if !reflect.DeepEqual(m.OldToNewUsers, []int{0, 1, -1, -1, -1}) {
+		t.Errorf("Prune: unexpected user remapping %v", m.OldToNewUsers)
+	}
+
+	if !reflect.DeepEqual(pruned.ItemWeights, []float64{1, 2}) {
+		t.Errorf("Prune: expected weights [1 2], got %v", pruned.ItemWeights)
+	}
+	if !reflect.DeepEqual(pruned.UsersToItems, [][]int{[]int{0, 1}, []int{0, 1}}) {
+		t.Errorf("Prune: unexpected users to items %v", pruned.UsersToItems)
+	}
+	if !reflect.DeepEqual(pruned.ItemsToUsers, [][]int{[]int{0, 1}, []int{0, 1}}) {
+		t.Errorf("Prune: unexpected items to users %v", pruned.ItemsToUsers)
+	}
+	if len(pruned.userItemsSamplers) != 2 {
+		t.Errorf("Prune: expected 2 samplers, got %d", len(pruned.userItemsSamplers))
+	}
+
+	if _, _, err := bird.Prune(10, 1); err == nil {
+		t.Errorf("Prune: pruning every node should have raised an error but did not")
+	}
+}
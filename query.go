@@ -0,0 +1,270 @@
+package birdland
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rlouf/birdland/sampler"
+)
+
+// QueryNormalization tells how the weights of the query items are normalized
+// before being multiplied by the global weights of the items. Items of zero
+// weight keep a zero weight whatever the normalization.
+type QueryNormalization int
+
+const (
+	// QueryWeightsRaw uses the weights of the query items as they are. This
+	// is the default.
+	QueryWeightsRaw QueryNormalization = iota
+	// QueryWeightsL1 divides the weights by their sum. The walks start from
+	// items drawn in proportion to their weight, so it does not change
+	// their distribution, but it bounds the weights whatever their scale.
+	QueryWeightsL1
+	// QueryWeightsSoftmax replaces the positive weights by their softmax,
+	// exp(w) divided by the sum of the exp(w). Adding a constant to the
+	// weights does not change it, so that raw interaction counts that differ
+	// by a few units weigh as they would if they were offset by thousands.
+	QueryWeightsSoftmax
+)
+
+// normalizeQueryWeights normalizes the weights in place.
+func normalizeQueryWeights(weights []float64, normalization QueryNormalization) {
+	if normalization == QueryWeightsRaw {
+		return
+	}
+
+	// The exponentials are taken relative to the largest weight so that
+	// they do not overflow.
+	largest := math.Inf(-1)
+	for _, w := range weights {
+		if w > 0 && w > largest {
+			largest = w
+		}
+	}
+	var total float64
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		if normalization == QueryWeightsSoftmax {
+			weights[i] = math.Exp(w - largest)
+		}
+		total += weights[i]
+	}
+	if total == 0 {
+		return
+	}
+	for i := range weights {
+		weights[i] /= total
+	}
+}
+
+// QueryItemProblem describes why an item of a query cannot start a walk.
+type QueryItemProblem struct {
+	Index  int // position of the item in the query
+	Item   int
+	Reason string
+}
+
+// InvalidQueryError is returned by ValidateQuery and lists every problematic
+// item of the query, in the order of the query. Process and the other query
+// methods return one for the first item whose draws or weight are invalid.
+type InvalidQueryError struct {
+	Problems []QueryItemProblem
+}
+
+func (e *InvalidQueryError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		problems[i] = fmt.Sprintf("item %d at index %d %s", p.Item, p.Index, p.Reason)
+	}
+
+	return "invalid query: " + strings.Join(problems, "; ")
+}
+
+// invalidQueryItem returns an *InvalidQueryError for the single item q at
+// position i of a query.
+func invalidQueryItem(i int, q QueryItem, reason string) error {
+	return &InvalidQueryError{Problems: []QueryItemProblem{{Index: i, Item: q.Item, Reason: reason}}}
+}
+
+// ValidateQuery checks, without performing any walk, that every item of the
+// query belongs to the graph, has a positive combined weight (query weight
+// times global weight) and has been interacted with by someone. Process
+// ignores the items of zero weight and drops the walks that start from items
+// no one has interacted with, so a query that fails validation may still be
+// processed, but part or all of its draws are wasted. The problems are listed
+// in an *InvalidQueryError.
+func (b *Bird) ValidateQuery(query []QueryItem) error {
+	if len(query) == 0 {
+		return &EmptyQueryError{}
+	}
+
+	items := make([]int, 0, len(query))
+	for _, q := range query {
+		if q.Item >= 0 && q.Item < len(b.ItemWeights) {
+			items = append(items, q.Item)
+		}
+	}
+	b.loadItemUsers(items)
+
+	var problems []QueryItemProblem
+	for i, q := range query {
+		reason := ""
+		if err := b.checkQueryItem(q); err != nil {
+			reason = err.Error()
+		} else if q.Draws < 0 {
+			reason = "has a negative number of draws"
+		} else if q.Weight*b.ItemWeights[q.Item] == 0 {
+			reason = "has a zero combined weight"
+		} else if len(b.itemUsers(q.Item)) == 0 {
+			reason = "was not interacted with by anyone"
+		}
+		if reason != "" {
+			problems = append(problems, QueryItemProblem{Index: i, Item: q.Item, Reason: reason})
+		}
+	}
+	if len(problems) > 0 {
+		return &InvalidQueryError{Problems: problems}
+	}
+
+	return nil
+}
+
+// checkQueryRange returns an *ItemOutOfRangeError for the first item of the
+// query that does not belong to the graph.
+func (b *Bird) checkQueryRange(query []QueryItem) error {
+	for i, q := range query {
+		if q.Item < 0 || q.Item >= len(b.ItemWeights) {
+			return &ItemOutOfRangeError{QueryIndex: i, Item: q.Item}
+		}
+	}
+
+	return nil
+}
+
+// checkQueryItem returns an error if the item of q does not belong to the
+// graph or if its combined weight is negative, NaN or infinite.
+func (b *Bird) checkQueryItem(q QueryItem) error {
+	if q.Item < 0 || q.Item >= len(b.ItemWeights) {
+		return errors.New("does not belong to the graph")
+	}
+
+	w := q.Weight * b.ItemWeights[q.Item]
+	if w < 0 {
+		return errors.New("has a negative weight")
+	}
+	if math.IsNaN(w) || math.IsInf(w, 0) {
+		return fmt.Errorf("has an invalid weight %v", w)
+	}
+
+	return nil
+}
+
+// queryStarts returns the start items of the walks assigned to query items
+// by their Draws or by Cfg.GumbelStarts, and the sampler from which the
+// starts of the other walks and the restarts are drawn. The Gumbel starts are
+// drawn from rng.
+func (b *Bird) queryStarts(query []QueryItem, rng sampler.Rand) ([]int, *querySampler, error) {
+	if err := b.checkQueryRange(query); err != nil {
+		return nil, nil, err
+	}
+
+	var fixed []int
+	var free []QueryItem
+	for i, q := range query {
+		if q.Draws < 0 {
+			return nil, nil, invalidQueryItem(i, q, "has a negative number of draws")
+		}
+		if q.Draws == 0 {
+			free = append(free, q)
+			continue
+		}
+		if err := b.checkQueryItem(q); err != nil {
+			return nil, nil, invalidQueryItem(i, q, err.Error())
+		}
+		for k := 0; k < q.Draws; k++ {
+			fixed = append(fixed, q.Item)
+		}
+	}
+	if len(fixed) > b.Cfg.Draws {
+		return nil, nil, &TooManyDrawsError{Draws: len(fixed), Walks: b.Cfg.Draws}
+	}
+	if len(fixed) == 0 && b.Cfg.GumbelStarts > 0 {
+		return b.gumbelStarts(query, rng)
+	}
+
+	if len(fixed) == 0 || len(free) == 0 {
+		free = query
+	}
+	s, err := b.newQuerySampler(free)
+	if err != nil && len(fixed) > 0 && errors.Cause(err) == sampler.ErrZeroTotalWeight {
+		// The items without draws cannot be drawn from.
+		s, err = b.newQuerySampler(query)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fixed, &s, nil
+}
+
+// gumbelStarts shares the walks among Cfg.GumbelStarts query items drawn
+// from rng with the Gumbel-max trick.
+func (b *Bird) gumbelStarts(query []QueryItem, rng sampler.Rand) ([]int, *querySampler, error) {
+	weights, err := b.queryWeights(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	chosen, err := sampler.TopKGumbel(rng, weights, b.Cfg.GumbelStarts)
+	if err != nil {
+		return nil, nil, wrap(err, "cannot draw the starting items")
+	}
+	s, err := newWeightedQuerySampler(query, weights)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fixed := make([]int, b.Cfg.Draws)
+	for i := range fixed {
+		fixed[i] = query[chosen[i%len(chosen)]].Item
+	}
+
+	return fixed, &s, nil
+}
+
+// dropWeakStarts sets to zero the weights of the query items whose users do
+// not overlap enough with the users of the whole query, as set by
+// Cfg.StartJaccardThreshold. Every set of users is included in their union,
+// so the Jaccard index of an item is its number of distinct users over that
+// of the query.
+func (b *Bird) dropWeakStarts(query []QueryItem, weights []float64) {
+	b.loadQueryItemUsers(query)
+
+	queryUsers := make(map[int]struct{})
+	numUsers := make([]int, len(query))
+	for i, q := range query {
+		itemUsers := make(map[int]struct{})
+		for _, user := range b.itemUsers(q.Item) {
+			itemUsers[user] = struct{}{}
+			queryUsers[user] = struct{}{}
+		}
+		numUsers[i] = len(itemUsers)
+	}
+
+	for i, n := range numUsers {
+		if float64(n) <= b.Cfg.StartJaccardThreshold*float64(len(queryUsers)) {
+			weights[i] = 0
+		}
+	}
+}
+
+// shuffle permutes the values at random.
+func shuffle(values []int, rng sampler.Rand) {
+	for i := len(values) - 1; i > 0; i-- {
+		j := rng.Intn(i + 1)
+		values[i], values[j] = values[j], values[i]
+	}
+}
@@ -0,0 +1,283 @@
+package birdland
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestBirdValidateQuery(t *testing.T) {
+	itemWeights := []float64{1, 0, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 2}}
+
+	for _, lazy := range []bool{false, true} {
+		cfg := NewBirdCfg()
+		cfg.LazyItemsToUsers = lazy
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("ValidateQuery: Bird initialization should not have raised an error but did: %v", err)
+		}
+
+		if err := bird.ValidateQuery([]QueryItem{{Item: 0, Weight: 1}, {Item: 2, Weight: 3}}); err != nil {
+			t.Errorf("ValidateQuery: lazy=%v: a valid query should not have raised an error but did: %v", lazy, err)
+		}
+		if err := bird.ValidateQuery(nil); err == nil {
+			t.Errorf("ValidateQuery: lazy=%v: an empty query should have raised an error but did not", lazy)
+		}
+
+		query := []QueryItem{
+			{Item: 0, Weight: 1},
+			{Item: 7, Weight: 1},
+			{Item: 1, Weight: 1},
+			{Item: 3, Weight: 1},
+			{Item: 2, Weight: -1},
+			{Item: 0, Weight: 0},
+			{Item: 2, Weight: math.NaN()},
+			{Item: 2, Weight: 1, Draws: -1},
+		}
+		err = bird.ValidateQuery(query)
+		queryErr, ok := err.(*InvalidQueryError)
+		if !ok {
+			t.Fatalf("ValidateQuery: lazy=%v: expected an *InvalidQueryError, got %v", lazy, err)
+		}
+		var indices []int
+		for _, p := range queryErr.Problems {
+			indices = append(indices, p.Index)
+		}
+		if expected := []int{1, 2, 3, 4, 5, 6, 7}; !reflect.DeepEqual(indices, expected) {
+			t.Errorf("ValidateQuery: lazy=%v: expected problems at indices %v, got %v", lazy, expected, queryErr)
+		}
+	}
+}
+
+func TestBirdProcessOutOfRangeQuery(t *testing.T) {
+	bird, err := NewBird(NewBirdCfg(), []float64{1, 1}, [][]int{[]int{0, 1}})
+	if err != nil {
+		t.Fatalf("ValidateQuery: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	if _, _, err := bird.Process([]QueryItem{{Item: 2, Weight: 1}}); err == nil {
+		t.Errorf("ValidateQuery: Process should have rejected an item that does not belong to the graph")
+	}
+}
+
+func TestBirdQueryDraws(t *testing.T) {
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 2, 3}}
+	cfg := NewBirdCfg()
+	cfg.Draws = 100
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("QueryDraws: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	cases := []struct {
+		Name     string
+		Query    []QueryItem
+		Expected map[int]int // number of walks from each item, -1 if left to chance
+	}{
+		{"Some draws", []QueryItem{{Item: 0, Weight: 1, Draws: 30}, {Item: 3, Weight: 1}}, map[int]int{0: 30, 3: 70}},
+		{"All draws", []QueryItem{{Item: 0, Weight: 1, Draws: 100}, {Item: 3, Weight: 0, Draws: 0}}, map[int]int{0: 100}},
+		{"Remaining draws", []QueryItem{{Item: 0, Weight: 1, Draws: 30}, {Item: 3, Weight: 1, Draws: 20}}, map[int]int{0: -1, 3: -1}},
+	}
+	for _, ex := range cases {
+		starts, err := bird.sampleItemsFromQuery(ex.Query)
+		if err != nil {
+			t.Fatalf("QueryDraws: %s: sampling the query should not have raised an error but did: %v", ex.Name, err)
+		}
+		counts := make(map[int]int)
+		for _, item := range starts {
+			counts[item]++
+		}
+		if len(counts) != len(ex.Expected) {
+			t.Errorf("QueryDraws: %s: expected walks to start from %d items, got %v", ex.Name, len(ex.Expected), counts)
+		}
+		for item, n := range ex.Expected {
+			if n >= 0 && counts[item] != n {
+				t.Errorf("QueryDraws: %s: expected %d walks from item %d, got %d", ex.Name, n, item, counts[item])
+			}
+		}
+	}
+
+	// The 50 walks left are drawn among both items.
+	starts, _ := bird.sampleItemsFromQuery(cases[2].Query)
+	counts := make(map[int]int)
+	for _, item := range starts {
+		counts[item]++
+	}
+	if counts[0] < 30 || counts[3] < 20 {
+		t.Errorf("QueryDraws: expected at least 30 and 20 walks from items 0 and 3, got %v", counts)
+	}
+
+	query := []QueryItem{{Item: 0, Weight: 1, Draws: 10}, {Item: 3, Weight: 1}}
+	first, _, err := bird.ProcessSeeded(query, 42, 4)
+	if err != nil {
+		t.Fatalf("QueryDraws: ProcessSeeded should not have raised an error but did: %v", err)
+	}
+	second, _, _ := bird.ProcessSeeded(query, 42, 2)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("QueryDraws: ProcessSeeded should not depend on the number of workers")
+	}
+
+	invalid := map[string][]QueryItem{
+		"Too many draws":    {{Item: 0, Weight: 1, Draws: 60}, {Item: 3, Weight: 1, Draws: 50}},
+		"Negative draws":    {{Item: 0, Weight: 1, Draws: -1}},
+		"Item not in graph": {{Item: 7, Weight: 1, Draws: 1}},
+	}
+	for name, query := range invalid {
+		if _, _, err := bird.Process(query); err == nil {
+			t.Errorf("QueryDraws: %s: Process should have raised an error but did not", name)
+		}
+	}
+}
+
+func TestBirdStartJaccardThreshold(t *testing.T) {
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 2}, []int{1, 3}, []int{0, 1}}
+
+	// The items 0, 1 and 3 were chosen by 2, 4 and 1 of the 4 users of the
+	// query.
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 1, Weight: 1}, {Item: 3, Weight: 1}}
+	for _, lazy := range []bool{false, true} {
+		cfg := NewBirdCfg()
+		cfg.LazyItemsToUsers = lazy
+		cfg.StartJaccardThreshold = 0.3
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("StartJaccardThreshold: Bird initialization should not have raised an error but did: %v", err)
+		}
+
+		starts, err := bird.sampleItemsFromQuery(query)
+		if err != nil {
+			t.Fatalf("StartJaccardThreshold: lazy=%v: sampling the query should not have raised an error but did: %v", lazy, err)
+		}
+		counts := make(map[int]int)
+		for _, item := range starts {
+			counts[item]++
+		}
+		if counts[3] != 0 || counts[0] == 0 || counts[1] == 0 {
+			t.Errorf("StartJaccardThreshold: lazy=%v: expected walks to start from items 0 and 1 only, got %v", lazy, counts)
+		}
+
+		// Without item 1, items 0 and 3 share none of their users.
+		cfg.StartJaccardThreshold = 0.7
+		if _, err := bird.sampleItemsFromQuery([]QueryItem{query[0], query[2]}); err == nil {
+			t.Errorf("StartJaccardThreshold: lazy=%v: filtering out every item should have raised an error", lazy)
+		}
+	}
+
+	for _, threshold := range []float64{-0.1, 1, math.NaN()} {
+		cfg := NewBirdCfg()
+		cfg.StartJaccardThreshold = threshold
+		if _, err := NewBird(cfg, itemWeights, usersToItems); err == nil {
+			t.Errorf("StartJaccardThreshold: a threshold of %v should have been rejected", threshold)
+		}
+	}
+}
+
+func TestBirdGumbelStarts(t *testing.T) {
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 2, 3}}
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 1, Weight: 0}, {Item: 2, Weight: 2}, {Item: 3, Weight: 3}}
+
+	cfg := NewBirdCfg()
+	cfg.Draws = 101
+	cfg.GumbelStarts = 2
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("GumbelStarts: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	for _, k := range []int{2, 10} {
+		cfg.GumbelStarts = k
+		starts, err := bird.sampleItemsFromQuery(query)
+		if err != nil {
+			t.Fatalf("GumbelStarts: %d: sampling the query should not have raised an error but did: %v", k, err)
+		}
+		counts := make(map[int]int)
+		for _, item := range starts {
+			counts[item]++
+		}
+		if counts[1] != 0 {
+			t.Errorf("GumbelStarts: %d: the item of zero weight started %d walks", k, counts[1])
+		}
+		numItems := minInt(k, 3)
+		if len(counts) != numItems {
+			t.Errorf("GumbelStarts: %d: expected walks to start from %d items, got %v", k, numItems, counts)
+		}
+		for item, n := range counts {
+			if n != cfg.Draws/numItems && n != cfg.Draws/numItems+1 {
+				t.Errorf("GumbelStarts: %d: item %d started %d walks instead of an equal share", k, item, n)
+			}
+		}
+	}
+
+	cfg.GumbelStarts = 2
+	first, _, err := bird.ProcessSeeded(query, 42, 4)
+	if err != nil {
+		t.Fatalf("GumbelStarts: ProcessSeeded should not have raised an error but did: %v", err)
+	}
+	second, _, _ := bird.ProcessSeeded(query, 42, 1)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("GumbelStarts: ProcessSeeded should not depend on the number of workers")
+	}
+
+	cfg.GumbelStarts = -1
+	if _, err := NewBird(cfg, itemWeights, usersToItems); err == nil {
+		t.Errorf("GumbelStarts: a negative number of starting items should have been rejected")
+	}
+}
+
+func TestBirdQueryNormalization(t *testing.T) {
+	itemWeights := []float64{1, 1, 2}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 2}}
+	cfg := NewBirdCfg()
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("QueryNormalization: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	e := math.E
+	cases := []struct {
+		Name          string
+		Normalization QueryNormalization
+		Query         []QueryItem
+		Expected      []float64
+	}{
+		{"Raw", QueryWeightsRaw, []QueryItem{{Item: 0, Weight: 0}, {Item: 1, Weight: 10}, {Item: 2, Weight: 30}}, []float64{0, 10, 60}},
+		{"L1", QueryWeightsL1, []QueryItem{{Item: 0, Weight: 0}, {Item: 1, Weight: 10}, {Item: 2, Weight: 30}}, []float64{0, 0.25, 1.5}},
+		{"Softmax", QueryWeightsSoftmax, []QueryItem{{Item: 0, Weight: 0}, {Item: 1, Weight: 1}, {Item: 2, Weight: 2}}, []float64{0, 1 / (1 + e), 2 * e / (1 + e)}},
+		{"Shifted softmax", QueryWeightsSoftmax, []QueryItem{{Item: 0, Weight: 0}, {Item: 1, Weight: 1001}, {Item: 2, Weight: 1002}}, []float64{0, 1 / (1 + e), 2 * e / (1 + e)}},
+	}
+	for _, ex := range cases {
+		cfg.QueryNormalization = ex.Normalization
+		weights, err := bird.queryWeights(ex.Query)
+		if err != nil {
+			t.Fatalf("QueryNormalization: %s: should not have raised an error but did: %v", ex.Name, err)
+		}
+		for i, w := range weights {
+			if math.Abs(w-ex.Expected[i]) > 1e-12 {
+				t.Errorf("QueryNormalization: %s: expected weights %v, got %v", ex.Name, ex.Expected, weights)
+				break
+			}
+		}
+	}
+
+	// Scaling the weights does not change the starting points once they
+	// are normalized.
+	cfg.QueryNormalization = QueryWeightsL1
+	bird.ReSeed(42)
+	counts, _ := bird.sampleItemsFromQuery([]QueryItem{{Item: 1, Weight: 100}, {Item: 2, Weight: 300}})
+	bird.ReSeed(42)
+	fractions, _ := bird.sampleItemsFromQuery([]QueryItem{{Item: 1, Weight: 0.25}, {Item: 2, Weight: 0.75}})
+	if !reflect.DeepEqual(counts, fractions) {
+		t.Errorf("QueryNormalization: L1: expected the same starting points whatever the scale of the weights")
+	}
+
+	cfg.QueryNormalization = QueryWeightsSoftmax + 1
+	if _, err := NewBird(cfg, itemWeights, usersToItems); err == nil {
+		t.Errorf("QueryNormalization: an unknown normalization should have been rejected")
+	}
+}
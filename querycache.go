@@ -0,0 +1,114 @@
+package birdland
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+
+	"github.com/rlouf/birdland/sampler"
+)
+
+// querySamplerCache keeps the starts prepared for the queries of a batch, so
+// that identical queries, with the same items, weights and draws in the same
+// order, reuse the sampler of the first one. It holds at most size queries;
+// once full, the query that was added first is evicted. It is safe for
+// concurrent use, and only valid as long as the Bird is not modified.
+type querySamplerCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]queryStartsEntry
+	keys    []string // keys of the entries in the order they were added
+	next    int      // index in keys of the next entry to evict
+}
+
+// queryStartsEntry holds what queryStarts returned for a query; neither is
+// modified by the walks.
+type queryStartsEntry struct {
+	fixed []int
+	s     *querySampler
+}
+
+// newQuerySamplerCache returns a cache of size queries, or nil, which caches
+// nothing, if size is 0.
+func newQuerySamplerCache(size int) *querySamplerCache {
+	if size == 0 {
+		return nil
+	}
+
+	return &querySamplerCache{size: size, entries: make(map[string]queryStartsEntry, size)}
+}
+
+// cachedQueryStarts is queryStarts that reuses the starts cached for query,
+// if any, and otherwise caches them. The starts drawn with Cfg.GumbelStarts
+// depend on rng and are never cached.
+func (b *Bird) cachedQueryStarts(query []QueryItem, rng sampler.Rand, cache *querySamplerCache) ([]int, *querySampler, error) {
+	if cache == nil || b.drawsGumbelStarts(query) {
+		return b.queryStarts(query, rng)
+	}
+
+	key := querySamplerKey(query)
+	cache.mu.Lock()
+	e, ok := cache.entries[key]
+	cache.mu.Unlock()
+	if ok {
+		return e.fixed, e.s, nil
+	}
+
+	fixed, s, err := b.queryStarts(query, rng)
+	if err != nil {
+		return nil, nil, err
+	}
+	cache.add(key, queryStartsEntry{fixed: fixed, s: s})
+
+	return fixed, s, nil
+}
+
+// drawsGumbelStarts returns true if queryStarts draws the starts of query
+// with the Gumbel-max trick.
+func (b *Bird) drawsGumbelStarts(query []QueryItem) bool {
+	if b.Cfg.GumbelStarts == 0 {
+		return false
+	}
+	for _, q := range query {
+		if q.Draws != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// add caches e under key, evicting the oldest entry if the cache is full.
+func (c *querySamplerCache) add(key string, e queryStartsEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+	if len(c.keys) < c.size {
+		c.keys = append(c.keys, key)
+	} else {
+		delete(c.entries, c.keys[c.next])
+		c.keys[c.next] = key
+		c.next = (c.next + 1) % c.size
+	}
+	c.entries[key] = e
+}
+
+// querySamplerKey returns the signature of query: the item, the bits of the
+// weight and the draws of each query item, in order.
+func querySamplerKey(query []QueryItem) string {
+	key := make([]byte, 0, 24*len(query))
+	var buf [8]byte
+	for _, q := range query {
+		binary.LittleEndian.PutUint64(buf[:], uint64(q.Item))
+		key = append(key, buf[:]...)
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(q.Weight))
+		key = append(key, buf[:]...)
+		binary.LittleEndian.PutUint64(buf[:], uint64(q.Draws))
+		key = append(key, buf[:]...)
+	}
+
+	return string(key)
+}
@@ -0,0 +1,53 @@
+package birdland
+
+import "testing"
+
+func TestQuerySamplerCache(t *testing.T) {
+	cfg := NewBirdCfg()
+	cfg.Draws = 10
+	bird, err := NewBird(cfg, []float64{1, 1, 1}, [][]int{[]int{0, 1}, []int{1, 2}})
+	if err != nil {
+		t.Fatalf("QuerySamplerCache: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	if newQuerySamplerCache(0) != nil {
+		t.Errorf("QuerySamplerCache: expected no cache for a size of 0")
+	}
+	cache := newQuerySamplerCache(2)
+	queries := [][]QueryItem{
+		{{Item: 0, Weight: 1}},
+		{{Item: 0, Weight: 1}, {Item: 1, Weight: 2, Draws: 3}},
+		{{Item: 1, Weight: 2, Draws: 3}, {Item: 0, Weight: 1}},
+	}
+	_, first, err := bird.cachedQueryStarts(queries[0], bird.RandSource, cache)
+	if err != nil {
+		t.Fatalf("QuerySamplerCache: should not have raised an error but did: %v", err)
+	}
+	if _, s, _ := bird.cachedQueryStarts([]QueryItem{{Item: 0, Weight: 1}}, bird.RandSource, cache); s != first {
+		t.Errorf("QuerySamplerCache: expected an identical query to reuse the cached sampler")
+	}
+	for _, query := range queries[1:] {
+		if _, _, err := bird.cachedQueryStarts(query, bird.RandSource, cache); err != nil {
+			t.Fatalf("QuerySamplerCache: should not have raised an error but did: %v", err)
+		}
+	}
+	if len(cache.entries) != 2 {
+		t.Errorf("QuerySamplerCache: expected the cache to hold 2 queries, got %d", len(cache.entries))
+	}
+	if _, ok := cache.entries[querySamplerKey(queries[0])]; ok {
+		t.Errorf("QuerySamplerCache: expected the first query to be evicted")
+	}
+
+	if _, _, err := bird.cachedQueryStarts([]QueryItem{{Item: 7, Weight: 1}}, bird.RandSource, cache); err == nil {
+		t.Errorf("QuerySamplerCache: an invalid query should have raised an error")
+	}
+	if _, ok := cache.entries[querySamplerKey([]QueryItem{{Item: 7, Weight: 1}})]; ok {
+		t.Errorf("QuerySamplerCache: expected the invalid query not to be cached")
+	}
+
+	cfg.GumbelStarts = 1
+	cache = newQuerySamplerCache(2)
+	if _, _, err := bird.cachedQueryStarts(queries[0], bird.RandSource, cache); err != nil || len(cache.entries) != 0 {
+		t.Errorf("QuerySamplerCache: expected the Gumbel starts not to be cached, got %d queries (error: %v)", len(cache.entries), err)
+	}
+}
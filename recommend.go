@@ -1,8 +1,6 @@
 package birdland
 
-import (
-	"sort"
-)
+import "sort"
 
 type Pair struct {
 	Object     int
@@ -11,6 +9,20 @@ type Pair struct {
 
 type PairList []Pair // necessary evil to sort map by value
 
+// ScoredItem is an item along with the score a recommender attributed to it.
+type ScoredItem struct {
+	Item     int
+	Score    float64
+	StdErr   float64 // standard error of the score, 0 if it was not estimated
+	Fallback bool    // whether the item was recommended by ScoreOptions.Fallback rather than by the walks
+}
+
+// ScoredUser is a user along with the score a recommender attributed to it.
+type ScoredUser struct {
+	User  int
+	Score float64
+}
+
 func (p PairList) Len() int           { return len(p) }
 func (p PairList) Less(i, j int) bool { return p[i].Occurences < p[j].Occurences }
 func (p PairList) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
@@ -18,7 +30,8 @@ func (p PairList) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
 // RecommendItems and RecommendUsers contain the current methods that should be
 // used to recommend items and users in production.  Used as an interface so
 // backend developpers do not need to worry about the zoology of recommending
-// methods.
+// methods. Like every Recommend function, they order tied items by ascending
+// index.
 func RecommendItems(items, referrers []int) []int { return RecommendMostVisited(items) }
 func RecommendUsers(items, referrers []int) []int { return RecommendMostVisited(referrers) }
 
@@ -27,23 +40,33 @@ func RecommendUsers(items, referrers []int) []int { return RecommendMostVisited(
 // probably should not be used in production. Works indifferently to
 // recommend users or items.
 func RecommendMostVisited(items []int) []int {
-	countItems := make(map[int]int)
-	for _, item := range items {
-		countItems[item] += 1
-	}
+	var counter Counter
+	counter.Add(items)
+	recommendedItems, _ := counter.TopN(counter.Len())
 
-	pairList := PairList{}
-	for item, count := range countItems {
-		pairList = append(pairList, Pair{item, count})
+	return recommendedItems
+}
+
+// TopReferrers processes the query and returns the n users who referred the
+// most visits of the walks, i.e. through whom the walks went most often,
+// along with their number of referrals, in descending order, users with the
+// same number being sorted by ascending index. Such users are the most
+// influential in spreading the recommendations for the query.
+func (b *Bird) TopReferrers(query []QueryItem, n int) ([]ScoredUser, error) {
+	_, referrers, err := b.Process(query)
+	if err != nil {
+		return nil, wrap(err, "cannot process query")
 	}
 
-	sort.Sort(sort.Reverse(pairList))
-	recommendedItems := make([]int, len(countItems))
-	for i, pair := range pairList {
-		recommendedItems[i] = pair.Object
+	var counter Counter
+	counter.Add(referrers)
+	users, counts := counter.TopN(n)
+	scored := make([]ScoredUser, len(users))
+	for i, user := range users {
+		scored[i] = ScoredUser{User: user, Score: counts[i]}
 	}
 
-	return recommendedItems
+	return scored, nil
 }
 
 // RecommendConsensus recommends the item by descending order of the number of
@@ -68,7 +91,7 @@ func RecommendConsensus(items, referrers []int) []int {
 		countUniqueReferrers = append(countUniqueReferrers, Pair{item, len(referrersMap)})
 	}
 
-	sort.Sort(sort.Reverse(countUniqueReferrers))
+	sortPairs(countUniqueReferrers)
 	recommendedItems := make([]int, 0, len(items))
 	for _, pair := range countUniqueReferrers {
 		recommendedItems = append(recommendedItems, pair.Object)
@@ -102,7 +125,7 @@ func RecommendTrust(items, referrers []int) []int {
 		pairList = append(pairList, Pair{item, count})
 	}
 
-	sort.Sort(sort.Reverse(pairList))
+	sortPairs(pairList)
 	recommendedItems := make([]int, len(itemWeights))
 	for i, pair := range pairList {
 		recommendedItems[i] = pair.Object
@@ -110,3 +133,28 @@ func RecommendTrust(items, referrers []int) []int {
 
 	return recommendedItems
 }
+
+// sortPairs sorts pairs in descending order of occurences. Pairs with the
+// same number of occurences are sorted by ascending object, so the order does
+// not depend on that of the map they were counted in.
+func sortPairs(pairs PairList) {
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Occurences != pairs[j].Occurences {
+			return pairs[i].Occurences > pairs[j].Occurences
+		}
+		return pairs[i].Object < pairs[j].Object
+	})
+}
+
+// rankItems returns the n items with the highest scores, in descending order
+// of score, items with the same score being sorted by ascending index. All
+// items are returned when there are fewer than n of them.
+func rankItems(scores map[int]float64, n int) []ScoredItem {
+	items, values := topScores(scores, n)
+	ranked := make([]ScoredItem, len(items))
+	for i, item := range items {
+		ranked[i] = ScoredItem{Item: item, Score: values[i]}
+	}
+
+	return ranked
+}
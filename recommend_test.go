@@ -1,6 +1,10 @@
 package birdland
 
-import "testing"
+import (
+	"math"
+	"reflect"
+	"testing"
+)
 
 type MostVisitedCase struct {
 	Name     string
@@ -19,6 +23,11 @@ var mostVisited_table = []MostVisitedCase{
 		Input:    []int{1, 2, 2, 2, 3, 3, 0, 0, 0, 0, 0, 5, 5, 5, 5, 5, 5},
 		Expected: []int{5, 0, 2, 3, 1},
 	},
+	{
+		Name:     "Tied input",
+		Input:    []int{4, 4, 2, 2, 7, 7, 1},
+		Expected: []int{2, 4, 7, 1},
+	},
 }
 
 type ConsensusCase struct {
@@ -41,6 +50,12 @@ var consensus_table = []ConsensusCase{
 		Referrers: []int{1, 3, 4, 5, 1, 1, 1, 2, 1},
 		Expected:  []int{2, 3, 1},
 	},
+	{
+		Name:      "Tied input",
+		Items:     []int{3, 3, 1, 1, 8},
+		Referrers: []int{1, 2, 3, 4, 5},
+		Expected:  []int{1, 3, 8},
+	},
 }
 
 type TrustCase struct {
@@ -57,6 +72,12 @@ var trust_table = []TrustCase{
 		Referrers: []int{1, 1, 1, 1, 2, 3, 4, 5},
 		Expected:  []int{1, 2, 5, 4},
 	},
+	{
+		Name:      "Tied input",
+		Items:     []int{9, 3, 6},
+		Referrers: []int{1, 2, 3},
+		Expected:  []int{3, 6, 9},
+	},
 }
 
 func TestRecommendMostVisited(t *testing.T) {
@@ -103,3 +124,84 @@ func TestRecommendTrust(t *testing.T) {
 		}
 	}
 }
+
+func TestRankItemsTies(t *testing.T) {
+	scores := map[int]float64{5: 1, 2: 1, 9: 2, 0: 1, 7: 0.5}
+
+	// Maps are iterated in a different order each time.
+	for run := 0; run < 20; run++ {
+		ranked := rankItems(scores, 4)
+		var items []int
+		for _, s := range ranked {
+			items = append(items, s.Item)
+		}
+		if expected := []int{9, 0, 2, 5}; !reflect.DeepEqual(items, expected) {
+			t.Fatalf("rankItems: expected tied items in ascending order %v, got %v", expected, items)
+		}
+	}
+
+	scores = map[int]float64{3: math.NaN(), 1: math.NaN(), 4: 0, 2: math.Inf(-1)}
+	for run := 0; run < 20; run++ {
+		ranked := rankItems(scores, 4)
+		var items []int
+		for _, s := range ranked {
+			items = append(items, s.Item)
+		}
+		if expected := []int{4, 2, 1, 3}; !reflect.DeepEqual(items, expected) {
+			t.Fatalf("rankItems: expected NaN scores last %v, got %v", expected, items)
+		}
+	}
+}
+
+func TestNormalizedScoresTies(t *testing.T) {
+	// The softmax of the two lowest scores underflows to 0.
+	ranked := []ScoredItem{{Item: 9, Score: 0}, {Item: 4, Score: -1000}, {Item: 2, Score: -2000}}
+	scored, _ := ScoreOptions{Normalization: ScoresSoftmax, Temperature: 1}.normalize(ranked, 3)
+	var items []int
+	for _, s := range scored {
+		items = append(items, s.Item)
+	}
+	if expected := []int{9, 2, 4}; !reflect.DeepEqual(items, expected) {
+		t.Errorf("normalize: expected the items tied by the normalization in ascending order %v, got %v", expected, scored)
+	}
+}
+
+func TestBirdTopReferrers(t *testing.T) {
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 2, 3}, []int{0, 3}, []int{2}}
+	query := []QueryItem{{Item: 0, Weight: 1}}
+	cfg := NewBirdCfg()
+	cfg.Depth = 3
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("TopReferrers: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	// The referrers are counted as by ProcessCountsWithReferrers.
+	bird.ReSeed(42)
+	top, err := bird.TopReferrers(query, 2)
+	if err != nil {
+		t.Fatalf("TopReferrers: should not have raised an error but did: %v", err)
+	}
+	bird.ReSeed(42)
+	_, userCounts, _ := bird.ProcessCountsWithReferrers(query)
+	scores := make(map[int]float64)
+	for user, n := range userCounts {
+		scores[user] = float64(n)
+	}
+	expected := rankItems(scores, 2)
+	if len(top) != len(expected) {
+		t.Fatalf("TopReferrers: expected %d users, got %v", len(expected), top)
+	}
+	for i, s := range expected {
+		if top[i].User != s.Item || top[i].Score != s.Score {
+			t.Errorf("TopReferrers: expected users %v, got %v", expected, top)
+			break
+		}
+	}
+
+	if _, err := bird.TopReferrers(nil, 2); err == nil {
+		t.Errorf("TopReferrers: an empty query should have raised an error")
+	}
+}
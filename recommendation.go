@@ -0,0 +1,83 @@
+package birdland
+
+import "fmt"
+
+// Recommendation holds the items and the users recommended for a query from
+// the same random walks, in descending order of score.
+type Recommendation struct {
+	Items      []int
+	ItemScores []float64
+	Users      []int
+	UserScores []float64
+
+	// Fallbacks is the number of items, at the end of Items, recommended
+	// by ScoreOptions.Fallback rather than by the walks.
+	Fallbacks int
+}
+
+// Recommend performs the random walks of ProcessScores once and returns both
+// the nItems items with the highest scores, as RecommendItemsScored would
+// with opts, and the nUsers users who referred the most visits, as
+// TopReferrers would. The users are scored by their number of referrals,
+// normalized as the items by opts.Normalization; opts.PopularityPenalty and
+// opts.Seen only apply to the items. When opts.Seen leaves fewer than nItems
+// items, the recommendation is returned along with ErrNotEnoughItems, unless
+// opts.Fallback makes up the missing items. A cold query, from which the
+// walks cannot start, gets the fallback items and no users.
+func (b *Bird) Recommend(query []QueryItem, nItems, nUsers int, opts ScoreOptions) (Recommendation, error) {
+	if nItems < 0 || nUsers < 0 {
+		return Recommendation{}, &InvalidInputError{
+			Err: fmt.Errorf("the numbers of items and users must not be negative, got %d and %d", nItems, nUsers),
+		}
+	}
+	aggregator, err := b.scoreAggregator(opts)
+	if err != nil {
+		return Recommendation{}, err
+	}
+	if err := b.checkQueryRange(query); err != nil {
+		return Recommendation{}, wrap(err, "cannot sample items")
+	}
+	var visits []Visit
+	var walks int
+	query, avoided := splitAvoided(query)
+	if opts.Fallback == FallbackNone || !b.coldQuery(query) {
+		visits, walks, err = b.walkVisits(query)
+		if err != nil {
+			return Recommendation{}, err
+		}
+	}
+
+	ranked, err := b.scoreVisits(visits, walks, aggregator)
+	if err != nil {
+		return Recommendation{}, err
+	}
+	if err := b.avoid(ranked, avoided); err != nil {
+		return Recommendation{}, err
+	}
+	items, itemsErr := opts.normalize(ranked, nItems)
+	items, itemsErr = b.recommendFallback(items, itemsErr, nItems, opts)
+
+	referrals := make(map[int]float64)
+	for _, v := range visits {
+		referrals[v.Referrer]++
+	}
+	users, _ := ScoreOptions{Normalization: opts.Normalization, Temperature: opts.Temperature}.normalize(rankItems(referrals, len(referrals)), nUsers)
+
+	r := Recommendation{
+		Items:      make([]int, len(items)),
+		ItemScores: make([]float64, len(items)),
+		Users:      make([]int, len(users)),
+		UserScores: make([]float64, len(users)),
+	}
+	for i, s := range items {
+		r.Items[i], r.ItemScores[i] = s.Item, s.Score
+		if s.Fallback {
+			r.Fallbacks++
+		}
+	}
+	for i, s := range users {
+		r.Users[i], r.UserScores[i] = s.Item, s.Score
+	}
+
+	return r, itemsErr
+}
@@ -0,0 +1,70 @@
+package birdland
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBirdRecommend(t *testing.T) {
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 2, 3}, []int{0, 3}, []int{2}}
+	query := []QueryItem{{Item: 0, Weight: 1}}
+	cfg := NewBirdCfg()
+	cfg.Depth = 3
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("Recommend: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	// Both rankings are those of the individual methods given the same
+	// walks.
+	opts := ScoreOptions{Normalization: ScoresProbability, Seen: SeenItems(0)}
+	bird.ReSeed(42)
+	r, err := bird.Recommend(query, 2, 3, opts)
+	if err != nil {
+		t.Fatalf("Recommend: should not have raised an error but did: %v", err)
+	}
+	bird.ReSeed(42)
+	items, _ := bird.RecommendItemsScored(query, 2, opts)
+	bird.ReSeed(42)
+	users, _ := bird.TopReferrers(query, 3)
+
+	var expectedItems, expectedUsers []int
+	var expectedItemScores []float64
+	for _, s := range items {
+		expectedItems = append(expectedItems, s.Item)
+		expectedItemScores = append(expectedItemScores, s.Score)
+	}
+	for _, s := range users {
+		expectedUsers = append(expectedUsers, s.User)
+	}
+	if !reflect.DeepEqual(r.Items, expectedItems) || !reflect.DeepEqual(r.ItemScores, expectedItemScores) {
+		t.Errorf("Recommend: expected items %v scored %v, got %v scored %v", expectedItems, expectedItemScores, r.Items, r.ItemScores)
+	}
+	if !reflect.DeepEqual(r.Users, expectedUsers) {
+		t.Errorf("Recommend: expected users %v, got %v", expectedUsers, r.Users)
+	}
+	var total float64
+	for _, s := range r.UserScores {
+		total += s
+	}
+	if total > 1+1e-9 {
+		t.Errorf("Recommend: expected the users' normalized scores to sum to at most 1, got %v", r.UserScores)
+	}
+
+	r, err = bird.Recommend(query, 4, 1, ScoreOptions{Seen: SeenItems(0, 1)})
+	if err != ErrNotEnoughItems || len(r.Items) != 2 || len(r.Users) != 1 {
+		t.Errorf("Recommend: expected 2 items, 1 user and ErrNotEnoughItems, got %+v and %v", r, err)
+	}
+	if _, err := bird.Recommend(query, 2, 2, ScoreOptions{PopularityPenalty: 2}); err == nil {
+		t.Errorf("Recommend: invalid options should have been rejected")
+	}
+	for _, n := range [][2]int{{-1, 2}, {2, -1}} {
+		if _, err := bird.Recommend(query, n[0], n[1], ScoreOptions{}); err == nil {
+			t.Errorf("Recommend: %d items and %d users should have been rejected", n[0], n[1])
+		} else if _, ok := err.(*InvalidInputError); !ok {
+			t.Errorf("Recommend: expected an *InvalidInputError, got %T", err)
+		}
+	}
+}
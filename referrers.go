@@ -0,0 +1,110 @@
+package birdland
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/pkg/errors"
+	"github.com/rlouf/birdland/sampler"
+)
+
+// userItem identifies an interaction of a user with an item.
+type userItem struct {
+	user, item int
+}
+
+// SetReferrerWeights makes the walks leave an item through one of the users
+// who interacted with it with a probability proportional to the weight of
+// their interaction, instead of uniformly. weights is aligned with
+// UsersToItems: weights[u][j] is the weight of the interaction of user u with
+// the item UsersToItems[u][j], for instance a decreasing function of its
+// age so that the walks favour the users who interacted recently. A nil
+// weights restores the uniform choice.
+//
+// The interactions added by incremental updates get a weight of 1. The
+// weights are not saved with the Bird, and they cannot be used with
+// Cfg.LazyItemsToUsers or with lists set by SetItemsToUsers.
+func (b *Bird) SetReferrerWeights(weights [][]float64) error {
+	if weights == nil {
+		b.referrerWeights, b.referrerSamplers = nil, nil
+		return nil
+	}
+	if b.lazyItemsToUsers != nil {
+		return errors.New("referrer weights cannot be used with lazy item-user lists")
+	}
+	if b.externalItemsToUsers != nil {
+		return errors.New("referrer weights cannot be used with item-user lists stored externally")
+	}
+	if len(weights) != len(b.UsersToItems) {
+		return fmt.Errorf("expected referrer weights for %d users, got %d", len(b.UsersToItems), len(weights))
+	}
+
+	// The weights of a user's interactions with the same item are assigned
+	// in order to the occurrences of the user in the item's list.
+	byInteraction := make(map[userItem][]float64)
+	for u, userItems := range b.UsersToItems {
+		if len(weights[u]) != len(userItems) {
+			return fmt.Errorf("expected %d referrer weights for user %d, got %d", len(userItems), u, len(weights[u]))
+		}
+		for j, item := range userItems {
+			w := weights[u][j]
+			if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+				return fmt.Errorf("invalid referrer weight %v for the interaction of user %d with item %d", w, u, item)
+			}
+			key := userItem{u, item}
+			byInteraction[key] = append(byInteraction[key], w)
+		}
+	}
+
+	itemWeights := make([][]float64, len(b.ItemsToUsers))
+	samplers := make([]sampler.AliasSampler, len(b.ItemsToUsers))
+	for item, itemUsers := range b.ItemsToUsers {
+		itemWeights[item] = make([]float64, len(itemUsers))
+		for k, user := range itemUsers {
+			key := userItem{user, item}
+			itemWeights[item][k], byInteraction[key] = byInteraction[key][0], byInteraction[key][1:]
+		}
+		s, err := newSamplerFromWeights(b.RandSource, itemWeights[item])
+		if err != nil {
+			return errors.Wrapf(err, "cannot build the referrer sampler of item %d", item)
+		}
+		samplers[item] = s
+	}
+	b.referrerWeights, b.referrerSamplers = itemWeights, samplers
+
+	return nil
+}
+
+// sampleReferrer draws the user through whom a walk leaves item, among the
+// users who interacted with it, which must not be empty.
+func (b *Bird) sampleReferrer(item int, itemUsers []int, rng sampler.Rand) int {
+	if b.referrerSamplers == nil {
+		return itemUsers[rng.Intn(len(itemUsers))]
+	}
+
+	return itemUsers[b.referrerSamplers[item].SampleWith(rng)]
+}
+
+// stageReferrers computes the referrer weights and sampler of the item of an
+// interaction as they will be once the staged change is committed.
+func (b *Bird) stageReferrers(s *stagedChange) error {
+	if b.referrerWeights == nil || (s.change.Kind != addInteraction && s.change.Kind != removeInteraction) {
+		return nil
+	}
+
+	weights := b.referrerWeights[s.change.Item]
+	if s.change.Kind == addInteraction {
+		s.referrerWeights = append(append([]float64{}, weights...), 1)
+	} else {
+		i := indexOf(b.ItemsToUsers[s.change.Item], s.change.User)
+		s.referrerWeights = append(append([]float64{}, weights[:i]...), weights[i+1:]...)
+	}
+
+	referrerSampler, err := newSamplerFromWeights(b.RandSource, s.referrerWeights)
+	if err != nil {
+		return errors.Wrapf(err, "cannot rebuild the referrer sampler of item %d", s.change.Item)
+	}
+	s.referrerSampler = referrerSampler
+
+	return nil
+}
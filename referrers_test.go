@@ -0,0 +1,100 @@
+package birdland
+
+import (
+	"testing"
+)
+
+func TestBirdReferrerWeights(t *testing.T) {
+	itemWeights := []float64{1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{0, 2}, []int{2, 0}}
+	cfg := NewBirdCfg()
+	cfg.Draws = 1000
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("ReferrerWeights: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	// Item 0 can only be left through user 1 or 2, 3 times out of 4 through
+	// user 2.
+	weights := [][]float64{{0, 1}, {1, 1}, {1, 3}}
+	if err := bird.SetReferrerWeights(weights); err != nil {
+		t.Fatalf("ReferrerWeights: SetReferrerWeights should not have raised an error but did: %v", err)
+	}
+	counts := referrerCounts(t, bird, 0)
+	if counts[0] != 0 {
+		t.Errorf("ReferrerWeights: user 0 has a zero weight but was chosen %d times", counts[0])
+	}
+	if share := float64(counts[2]) / float64(cfg.Draws); share < 0.7 || share > 0.8 {
+		t.Errorf("ReferrerWeights: expected user 2 to be chosen about 75%% of the time, got %.2f", share)
+	}
+
+	// The new interaction gets a weight of 1: user 0 is now chosen about
+	// a fifth of the time.
+	if err := bird.AddInteraction(0, 0); err != nil {
+		t.Fatalf("ReferrerWeights: AddInteraction should not have raised an error but did: %v", err)
+	}
+	counts = referrerCounts(t, bird, 0)
+	if share := float64(counts[0]) / float64(cfg.Draws); share < 0.15 || share > 0.25 {
+		t.Errorf("ReferrerWeights: expected user 0 to be chosen about 20%% of the time, got %.2f", share)
+	}
+	if err := bird.RemoveInteraction(2, 0); err != nil {
+		t.Fatalf("ReferrerWeights: RemoveInteraction should not have raised an error but did: %v", err)
+	}
+	counts = referrerCounts(t, bird, 0)
+	if counts[2] != 0 || counts[0] == 0 || counts[1] == 0 {
+		t.Errorf("ReferrerWeights: expected users 0 and 1 to be chosen after the removal, got %v", counts)
+	}
+
+	if err := bird.SetReferrerWeights(nil); err != nil {
+		t.Fatalf("ReferrerWeights: SetReferrerWeights should not have raised an error but did: %v", err)
+	}
+	if bird.referrerSamplers != nil {
+		t.Errorf("ReferrerWeights: nil weights should restore the uniform choice")
+	}
+
+	// The collections are now {0, 1, 0}, {0, 2} and {2}.
+	if err := bird.SetReferrerWeights([][]float64{{1, 1, 1}, {1, 1}, {1}}); err != nil {
+		t.Errorf("ReferrerWeights: SetReferrerWeights should not have raised an error but did: %v", err)
+	}
+	invalid := map[string][][]float64{
+		"Missing user":     {{1, 1, 1}, {1, 1}},
+		"Missing weight":   {{1, 1, 1}, {1}, {1}},
+		"Too many weights": {{1, 1, 1, 1}, {1, 1}, {1}},
+		"Negative weight":  {{1, 1, 1}, {1, -1}, {1}},
+		"All weights zero": {{1, 1, 1}, {1, 0}, {0}},
+	}
+	for name, w := range invalid {
+		if err := bird.SetReferrerWeights(w); err == nil {
+			t.Errorf("ReferrerWeights: %s: SetReferrerWeights should have raised an error but did not", name)
+		}
+	}
+
+	cfg.LazyItemsToUsers = true
+	lazy, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("ReferrerWeights: Bird initialization should not have raised an error but did: %v", err)
+	}
+	if err := lazy.SetReferrerWeights(weights); err == nil {
+		t.Errorf("ReferrerWeights: SetReferrerWeights should have raised an error with lazy item-user lists")
+	}
+}
+
+// referrerCounts counts the referrers of the walks that start from item.
+func referrerCounts(t *testing.T, bird *Bird, item int) map[int]int {
+	items := make([]int, bird.Cfg.Draws)
+	for i := range items {
+		items[i] = item
+	}
+	_, referrers, err := bird.step(items)
+	if err != nil {
+		t.Fatalf("ReferrerWeights: step should not have raised an error but did: %v", err)
+	}
+
+	counts := make(map[int]int)
+	for _, user := range referrers {
+		counts[user]++
+	}
+
+	return counts
+}
@@ -0,0 +1,102 @@
+package birdland
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Rerank selects n of the candidates, in order, by maximal marginal
+// relevance: each step picks the candidate that maximizes
+//
+//	lambda * relevance - (1 - lambda) * max similarity to the picked ones
+//
+// where relevance is the candidate's score divided by the largest absolute
+// score, so that it is on the scale of similarities in [0, 1]. A lambda of 1
+// keeps the order of the scores, and lower values favour candidates unlike
+// those already picked. Ties go to the candidate that comes first. sim is
+// called O(n len(candidates)) times.
+func Rerank(candidates []int, scores []float64, n int, lambda float64, sim func(a, b int) float64) ([]int, error) {
+	if len(scores) != len(candidates) {
+		return nil, fmt.Errorf("there are %d candidates but %d scores", len(candidates), len(scores))
+	}
+	if !(lambda >= 0 && lambda <= 1) {
+		return nil, fmt.Errorf("lambda must be in [0, 1], got %v", lambda)
+	}
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+	if n <= 0 {
+		return []int{}, nil
+	}
+
+	var maxScore float64
+	for _, s := range scores {
+		maxScore = math.Max(maxScore, math.Abs(s))
+	}
+	relevance := make([]float64, len(scores))
+	for i, s := range scores {
+		if maxScore > 0 {
+			relevance[i] = s / maxScore
+		}
+	}
+
+	// maxSim[i] is the largest similarity of candidate i to the picked ones.
+	maxSim := make([]float64, len(candidates))
+	picked := make([]bool, len(candidates))
+	selected := make([]int, 0, n)
+	for len(selected) < n {
+		best := -1
+		var bestValue float64
+		for i := range candidates {
+			if picked[i] {
+				continue
+			}
+			value := lambda*relevance[i] - (1-lambda)*maxSim[i]
+			if best == -1 || value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+
+		picked[best] = true
+		selected = append(selected, candidates[best])
+		for i, c := range candidates {
+			if !picked[i] {
+				maxSim[i] = math.Max(maxSim[i], sim(c, candidates[best]))
+			}
+		}
+	}
+
+	return selected, nil
+}
+
+// Rerank is the package's Rerank with the Jaccard index of the sets of users
+// of two items as their similarity. The users of the candidates are gathered
+// once, so only the candidates' lists are read.
+func (b *Bird) Rerank(candidates []int, scores []float64, n int, lambda float64) ([]int, error) {
+	for _, item := range candidates {
+		if item < 0 || item >= len(b.ItemWeights) {
+			return nil, fmt.Errorf("the candidate %d does not belong to the graph", item)
+		}
+	}
+	b.loadItemUsers(candidates)
+
+	users := make(map[int]map[int]bool, len(candidates))
+	for _, item := range candidates {
+		if _, ok := users[item]; ok {
+			continue
+		}
+		users[item] = make(map[int]bool)
+		for _, user := range b.itemUsers(item) {
+			users[item][user] = true
+		}
+	}
+
+	reranked, err := Rerank(candidates, scores, n, lambda, func(x, y int) float64 { return jaccard(users[x], users[y]) })
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot rerank the candidates")
+	}
+
+	return reranked, nil
+}
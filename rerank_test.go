@@ -0,0 +1,73 @@
+package birdland
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRerank(t *testing.T) {
+	// Items 0, 1 and 2 come from the same album, and are all similar.
+	album := map[int]bool{0: true, 1: true, 2: true}
+	sim := func(a, b int) float64 {
+		if album[a] && album[b] {
+			return 1
+		}
+		return 0
+	}
+	candidates := []int{0, 1, 2, 3, 4}
+	scores := []float64{10, 9, 8, 5, 4}
+
+	cases := []struct {
+		Lambda   float64
+		Expected []int
+	}{
+		{1, []int{0, 1, 2}},
+		{0.5, []int{0, 3, 4}},
+		{0, []int{0, 3, 4}},
+	}
+	for _, ex := range cases {
+		reranked, err := Rerank(candidates, scores, 3, ex.Lambda, sim)
+		if err != nil {
+			t.Fatalf("Rerank: should not have raised an error but did: %v", err)
+		}
+		if !reflect.DeepEqual(reranked, ex.Expected) {
+			t.Errorf("Rerank: lambda %v: expected %v, got %v", ex.Lambda, ex.Expected, reranked)
+		}
+	}
+
+	if reranked, _ := Rerank(candidates, scores, 10, 0.5, sim); len(reranked) != len(candidates) {
+		t.Errorf("Rerank: expected all %d candidates, got %v", len(candidates), reranked)
+	}
+	if _, err := Rerank(candidates, scores[:2], 3, 0.5, sim); err == nil {
+		t.Errorf("Rerank: mismatched scores should have raised an error")
+	}
+	if _, err := Rerank(candidates, scores, 3, 2, sim); err == nil {
+		t.Errorf("Rerank: a lambda above 1 should have raised an error")
+	}
+}
+
+func TestBirdRerank(t *testing.T) {
+	// Items 0 and 1 share all their users, item 2 has its own.
+	itemWeights := []float64{1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{0, 1}, []int{2}}
+
+	for _, lazy := range []bool{false, true} {
+		cfg := NewBirdCfg()
+		cfg.LazyItemsToUsers = lazy
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("Rerank: Bird initialization should not have raised an error but did: %v", err)
+		}
+
+		reranked, err := bird.Rerank([]int{0, 1, 2}, []float64{3, 2, 1}, 2, 0.5)
+		if err != nil {
+			t.Fatalf("Rerank: lazy=%v: should not have raised an error but did: %v", lazy, err)
+		}
+		if !reflect.DeepEqual(reranked, []int{0, 2}) {
+			t.Errorf("Rerank: lazy=%v: expected item 2 to replace item 1, got %v", lazy, reranked)
+		}
+		if _, err := bird.Rerank([]int{0, 5}, []float64{1, 1}, 1, 0.5); err == nil {
+			t.Errorf("Rerank: lazy=%v: a candidate out of the graph should have raised an error", lazy)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package birdland
+
+import (
+	"encoding"
+	"encoding/binary"
+	"math/rand"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rlouf/birdland/sampler"
+)
+
+// SplitMix64 is a fast pseudo-random generator whose whole state is a single
+// 64-bit integer, which makes it trivial to snapshot and restore. It
+// implements both rand.Source64 and sampler.Rand, and is the default random
+// source of Bird.
+type SplitMix64 struct {
+	state uint64
+}
+
+// NewSplitMix64 returns a generator seeded with seed.
+func NewSplitMix64(seed int64) *SplitMix64 {
+	return &SplitMix64{state: uint64(seed)}
+}
+
+// newRandSource returns a generator seeded with the current time.
+func newRandSource() *SplitMix64 {
+	return NewSplitMix64(time.Now().UnixNano())
+}
+
+// subSeed returns the i-th number generated by a SplitMix64 seeded with seed,
+// without generating the previous ones. Nearby seeds and indices give
+// unrelated results, which makes it suitable to derive the seeds of
+// independent generators from a single one.
+func subSeed(seed int64, i int) int64 {
+	s := SplitMix64{state: uint64(seed) + uint64(i)*splitMixGamma}
+
+	return int64(s.Uint64())
+}
+
+// Seed resets the state of the generator.
+func (s *SplitMix64) Seed(seed int64) {
+	s.state = uint64(seed)
+}
+
+// splitMixGamma is the increment of the state of SplitMix64 at each draw.
+const splitMixGamma = 0x9e3779b97f4a7c15
+
+// Uint64 returns a pseudo-random 64-bit integer.
+func (s *SplitMix64) Uint64() uint64 {
+	s.state += splitMixGamma
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+
+	return z ^ (z >> 31)
+}
+
+// Int63 returns a non-negative pseudo-random 63-bit integer.
+func (s *SplitMix64) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// Intn returns a pseudo-random integer in [0, n). It panics if n <= 0.
+func (s *SplitMix64) Intn(n int) int {
+	return boundedUint64(s.Uint64, n)
+}
+
+// Float64 returns a pseudo-random float in [0, 1).
+func (s *SplitMix64) Float64() float64 {
+	return float64(s.Uint64()>>11) / (1 << 53)
+}
+
+// MarshalBinary returns the state of the generator.
+func (s *SplitMix64) MarshalBinary() ([]byte, error) {
+	state := make([]byte, 8)
+	binary.LittleEndian.PutUint64(state, s.state)
+
+	return state, nil
+}
+
+// UnmarshalBinary restores a state returned by MarshalBinary.
+func (s *SplitMix64) UnmarshalBinary(state []byte) error {
+	if len(state) != 8 {
+		return errors.Errorf("invalid state length %d", len(state))
+	}
+	s.state = binary.LittleEndian.Uint64(state)
+
+	return nil
+}
+
+// Xoshiro256 is the xoshiro256** generator of Blackman and Vigna. It is
+// faster than math/rand, has a period of 2^256 - 1 and passes the usual
+// statistical test suites. Like SplitMix64 it implements rand.Source64 and
+// sampler.Rand, and its state can be saved and restored.
+type Xoshiro256 struct {
+	s [4]uint64
+}
+
+// NewXoshiro256 returns a generator seeded with seed.
+func NewXoshiro256(seed int64) *Xoshiro256 {
+	x := &Xoshiro256{}
+	x.Seed(seed)
+
+	return x
+}
+
+// Seed resets the state of the generator. The four words of the state are
+// drawn from a SplitMix64 seeded with seed, as recommended by the authors, so
+// that the state is never all zeros.
+func (x *Xoshiro256) Seed(seed int64) {
+	sm := NewSplitMix64(seed)
+	for i := range x.s {
+		x.s[i] = sm.Uint64()
+	}
+}
+
+// Uint64 returns a pseudo-random 64-bit integer.
+func (x *Xoshiro256) Uint64() uint64 {
+	s := &x.s
+	result := rotl(s[1]*5, 7) * 9
+	t := s[1] << 17
+
+	s[2] ^= s[0]
+	s[3] ^= s[1]
+	s[1] ^= s[2]
+	s[0] ^= s[3]
+	s[2] ^= t
+	s[3] = rotl(s[3], 45)
+
+	return result
+}
+
+func rotl(x uint64, k uint) uint64 {
+	return (x << k) | (x >> (64 - k))
+}
+
+// Int63 returns a non-negative pseudo-random 63-bit integer.
+func (x *Xoshiro256) Int63() int64 {
+	return int64(x.Uint64() >> 1)
+}
+
+// Intn returns a pseudo-random integer in [0, n). It panics if n <= 0.
+func (x *Xoshiro256) Intn(n int) int {
+	return boundedUint64(x.Uint64, n)
+}
+
+// Float64 returns a pseudo-random float in [0, 1).
+func (x *Xoshiro256) Float64() float64 {
+	return float64(x.Uint64()>>11) / (1 << 53)
+}
+
+// MarshalBinary returns the state of the generator.
+func (x *Xoshiro256) MarshalBinary() ([]byte, error) {
+	state := make([]byte, 32)
+	for i, v := range x.s {
+		binary.LittleEndian.PutUint64(state[8*i:], v)
+	}
+
+	return state, nil
+}
+
+// UnmarshalBinary restores a state returned by MarshalBinary.
+func (x *Xoshiro256) UnmarshalBinary(state []byte) error {
+	if len(state) != 32 {
+		return errors.Errorf("invalid state length %d", len(state))
+	}
+	var s [4]uint64
+	for i := range s {
+		s[i] = binary.LittleEndian.Uint64(state[8*i:])
+	}
+	if s == [4]uint64{} {
+		return errors.New("the state cannot be all zeros")
+	}
+	x.s = s
+
+	return nil
+}
+
+// boundedUint64 returns an integer in [0, n) drawn from next without modulo
+// bias. It panics if n <= 0.
+func boundedUint64(next func() uint64, n int) int {
+	if n <= 0 {
+		panic("invalid argument to Intn")
+	}
+	max := uint64(n)
+	threshold := -max % max // 2^64 mod n, values below it would bias the result
+	for {
+		v := next()
+		if v >= threshold {
+			return int(v % max)
+		}
+	}
+}
+
+// SetRandSource replaces the random source of the Bird, for instance with a
+// *Xoshiro256 or with rand.New(src) for any rand.Source64 src. The Bird draws
+// from RandSource only, so assigning the field directly is enough for
+// Process; SetRandSource also updates the alias and Fenwick samplers returned
+// by UserSampler for callers that sample from them directly.
+func (b *Bird) SetRandSource(source sampler.Rand) {
+	b.RandSource = source
+	for u := range b.userItemsSamplers {
+		if len(b.userItemsSamplers[u].AliasTable) > 0 {
+			b.userItemsSamplers[u].Source = source
+		}
+	}
+	for _, f := range b.fenwickSamplers {
+		f.Source = source
+	}
+}
+
+// ReSeed replaces the random source of the Bird and of its samplers with a
+// fresh one seeded with seed, so that the following calls to Process are
+// reproducible. The new source is of the same kind as the current one when
+// it is a *SplitMix64, a *Xoshiro256 or a *rand.Rand, and a *SplitMix64
+// otherwise.
+func (b *Bird) ReSeed(seed int64) {
+	var source sampler.Rand
+	switch b.RandSource.(type) {
+	case *Xoshiro256:
+		source = NewXoshiro256(seed)
+	case *rand.Rand:
+		source = rand.New(rand.NewSource(seed))
+	default:
+		source = NewSplitMix64(seed)
+	}
+	b.SetRandSource(source)
+}
+
+// RandState returns a snapshot of the state of the Bird's random source. The
+// snapshot can later be restored with SetRandState, on this Bird or on another
+// Bird built from the same data, to replay a Process call exactly. It fails
+// if RandSource cannot be serialized.
+func (b *Bird) RandState() ([]byte, error) {
+	m, ok := b.RandSource.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, errors.Errorf("the random source %T cannot be serialized", b.RandSource)
+	}
+
+	return m.MarshalBinary()
+}
+
+// SetRandState restores a state returned by RandState. The random source is
+// updated in place so that the samplers, which share it, observe the new
+// state.
+func (b *Bird) SetRandState(state []byte) error {
+	u, ok := b.RandSource.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return errors.Errorf("the random source %T cannot be restored", b.RandSource)
+	}
+
+	return u.UnmarshalBinary(state)
+}
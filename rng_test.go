@@ -0,0 +1,203 @@
+package birdland
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/rlouf/birdland/sampler"
+	"github.com/rlouf/birdland/sampler/samplertest"
+)
+
+func TestGeneratorsUniformity(t *testing.T) {
+	generators := map[string]sampler.Rand{
+		"SplitMix64": NewSplitMix64(42),
+		"Xoshiro256": NewXoshiro256(42),
+	}
+	for name, s := range generators {
+		const numBuckets, numSamples = 10, 100000
+		counts := make([]int, numBuckets)
+		for i := 0; i < numSamples; i++ {
+			counts[s.Intn(numBuckets)]++
+		}
+		expected := float64(numSamples) / numBuckets
+		for k, c := range counts {
+			if math.Abs(float64(c)-expected) > 0.05*expected {
+				t.Errorf("%s: bucket %d has %d samples, expected about %.0f", name, k, c, expected)
+			}
+		}
+		uniform := make([]float64, numBuckets)
+		for k := range uniform {
+			uniform[k] = 1
+		}
+		if chi2 := samplertest.ChiSquare(counts, uniform); chi2 > samplertest.ChiSquareQuantile(numBuckets-1, 0.001) {
+			t.Errorf("%s: the chi-square statistic %.2f rejects uniformity", name, chi2)
+		}
+
+		for i := 0; i < numSamples; i++ {
+			if f := s.Float64(); f < 0 || f >= 1 {
+				t.Fatalf("%s: Float64 returned %v, outside of [0, 1)", name, f)
+			}
+		}
+	}
+}
+
+func TestXoshiro256State(t *testing.T) {
+	x := NewXoshiro256(42)
+	state, err := x.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Xoshiro256: MarshalBinary should not have raised an error but did: %v", err)
+	}
+	first := []uint64{x.Uint64(), x.Uint64(), x.Uint64()}
+
+	if err := x.UnmarshalBinary(state); err != nil {
+		t.Fatalf("Xoshiro256: UnmarshalBinary should not have raised an error but did: %v", err)
+	}
+	second := []uint64{x.Uint64(), x.Uint64(), x.Uint64()}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Xoshiro256: restoring the state should replay the sequence %v, got %v", first, second)
+	}
+
+	if err := x.UnmarshalBinary(make([]byte, 32)); err == nil {
+		t.Errorf("Xoshiro256: UnmarshalBinary should have rejected an all-zero state but did not")
+	}
+}
+
+func TestSplitMix64State(t *testing.T) {
+	s := NewSplitMix64(42)
+	state, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatalf("SplitMix64: MarshalBinary should not have raised an error but did: %v", err)
+	}
+	first := []uint64{s.Uint64(), s.Uint64(), s.Uint64()}
+
+	if err := s.UnmarshalBinary(state); err != nil {
+		t.Fatalf("SplitMix64: UnmarshalBinary should not have raised an error but did: %v", err)
+	}
+	second := []uint64{s.Uint64(), s.Uint64(), s.Uint64()}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("SplitMix64: restoring the state should replay the sequence %v, got %v", first, second)
+	}
+
+	if err := s.UnmarshalBinary([]byte{1, 2, 3}); err == nil {
+		t.Errorf("SplitMix64: UnmarshalBinary should have raised an error on a short state but did not")
+	}
+}
+
+func TestBirdRandStateReplay(t *testing.T) {
+	cfg := NewBirdCfg()
+	cfg.Depth = 3
+	cfg.Draws = 100
+	bird, err := NewBird(cfg, []float64{1, 2, 3, 4}, [][]int{[]int{0, 1, 2}, []int{1, 3}, []int{0, 2, 3}})
+	if err != nil {
+		t.Fatalf("RandState: Bird initialization should not have raised an error but did: %v", err)
+	}
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 3, Weight: 2}}
+
+	state, err := bird.RandState()
+	if err != nil {
+		t.Fatalf("RandState: should not have raised an error but did: %v", err)
+	}
+	items, referrers, _ := bird.Process(query)
+
+	if err := bird.SetRandState(state); err != nil {
+		t.Fatalf("RandState: SetRandState should not have raised an error but did: %v", err)
+	}
+	replayedItems, replayedReferrers, _ := bird.Process(query)
+	if !reflect.DeepEqual(items, replayedItems) || !reflect.DeepEqual(referrers, replayedReferrers) {
+		t.Errorf("RandState: replaying from the same state should produce identical output")
+	}
+
+	bird.RandSource = rand.New(rand.NewSource(42))
+	if _, err := bird.RandState(); err == nil {
+		t.Errorf("RandState: a math/rand source cannot be serialized but no error was raised")
+	}
+}
+
+func TestBirdReSeed(t *testing.T) {
+	cfg := NewBirdCfg()
+	cfg.Depth = 3
+	cfg.Draws = 100
+	bird, err := NewBird(cfg, []float64{1, 2, 3, 4}, [][]int{[]int{0, 1, 2}, []int{1, 3}, []int{0, 2, 3}})
+	if err != nil {
+		t.Fatalf("ReSeed: Bird initialization should not have raised an error but did: %v", err)
+	}
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 3, Weight: 2}}
+
+	sources := map[string]sampler.Rand{
+		"SplitMix64": NewSplitMix64(1),
+		"Xoshiro256": NewXoshiro256(1),
+		"math/rand":  rand.New(rand.NewSource(1)),
+	}
+	for name, source := range sources {
+		bird.SetRandSource(source)
+
+		bird.ReSeed(42)
+		items, referrers, _ := bird.Process(query)
+		bird.ReSeed(42)
+		replayedItems, replayedReferrers, _ := bird.Process(query)
+		if !reflect.DeepEqual(items, replayedItems) || !reflect.DeepEqual(referrers, replayedReferrers) {
+			t.Errorf("ReSeed: %s: two runs after the same seed should produce identical output", name)
+		}
+		if reflect.TypeOf(bird.RandSource) != reflect.TypeOf(source) {
+			t.Errorf("ReSeed: %s: expected a new %T, got %T", name, source, bird.RandSource)
+		}
+	}
+}
+
+func TestBirdRandSourceReplaced(t *testing.T) {
+	cfg := NewBirdCfg()
+	cfg.Depth = 3
+	cfg.Draws = 100
+	bird, err := NewBird(cfg, []float64{1, 2, 3, 4}, [][]int{[]int{0, 1, 2}, []int{1, 3}, []int{0, 2, 3}})
+	if err != nil {
+		t.Fatalf("RandSource: Bird initialization should not have raised an error but did: %v", err)
+	}
+	query := []QueryItem{{Item: 0, Weight: 1}, {Item: 3, Weight: 2}}
+
+	// Replacing the field without SetRandSource must reach the per-user draws.
+	bird.RandSource = NewSplitMix64(7)
+	items, _, _ := bird.Process(query)
+	bird.RandSource = NewSplitMix64(7)
+	replayed, _, _ := bird.Process(query)
+	if !reflect.DeepEqual(items, replayed) {
+		t.Errorf("RandSource: the samplers should draw from the new RandSource")
+	}
+}
+
+func benchmarkBirdProcessSource(source sampler.Rand, b *testing.B) {
+	itemWeights, usersToItems := newBenchmarkGraph(100000, 100000)
+	cfg := NewBirdCfg()
+	cfg.Depth = 3
+	cfg.Draws = 10000
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		b.Fatalf("Unable to initialize the random source benchmark: %v", err)
+	}
+	bird.SetRandSource(source)
+
+	query := make([]QueryItem, 100)
+	for i := range query {
+		query[i] = QueryItem{Item: usersToItems[i][0], Weight: 1}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := bird.Process(query); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBirdProcessMathRand(b *testing.B) {
+	benchmarkBirdProcessSource(rand.New(rand.NewSource(42)), b)
+}
+
+func BenchmarkBirdProcessSplitMix64(b *testing.B) {
+	benchmarkBirdProcessSource(NewSplitMix64(42), b)
+}
+
+func BenchmarkBirdProcessXoshiro256(b *testing.B) {
+	benchmarkBirdProcessSource(NewXoshiro256(42), b)
+}
@@ -2,7 +2,7 @@ package sampler
 
 import (
 	"fmt"
-	"math/rand"
+	"sort"
 
 	"github.com/pkg/errors"
 )
@@ -10,19 +10,32 @@ import (
 // AliasSampler implements the Alias Method to sample from a discrete
 // probability distribution. Initialized with the Vose Method, the
 // sampler takes O(n) to initialize and O(1) to sample.
+//
+// An AliasSampler is a small header over its tables: copies are cheap and
+// share the tables, so it must not hold state that cannot be copied.
 type AliasSampler struct {
 	ProbabilityTable []float64
 	AliasTable       []int
-	Source           *rand.Rand
+	Source           Rand
 }
 
-func NewAliasSampler(source *rand.Rand, weights []float64) (*AliasSampler, error) {
+// NewAliasSampler builds a sampler from non-negative, finite weights. It
+// returns an error naming the first invalid weight, and ErrZeroTotalWeight,
+// possibly wrapped, if all the weights are zero.
+func NewAliasSampler(source Rand, weights []float64) (*AliasSampler, error) {
+	return NewAliasSamplerScratch(source, weights, nil)
+}
+
+// NewAliasSamplerScratch is NewAliasSampler with the working memory of the
+// initialization taken from scratch, which saves its allocation when many
+// samplers are built in a row. A nil scratch allocates it.
+func NewAliasSamplerScratch(source Rand, weights []float64, scratch *AliasScratch) (*AliasSampler, error) {
 
 	if len(weights) == 0 {
 		return &AliasSampler{}, fmt.Errorf("weights is an empty slice")
 	}
 
-	probabilityTable, aliasTable, err := VoseInitialization(weights)
+	probabilityTable, aliasTable, err := voseInitialization(weights, scratch)
 	if err != nil {
 		return &AliasSampler{}, errors.Wrap(err, "cannot initialize the alias sampler")
 	}
@@ -35,6 +48,28 @@ func NewAliasSampler(source *rand.Rand, weights []float64) (*AliasSampler, error
 	return &t, nil
 }
 
+// AliasScratch holds the lists of small and large items used while
+// initializing the tables of an AliasSampler. It can be reused across
+// initializations, but not by several of them at once.
+type AliasScratch struct {
+	small, large []int
+}
+
+// NewAliasScratch returns the scratch space needed to build samplers of up to
+// n items. It grows when it is used for larger samplers.
+func NewAliasScratch(n int) *AliasScratch {
+	return &AliasScratch{small: make([]int, n), large: make([]int, n)}
+}
+
+// reserve returns the two lists, with room for n items each.
+func (s *AliasScratch) reserve(n int) ([]int, []int) {
+	if len(s.small) < n {
+		s.small, s.large = make([]int, n), make([]int, n)
+	}
+
+	return s.small[:n], s.large[:n]
+}
+
 // Sample generates a slice of items obtained by sampling the original distribution.
 func (t *AliasSampler) Sample(numSamples int) []int {
 	n := len(t.AliasTable)
@@ -43,85 +78,195 @@ func (t *AliasSampler) Sample(numSamples int) []int {
 	}
 
 	samples := make([]int, numSamples)
-	for i := 0; i < numSamples; i++ {
-		k := t.Source.Intn(n)
-		toss := t.Source.Float64()
-		if toss < t.ProbabilityTable[k] {
-			samples[i] = k
-		} else {
-			samples[i] = t.AliasTable[k]
+	t.SampleInto(samples)
+
+	return samples
+}
+
+// SampleInto fills dst with items obtained by sampling the original
+// distribution, and returns the number of items written. Reusing dst across
+// calls avoids the allocation of Sample. Nothing is written if the sampler is
+// empty.
+func (t *AliasSampler) SampleInto(dst []int) int {
+	if len(t.AliasTable) == 0 {
+		return 0
+	}
+
+	for i := range dst {
+		dst[i] = t.SampleWith(t.Source)
+	}
+
+	return len(dst)
+}
+
+// Sample1 draws a single item from the sampler's own random source. Unlike
+// Sample(1) it does not allocate. The sampler must not be empty.
+func (t *AliasSampler) Sample1() int {
+	return t.SampleWith(t.Source)
+}
+
+// SampleWith draws a single item using source instead of the sampler's own
+// random source, which lets several goroutines share the sampler as long as
+// each one has its own source. The sampler must not be empty.
+func (t *AliasSampler) SampleWith(source Rand) int {
+	k := source.Intn(len(t.AliasTable))
+	toss := source.Float64()
+	if toss < t.ProbabilityTable[k] {
+		return k
+	}
+
+	return t.AliasTable[k]
+}
+
+// Probabilities returns the probability with which each item is drawn, as
+// encoded by the tables, in O(n).
+func (t *AliasSampler) Probabilities() []float64 {
+	n := len(t.ProbabilityTable)
+	probabilities := make([]float64, n)
+
+	// Each column of the table holds a mass of 1/n, split between its own
+	// item and its alias.
+	for k, p := range t.ProbabilityTable {
+		probabilities[k] += p / float64(n)
+		if p < 1 {
+			probabilities[t.AliasTable[k]] += (1 - p) / float64(n)
 		}
 	}
 
-	return samples
+	return probabilities
+}
+
+// Describe returns the topK most probable items in decreasing order of
+// probability, ties being resolved in favor of the smallest item, along with
+// their probabilities. It is meant for debugging. A negative topK is treated
+// as 0.
+func (t *AliasSampler) Describe(topK int) ([]int, []float64) {
+	return TopProbabilities(t.Probabilities(), topK)
+}
+
+// TopProbabilities returns the topK indices with the highest probabilities,
+// in the order of Describe, and their probabilities. A negative topK is
+// treated as 0.
+func TopProbabilities(probabilities []float64, topK int) ([]int, []float64) {
+	if topK < 0 {
+		topK = 0
+	}
+	indices := make([]int, len(probabilities))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool { return probabilities[indices[i]] > probabilities[indices[j]] })
+	if topK < len(indices) {
+		indices = indices[:topK]
+	}
+
+	top := make([]float64, len(indices))
+	for i, index := range indices {
+		top[i] = probabilities[index]
+	}
+
+	return indices, top
 }
 
 // VoseInitialization initialises the probability and alias tables using Vose's
 // method. Vose's method runs in O(n) and is more numerically stable than
 // alternatives. See http://www.keithschwarz.com/darts-dice-coins/ for more
 // details.
+//
+// The tables only depend on the weights: the items are put in the lists of
+// small and large items in index order and taken from them first in, first
+// out, and every operation is a single IEEE 754 operation that Go rounds the
+// same way on every platform, so the tables are identical wherever they are
+// built.
 func VoseInitialization(weights []float64) ([]float64, []int, error) {
+	return voseInitialization(weights, nil)
+}
 
-	normalizedWeights, err := normalize(weights)
-	if err != nil {
+// voseInitialization builds the tables in O(n) without allocating more than
+// the tables themselves when scratch is large enough. The probability table
+// holds the normalized weights while they are worked on, and the lists of
+// small and large items are queues over scratch: each list never holds more
+// than n items at a time, so its queue wraps around n slots.
+func voseInitialization(weights []float64, scratch *AliasScratch) ([]float64, []int, error) {
+	n := len(weights)
+	probabilityTable := make([]float64, n)
+	if err := normalize(probabilityTable, weights); err != nil {
 		return nil, nil, errors.Wrap(err, "cannot normalize input weights")
 	}
 
-	small := make([]int, 0, len(normalizedWeights))
-	large := make([]int, 0, len(normalizedWeights))
-	for i, w := range normalizedWeights {
+	if scratch == nil {
+		scratch = &AliasScratch{}
+	}
+	small, large := scratch.reserve(n)
+	var numSmall, numLarge int
+	for i, w := range probabilityTable {
 		if w < 1.0 {
-			small = append(small, i)
+			small[numSmall] = i
+			numSmall++
 		} else {
-			large = append(large, i)
+			large[numLarge] = i
+			numLarge++
 		}
 	}
 
-	aliasTable := make([]int, len(weights))
-	probabilityTable := make([]float64, len(weights))
-	var g, l int
-	for (len(small) > 0) && (len(large) > 0) {
-		l, small = small[0], small[1:]
-		g, large = large[0], large[1:]
+	// The items are taken from the head of each queue and put back at its
+	// tail, in the order in which they enter the lists.
+	aliasTable := make([]int, n)
+	var smallHead, largeHead int
+	for numSmall > 0 && numLarge > 0 {
+		l := small[smallHead]
+		smallHead, numSmall = (smallHead+1)%n, numSmall-1
+		g := large[largeHead]
+		largeHead, numLarge = (largeHead+1)%n, numLarge-1
 
+		// probabilityTable[l] keeps its normalized weight.
 		aliasTable[l] = g
-		probabilityTable[l] = normalizedWeights[l]
 
-		normalizedWeights[g] = (normalizedWeights[g] + normalizedWeights[l]) - 1.0
-		if normalizedWeights[g] < 1.0 {
-			small = append(small, g)
+		probabilityTable[g] = (probabilityTable[g] + probabilityTable[l]) - 1.0
+		if probabilityTable[g] < 1.0 {
+			small[(smallHead+numSmall)%n] = g
+			numSmall++
 		} else {
-			large = append(large, g)
+			large[(largeHead+numLarge)%n] = g
+			numLarge++
 		}
 	}
 
-	for len(large) > 0 {
-		g, large = large[0], large[1:]
-		probabilityTable[g] = 1
+	// What is left in either list has a normalized weight of 1 up to
+	// rounding errors, and keeps its own column. Giving these columns a
+	// probability of exactly 1 keeps the rounding errors, which may differ
+	// across platforms and compilers, from sending them to another item.
+	for ; numLarge > 0; numLarge-- {
+		probabilityTable[large[largeHead]] = 1
+		largeHead = (largeHead + 1) % n
 	}
-	for len(small) > 0 {
-		l, small = small[0], small[1:]
-		probabilityTable[g] = 1
+	for ; numSmall > 0; numSmall-- {
+		probabilityTable[small[smallHead]] = 1
+		smallHead = (smallHead + 1) % n
 	}
 
 	return probabilityTable, aliasTable, nil
 }
 
-// normalize prepares the weights for the algorithm's initialization.
-func normalize(weights []float64) ([]float64, error) {
+// normalize writes the weights, normalized for the algorithm's
+// initialization, to dst, which must be as long as weights. It returns
+// ErrZeroTotalWeight if all the weights are zero.
+func normalize(dst, weights []float64) error {
 	var sum float64
-	for _, w := range weights {
-		if w < 0 {
-			return nil, fmt.Errorf("found negative weight %v", w)
+	for i, w := range weights {
+		if err := checkWeight(w); err != nil {
+			return errors.Wrapf(err, "at index %d", i)
 		}
 		sum += w
 	}
+	if sum == 0 {
+		return ErrZeroTotalWeight
+	}
 
 	n := len(weights)
-	normalizedWeights := make([]float64, n)
 	for i, weight := range weights {
-		normalizedWeights[i] = float64(n) * weight / sum
+		dst[i] = float64(n) * weight / sum
 	}
 
-	return normalizedWeights, nil
+	return nil
 }
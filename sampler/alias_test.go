@@ -1,8 +1,14 @@
 package sampler
 
 import (
+	"math"
 	"math/rand"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/rlouf/birdland/sampler/samplertest"
 )
 
 type NormalizeCase struct {
@@ -137,6 +143,16 @@ func TestAliasSampling(t *testing.T) {
 						got none instead`, ex.Name)
 		}
 
+		if ex.Valid && ex.NumSamples > 0 {
+			r.Seed(42)
+			first := ts.Sample1()
+			r.Seed(42)
+			if sample := ts.Sample(1)[0]; sample != first {
+				t.Errorf("alias sampler: Sample1: %s: expected %d like Sample(1), got %d", ex.Name, sample, first)
+			}
+			r.Seed(42)
+		}
+
 		samples := ts.Sample(ex.NumSamples)
 		if len(samples) != ex.NumSamples {
 			t.Errorf(`tower sampler: init: %s: expected %v samples,
@@ -153,6 +169,154 @@ func TestAliasSampling(t *testing.T) {
 	}
 }
 
+func TestAliasSampleInto(t *testing.T) {
+	weights := []float64{1, 2, 3, 4, 0, 10}
+	r := rand.New(rand.NewSource(42))
+	ts, err := NewAliasSampler(r, weights)
+	if err != nil {
+		t.Fatalf("alias sampler: SampleInto: init should not have raised an error, raised %v instead", err)
+	}
+
+	r.Seed(42)
+	expected := ts.Sample(100)
+	r.Seed(42)
+	samples := make([]int, 100)
+	if n := ts.SampleInto(samples); n != len(samples) {
+		t.Errorf("alias sampler: SampleInto: expected %d samples, got %d", len(samples), n)
+	}
+	if !reflect.DeepEqual(samples, expected) {
+		t.Errorf("alias sampler: SampleInto: expected the same samples as Sample, got %v instead of %v", samples, expected)
+	}
+
+	counts := make([]int, len(weights))
+	for i := 0; i < 100; i++ {
+		ts.SampleInto(samples)
+		for _, s := range samples {
+			counts[s]++
+		}
+	}
+	samplertest.AssertCounts(t, counts, weights, 0.001)
+
+	empty := AliasSampler{}
+	if n := empty.SampleInto(samples); n != 0 {
+		t.Errorf("alias sampler: SampleInto: expected an empty sampler to write 0 samples, wrote %d", n)
+	}
+}
+
+func TestAliasDescribe(t *testing.T) {
+	ts, err := NewAliasSampler(rand.New(rand.NewSource(42)), []float64{1, 3, 0, 4, 2})
+	if err != nil {
+		t.Fatalf("alias sampler: Describe: init should not have raised an error, raised %v instead", err)
+	}
+
+	expected := []float64{0.1, 0.3, 0, 0.4, 0.2}
+	for i, p := range ts.Probabilities() {
+		if math.Abs(p-expected[i]) > 1e-12 {
+			t.Errorf("alias sampler: Probabilities: expected %v, got %v", expected, ts.Probabilities())
+			break
+		}
+	}
+
+	indices, probabilities := ts.Describe(2)
+	if !reflect.DeepEqual(indices, []int{3, 1}) || math.Abs(probabilities[0]-0.4) > 1e-12 || math.Abs(probabilities[1]-0.3) > 1e-12 {
+		t.Errorf("alias sampler: Describe: expected indices [3 1] with probabilities [0.4 0.3], got %v and %v", indices, probabilities)
+	}
+	if indices, _ := ts.Describe(10); len(indices) != 5 {
+		t.Errorf("alias sampler: Describe: expected all 5 indices, got %v", indices)
+	}
+	if indices, _ := ts.Describe(-1); len(indices) != 0 {
+		t.Errorf("alias sampler: Describe: expected no indices for a negative topK, got %v", indices)
+	}
+}
+
+// The tables must be bit for bit identical on every platform, so that walks
+// seeded the same way are the same wherever the Bird was built.
+func TestVoseInitializationGolden(t *testing.T) {
+	cases := []struct {
+		Name          string
+		Weights       []float64
+		Probabilities []uint64
+		Aliases       []int
+	}{
+		{
+			Name:    "Inexact weights",
+			Weights: []float64{0.1, 0.2, 0.3, 0.4, 1.0 / 3, 2.0 / 3, 0, 1e-9},
+			Probabilities: []uint64{0x3fd9999999629fda, 0x3fe9999999629fda, 0x3fe3333332c53fb4, 0x3feffffffeed1f44,
+				0x3fd55555549e14d4, 0x3ff0000000000000, 0x0000000000000000, 0x3e312e0be801f1d9},
+			Aliases: []int{2, 3, 3, 5, 5, 0, 4, 5},
+		},
+		{
+			Name:          "Uniform weights",
+			Weights:       []float64{1, 1, 1},
+			Probabilities: []uint64{0x3ff0000000000000, 0x3ff0000000000000, 0x3ff0000000000000},
+			Aliases:       []int{0, 0, 0},
+		},
+		{
+			Name:          "One large weight",
+			Weights:       []float64{0.7, 0.1, 0.1, 0.1},
+			Probabilities: []uint64{0x3ff0000000000000, 0x3fd999999999999b, 0x3fd999999999999b, 0x3fd999999999999b},
+			Aliases:       []int{0, 0, 0, 0},
+		},
+	}
+
+	for _, ex := range cases {
+		probabilities, aliases, err := VoseInitialization(ex.Weights)
+		if err != nil {
+			t.Fatalf("alias sampler: golden: %s should not have raised an error, raised %v instead", ex.Name, err)
+		}
+		for i, p := range probabilities {
+			if math.Float64bits(p) != ex.Probabilities[i] {
+				t.Errorf("alias sampler: golden: %s: probability %d is %#016x instead of %#016x",
+					ex.Name, i, math.Float64bits(p), ex.Probabilities[i])
+			}
+		}
+		if !reflect.DeepEqual(aliases, ex.Aliases) {
+			t.Errorf("alias sampler: golden: %s: expected aliases %v, got %v", ex.Name, ex.Aliases, aliases)
+		}
+	}
+}
+
+func TestAliasSamplerInvalidWeights(t *testing.T) {
+	cases := map[string][]float64{
+		"Negative weight": {1, -1, 1},
+		"NaN weight":      {1, 1, math.NaN()},
+		"Infinite weight": {math.Inf(1), 1},
+	}
+	for name, weights := range cases {
+		_, err := NewAliasSampler(rand.New(rand.NewSource(42)), weights)
+		if err == nil {
+			t.Errorf("alias sampler: init: %s should have raised an error, got none instead", name)
+			continue
+		}
+		if !strings.Contains(err.Error(), "index") {
+			t.Errorf("alias sampler: init: %s: expected the error to name the index of the weight, got %v", name, err)
+		}
+	}
+
+	_, err := NewAliasSampler(rand.New(rand.NewSource(42)), []float64{0, 0, 0})
+	if errors.Cause(err) != ErrZeroTotalWeight {
+		t.Errorf("alias sampler: init: expected all-zero weights to raise ErrZeroTotalWeight, got %v", err)
+	}
+}
+
+func TestAliasSamplerScratch(t *testing.T) {
+	scratch := NewAliasScratch(4)
+	for _, n := range []int{4, 1, 100, 3} {
+		weights := initWeightsForAliasBenchmarks(n)
+		expected, err := NewAliasSampler(nil, weights)
+		if err != nil {
+			t.Fatalf("alias sampler: scratch: init should not have raised an error, raised %v instead", err)
+		}
+		s, err := NewAliasSamplerScratch(nil, weights, scratch)
+		if err != nil {
+			t.Fatalf("alias sampler: scratch: init should not have raised an error, raised %v instead", err)
+		}
+		if !reflect.DeepEqual(s, expected) {
+			t.Errorf("alias sampler: scratch: the tables of %d weights depend on the scratch space", n)
+		}
+	}
+}
+
 // Benchmarks
 // ////////////////////////////////////////////////////////////////////////////
 
@@ -183,12 +347,26 @@ func BenchmarkAliasSamplerInit10000(b *testing.B)   { benchmarkAliasSamplerInit(
 func BenchmarkAliasSamplerInit100000(b *testing.B)  { benchmarkAliasSamplerInit(100000, b) }
 func BenchmarkAliasSamplerInit1000000(b *testing.B) { benchmarkAliasSamplerInit(1000000, b) }
 
+func BenchmarkAliasSamplerInitScratch1000000(b *testing.B) {
+	b.StopTimer()
+	weights := initWeightsForAliasBenchmarks(1000000)
+	r := rand.New(rand.NewSource(42))
+	scratch := NewAliasScratch(len(weights))
+	b.ReportAllocs()
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = NewAliasSamplerScratch(r, weights, scratch)
+	}
+}
+
 func benchmarkAliasSamplerSampling(numWeights int, numSamples int, b *testing.B) {
 
 	b.StopTimer()
 	weights := initWeightsForAliasBenchmarks(numWeights)
 	r := rand.New(rand.NewSource(42))
 	ts, _ := NewAliasSampler(r, weights)
+	b.ReportAllocs()
 	b.StartTimer()
 
 	for i := 0; i < b.N; i++ {
@@ -196,6 +374,36 @@ func benchmarkAliasSamplerSampling(numWeights int, numSamples int, b *testing.B)
 	}
 }
 
+func BenchmarkAliasSamplerSample1(b *testing.B) {
+	b.StopTimer()
+	weights := initWeightsForAliasBenchmarks(10000)
+	r := rand.New(rand.NewSource(42))
+	ts, _ := NewAliasSampler(r, weights)
+	b.ReportAllocs()
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = ts.Sample1()
+	}
+}
+
+func BenchmarkAliasSamplerSampleInto1000(b *testing.B) {
+	b.StopTimer()
+	weights := initWeightsForAliasBenchmarks(10000)
+	r := rand.New(rand.NewSource(42))
+	ts, _ := NewAliasSampler(r, weights)
+	samples := make([]int, 1000)
+	b.ReportAllocs()
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = ts.SampleInto(samples)
+	}
+}
+
+func BenchmarkAliasSamplerSampling1(b *testing.B) {
+	benchmarkAliasSamplerSampling(10000, 1, b)
+}
 func BenchmarkAliasSamplerSampling100(b *testing.B) {
 	benchmarkAliasSamplerSampling(10000, 100, b)
 }
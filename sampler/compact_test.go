@@ -0,0 +1,46 @@
+package sampler
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestCompactAliasSampler(t *testing.T) {
+	weights := initWeightsForAliasBenchmarks(1000)
+	r := rand.New(rand.NewSource(42))
+	ts, err := NewAliasSampler(r, weights)
+	if err != nil {
+		t.Fatalf("compact alias sampler: init: the alias sampler should not have raised an error, raised %v instead", err)
+	}
+	cs, err := NewCompactAliasSampler(r, weights)
+	if err != nil {
+		t.Fatalf("compact alias sampler: init: should not have raised an error, raised %v instead", err)
+	}
+
+	for i, p := range ts.ProbabilityTable {
+		if math.Abs(float64(cs.ProbabilityTable[i])-p) > 1e-7 {
+			t.Fatalf("compact alias sampler: init: probability %d is %v instead of %v", i, cs.ProbabilityTable[i], p)
+		}
+		if int(cs.AliasTable[i]) != ts.AliasTable[i] {
+			t.Fatalf("compact alias sampler: init: alias %d is %d instead of %d", i, cs.AliasTable[i], ts.AliasTable[i])
+		}
+	}
+
+	// Rounding only changes a draw whose toss falls between the float32 and
+	// the float64 probabilities, which does not happen in practice.
+	r.Seed(42)
+	expected := ts.Sample(100000)
+	r.Seed(42)
+	if samples := cs.Sample(100000); !reflect.DeepEqual(samples, expected) {
+		t.Errorf("compact alias sampler: sample: expected the same samples as the alias sampler")
+	}
+
+	if _, err := NewCompactAliasSampler(r, []float64{}); err == nil {
+		t.Errorf("compact alias sampler: init: empty weights should have raised an error, got none instead")
+	}
+	if _, err := NewCompactAliasSampler(r, []float64{1, -1}); err == nil {
+		t.Errorf("compact alias sampler: init: negative weights should have raised an error, got none instead")
+	}
+}
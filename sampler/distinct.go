@@ -0,0 +1,129 @@
+package sampler
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// SampleWithoutReplacement draws k distinct indices with a probability
+// proportional to their weights, as if each index were drawn from the
+// remaining ones and then removed. The indices are returned in the order in
+// which they would have been drawn. It uses exponential races (the
+// Efraimidis-Spirakis method): each index of positive weight w gets the key
+// log(u)/w, u being uniform in (0, 1), and the k largest keys win, which
+// takes O(n log n) and a single pass over the source. *rand.Rand can be used
+// as source.
+//
+// Indices of zero weight are never drawn, so k cannot exceed the number of
+// positive weights.
+func SampleWithoutReplacement(source Rand, weights []float64, k int) ([]int, error) {
+	if k < 0 {
+		return nil, fmt.Errorf("cannot draw %d indices", k)
+	}
+
+	type race struct {
+		index int
+		key   float64
+	}
+	races := make([]race, 0, len(weights))
+	for i, w := range weights {
+		if err := checkWeight(w); err != nil {
+			return nil, errors.Wrapf(err, "at index %d", i)
+		}
+		if w == 0 {
+			continue
+		}
+		races = append(races, race{i, math.Log(source.Float64()) / w})
+	}
+	if k > len(races) {
+		return nil, fmt.Errorf("cannot draw %d distinct indices from %d positive weights", k, len(races))
+	}
+
+	// Ties, however unlikely, go to the smallest index whatever the sorting
+	// algorithm.
+	sort.SliceStable(races, func(i, j int) bool { return races[i].key > races[j].key })
+	indices := make([]int, k)
+	for i := range indices {
+		indices[i] = races[i].index
+	}
+
+	return indices, nil
+}
+
+// TopKGumbel returns the k indices with the largest weights perturbed by the
+// Gumbel-max trick: each index of positive weight w gets the key
+// log(w) + G, G following a standard Gumbel distribution, and the indices are
+// returned in decreasing order of key. The keys rank the indices as the
+// exponential races of SampleWithoutReplacement, so the indices follow the
+// same distribution, but k may exceed the number of positive weights, in
+// which case they are all returned. Indices of zero weight are never
+// returned.
+//
+// Only the k largest keys are kept, in a heap, which takes O(n log k): for a
+// small k it is cheaper than building a sampler to draw from, let alone
+// drawing again until k distinct indices are found.
+func TopKGumbel(source Rand, weights []float64, k int) ([]int, error) {
+	if k < 0 {
+		return nil, fmt.Errorf("cannot draw %d indices", k)
+	}
+
+	top := make(gumbelHeap, 0, k)
+	for i, w := range weights {
+		if err := checkWeight(w); err != nil {
+			return nil, errors.Wrapf(err, "at index %d", i)
+		}
+		if w == 0 || k == 0 {
+			continue
+		}
+		// A u of 0 gives a key of -Inf, which still ranks the index.
+		u := source.Float64()
+		key := gumbelKey{i, math.Log(w) - math.Log(-math.Log(u))}
+		if len(top) < k {
+			heap.Push(&top, key)
+		} else if key.before(top[0]) {
+			top[0] = key
+			heap.Fix(&top, 0)
+		}
+	}
+
+	sort.Slice(top, func(i, j int) bool { return top[i].before(top[j]) })
+	indices := make([]int, len(top))
+	for i, key := range top {
+		indices[i] = key.index
+	}
+
+	return indices, nil
+}
+
+// gumbelKey is the perturbed log weight of an index.
+type gumbelKey struct {
+	index int
+	key   float64
+}
+
+// before tells whether k ranks before other: its key is larger or, however
+// unlikely, equal with a smaller index.
+func (k gumbelKey) before(other gumbelKey) bool {
+	if k.key != other.key {
+		return k.key > other.key
+	}
+	return k.index < other.index
+}
+
+// gumbelHeap is a heap of keys whose root ranks last.
+type gumbelHeap []gumbelKey
+
+func (h gumbelHeap) Len() int            { return len(h) }
+func (h gumbelHeap) Less(i, j int) bool  { return h[j].before(h[i]) }
+func (h gumbelHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *gumbelHeap) Push(x interface{}) { *h = append(*h, x.(gumbelKey)) }
+func (h *gumbelHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,142 @@
+package sampler
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/rlouf/birdland/sampler/samplertest"
+)
+
+func TestSampleWithoutReplacement(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	weights := []float64{1, 2, 0, 3, 4}
+
+	// Exact probability that each index is among the 2 drawn: it is either
+	// drawn first, or second after some other index j.
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	expected := make([]float64, len(weights))
+	for i, wi := range weights {
+		expected[i] = wi / total
+		for j, wj := range weights {
+			if j != i {
+				expected[i] += wj / total * wi / (total - wj)
+			}
+		}
+	}
+
+	const repetitions = 100000
+	counts := make([]int, len(weights))
+	for n := 0; n < repetitions; n++ {
+		indices, err := SampleWithoutReplacement(r, weights, 2)
+		if err != nil {
+			t.Fatalf("sample without replacement: should not have raised an error, raised %v instead", err)
+		}
+		if len(indices) != 2 || indices[0] == indices[1] {
+			t.Fatalf("sample without replacement: expected 2 distinct indices, got %v", indices)
+		}
+		for _, i := range indices {
+			counts[i]++
+		}
+	}
+	for i, c := range counts {
+		if p := float64(c) / repetitions; math.Abs(p-expected[i]) > 0.01 {
+			t.Errorf("sample without replacement: index %d was drawn with probability %.4f, expected %.4f", i, p, expected[i])
+		}
+	}
+
+	all, err := SampleWithoutReplacement(r, weights, 4)
+	if err != nil || len(all) != 4 {
+		t.Errorf("sample without replacement: expected the 4 indices of positive weight, got %v (error: %v)", all, err)
+	}
+
+	invalid := map[string]struct {
+		weights []float64
+		k       int
+	}{
+		"More indices than positive weights": {weights, 5},
+		"Negative k":                         {weights, -1},
+		"Negative weight":                    {[]float64{1, -1}, 1},
+		"NaN weight":                         {[]float64{1, math.NaN()}, 1},
+	}
+	for name, ex := range invalid {
+		if _, err := SampleWithoutReplacement(r, ex.weights, ex.k); err == nil {
+			t.Errorf("sample without replacement: %s should have raised an error, got none instead", name)
+		}
+	}
+}
+
+func TestTopKGumbel(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	weights := []float64{1, 2, 0, 3, 4}
+
+	// The first index follows the weights, as with any draw, and the second
+	// one follows the weights of the others: the pairs are drawn without
+	// replacement.
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	pairs := make([]float64, len(weights)*len(weights))
+	for i, wi := range weights {
+		for j, wj := range weights {
+			if i != j && wi > 0 {
+				pairs[i*len(weights)+j] = wi / total * wj / (total - wi)
+			}
+		}
+	}
+
+	const repetitions = 100000
+	first := make([]int, len(weights))
+	pairCounts := make([]int, len(pairs))
+	for n := 0; n < repetitions; n++ {
+		indices, err := TopKGumbel(r, weights, 2)
+		if err != nil {
+			t.Fatalf("top k Gumbel: should not have raised an error, raised %v instead", err)
+		}
+		if len(indices) != 2 || indices[0] == indices[1] {
+			t.Fatalf("top k Gumbel: expected 2 distinct indices, got %v", indices)
+		}
+		first[indices[0]]++
+		pairCounts[indices[0]*len(weights)+indices[1]]++
+	}
+	samplertest.AssertCounts(t, first, weights, 0.001)
+	samplertest.AssertCounts(t, pairCounts, pairs, 0.001)
+
+	all, err := TopKGumbel(r, weights, 10)
+	if err != nil || len(all) != 4 {
+		t.Errorf("top k Gumbel: expected the 4 indices of positive weight, got %v (error: %v)", all, err)
+	}
+	for _, i := range all {
+		if weights[i] == 0 {
+			t.Errorf("top k Gumbel: the index %d of zero weight was returned", i)
+		}
+	}
+
+	invalid := map[string]struct {
+		weights []float64
+		k       int
+	}{
+		"Negative k":      {weights, -1},
+		"Negative weight": {[]float64{1, -1}, 1},
+		"NaN weight":      {[]float64{1, math.NaN()}, 1},
+	}
+	for name, ex := range invalid {
+		if _, err := TopKGumbel(r, ex.weights, ex.k); err == nil {
+			t.Errorf("top k Gumbel: %s should have raised an error, got none instead", name)
+		}
+	}
+}
+
+func BenchmarkTopKGumbel10Of100000(b *testing.B) {
+	weights := initWeightsForAliasBenchmarks(100000)
+	r := rand.New(rand.NewSource(42))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = TopKGumbel(r, weights, 10)
+	}
+}
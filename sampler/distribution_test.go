@@ -0,0 +1,56 @@
+package sampler
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/rlouf/birdland/sampler/samplertest"
+)
+
+// towerSample1 draws one item at a time from a TowerSampler.
+type towerSample1 struct {
+	*TowerSampler
+}
+
+func (t towerSample1) Sample1() int { return t.Sample(1)[0] }
+
+func TestSamplersDistribution(t *testing.T) {
+	weightSets := map[string][]float64{
+		"Uniform":     {1, 1, 1, 1, 1},
+		"Skewed":      {100, 1, 0.1, 10, 0.01, 5},
+		"Zero weight": {1, 0, 2, 0, 3},
+		"Random":      initWeightsForAliasBenchmarks(50),
+	}
+
+	for name, weights := range weightSets {
+		r := rand.New(rand.NewSource(42))
+		alias, err := NewAliasSampler(r, weights)
+		if err != nil {
+			t.Fatalf("distribution: %s: the alias sampler should not have raised an error, raised %v instead", name, err)
+		}
+		compact, err := NewCompactAliasSampler(r, weights)
+		if err != nil {
+			t.Fatalf("distribution: %s: the compact alias sampler should not have raised an error, raised %v instead", name, err)
+		}
+		fenwick, err := NewFenwickSampler(r, weights)
+		if err != nil {
+			t.Fatalf("distribution: %s: the Fenwick sampler should not have raised an error, raised %v instead", name, err)
+		}
+		tower, err := NewTowerSampler(r, weights)
+		if err != nil {
+			t.Fatalf("distribution: %s: the tower sampler should not have raised an error, raised %v instead", name, err)
+		}
+
+		samplers := map[string]samplertest.Sampler{
+			"alias":   alias,
+			"compact": compact,
+			"fenwick": fenwick,
+			"tower":   towerSample1{tower},
+		}
+		for samplerName, s := range samplers {
+			t.Run(name+"/"+samplerName, func(t *testing.T) {
+				samplertest.AssertDistribution(t, s, weights, 100000, 0.001)
+			})
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package sampler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Binary format of an AliasSampler, little-endian:
+//
+//	version       1 byte
+//	length        number of items n, 4 bytes
+//	probabilities n float64, 8 bytes each
+//	aliases       n uint32, 4 bytes each
+const aliasEncodingVersion byte = 1
+
+// aliasHeaderSize is the size of the version and the length.
+const aliasHeaderSize = 1 + 4
+
+// MarshalBinary implements encoding.BinaryMarshaler. The random source is not
+// encoded.
+func (t *AliasSampler) MarshalBinary() ([]byte, error) {
+	n := len(t.ProbabilityTable)
+	if len(t.AliasTable) != n {
+		return nil, fmt.Errorf("the probability table has %d entries but the alias table %d", n, len(t.AliasTable))
+	}
+	if uint64(n) > math.MaxUint32 {
+		return nil, fmt.Errorf("cannot encode a sampler of %d items", n)
+	}
+
+	data := make([]byte, aliasHeaderSize+12*n)
+	data[0] = aliasEncodingVersion
+	binary.LittleEndian.PutUint32(data[1:], uint32(n))
+	probabilities := data[aliasHeaderSize:]
+	aliases := probabilities[8*n:]
+	for i, p := range t.ProbabilityTable {
+		binary.LittleEndian.PutUint64(probabilities[8*i:], math.Float64bits(p))
+	}
+	for i, a := range t.AliasTable {
+		binary.LittleEndian.PutUint32(aliases[4*i:], uint32(a))
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. It replaces the
+// tables of the sampler and keeps its random source. Data that does not hold
+// valid tables, in which case sampling could panic, results in an error and
+// leaves the sampler unchanged.
+func (t *AliasSampler) UnmarshalBinary(data []byte) error {
+	if len(data) < aliasHeaderSize {
+		return errors.New("the alias sampler data is truncated")
+	}
+	if data[0] != aliasEncodingVersion {
+		return fmt.Errorf("unsupported alias sampler encoding version %d", data[0])
+	}
+	n := uint64(binary.LittleEndian.Uint32(data[1:]))
+	if uint64(len(data)-aliasHeaderSize) != 12*n {
+		return fmt.Errorf("expected %d bytes of tables for %d items, got %d", 12*n, n, len(data)-aliasHeaderSize)
+	}
+
+	probabilities := data[aliasHeaderSize:]
+	aliases := probabilities[8*n:]
+	probabilityTable := make([]float64, n)
+	aliasTable := make([]int, n)
+	for i := range probabilityTable {
+		p := math.Float64frombits(binary.LittleEndian.Uint64(probabilities[8*i:]))
+		if !(p >= 0 && p <= 1) {
+			return fmt.Errorf("invalid probability %v for item %d", p, i)
+		}
+		a := binary.LittleEndian.Uint32(aliases[4*i:])
+		if uint64(a) >= n {
+			return fmt.Errorf("the alias %d of item %d is out of range", a, i)
+		}
+		probabilityTable[i], aliasTable[i] = p, int(a)
+	}
+	t.ProbabilityTable, t.AliasTable = probabilityTable, aliasTable
+
+	return nil
+}
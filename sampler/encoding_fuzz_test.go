@@ -0,0 +1,40 @@
+//go:build go1.18
+// +build go1.18
+
+package sampler
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func FuzzAliasSamplerUnmarshalBinary(f *testing.F) {
+	for _, n := range []int{1, 3, 10} {
+		s, _ := NewAliasSampler(nil, initWeightsForAliasBenchmarks(n))
+		data, _ := s.MarshalBinary()
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s := AliasSampler{Source: rand.New(rand.NewSource(42))}
+		if err := s.UnmarshalBinary(data); err != nil {
+			return
+		}
+		// Whatever the data, a sampler that was accepted can be sampled from
+		// and encodes back to the same bytes.
+		if len(s.AliasTable) > 0 {
+			for i := 0; i < 10; i++ {
+				if k := s.Sample1(); k < 0 || k >= len(s.AliasTable) {
+					t.Fatalf("sampled the item %d out of %d", k, len(s.AliasTable))
+				}
+			}
+		}
+		encoded, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatalf("cannot encode an accepted sampler: %v", err)
+		}
+		if string(encoded) != string(data) {
+			t.Fatalf("the sampler does not encode back to its data")
+		}
+	})
+}
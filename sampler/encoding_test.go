@@ -0,0 +1,67 @@
+package sampler
+
+import (
+	"encoding"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*AliasSampler)(nil)
+	_ encoding.BinaryUnmarshaler = (*AliasSampler)(nil)
+)
+
+func TestAliasSamplerBinary(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	for _, n := range []int{1, 5, 1000} {
+		s, err := NewAliasSampler(r, initWeightsForAliasBenchmarks(n))
+		if err != nil {
+			t.Fatalf("alias sampler: binary: init should not have raised an error, raised %v instead", err)
+		}
+		data, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatalf("alias sampler: binary: marshaling should not have raised an error, raised %v instead", err)
+		}
+		if len(data) != 5+12*n {
+			t.Errorf("alias sampler: binary: expected %d bytes for %d items, got %d", 5+12*n, n, len(data))
+		}
+
+		decoded := AliasSampler{Source: r}
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatalf("alias sampler: binary: unmarshaling should not have raised an error, raised %v instead", err)
+		}
+		if !reflect.DeepEqual(decoded.ProbabilityTable, s.ProbabilityTable) || !reflect.DeepEqual(decoded.AliasTable, s.AliasTable) {
+			t.Errorf("alias sampler: binary: the tables of %d items changed through a round trip", n)
+		}
+	}
+
+	empty := AliasSampler{}
+	data, err := empty.MarshalBinary()
+	if err != nil {
+		t.Fatalf("alias sampler: binary: marshaling an empty sampler should not have raised an error, raised %v instead", err)
+	}
+	if err := empty.UnmarshalBinary(data); err != nil || len(empty.AliasTable) != 0 {
+		t.Errorf("alias sampler: binary: expected an empty sampler back, got %v (error: %v)", empty, err)
+	}
+
+	valid, _ := (&AliasSampler{ProbabilityTable: []float64{0.5, 1}, AliasTable: []int{1, 0}}).MarshalBinary()
+	corrupt := map[string][]byte{
+		"Empty":                {},
+		"Unknown version":      append([]byte{2}, valid[1:]...),
+		"Truncated":            valid[:len(valid)-1],
+		"Trailing bytes":       append(append([]byte{}, valid...), 0),
+		"Alias out of range":   append(append([]byte{}, valid[:len(valid)-4]...), 2, 0, 0, 0),
+		"Invalid probability":  append(append(append([]byte{}, valid[:5]...), 0, 0, 0, 0, 0, 0, 0xf8, 0x7f), valid[13:]...),
+		"Length beyond tables": append([]byte{1, 0xff, 0xff, 0xff, 0xff}, valid[5:]...),
+	}
+	for name, data := range corrupt {
+		s := AliasSampler{ProbabilityTable: []float64{1}, AliasTable: []int{0}}
+		if err := s.UnmarshalBinary(data); err == nil {
+			t.Errorf("alias sampler: binary: %s data should have raised an error, got none instead", name)
+		}
+		if len(s.AliasTable) != 1 {
+			t.Errorf("alias sampler: binary: %s data should have left the sampler unchanged", name)
+		}
+	}
+}
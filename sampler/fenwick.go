@@ -0,0 +1,179 @@
+package sampler
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// FenwickSampler samples from a discrete probability distribution whose
+// weights can change. The weights are kept in a Fenwick (binary indexed)
+// tree, so that sampling and updating a weight both take O(log n), and
+// appending a weight takes O(log n) as well. Removing a weight rebuilds the
+// tree in O(n), which is still cheaper than rebuilding an AliasSampler.
+//
+// It is slower to sample from than an AliasSampler, and is meant for
+// distributions that are modified often.
+type FenwickSampler struct {
+	weights []float64
+	tree    []float64 // tree[i] is the sum of the weights in (i - lowbit(i), i], 1-indexed
+	Source  Rand
+}
+
+func NewFenwickSampler(source Rand, weights []float64) (*FenwickSampler, error) {
+	if len(weights) == 0 {
+		return &FenwickSampler{}, fmt.Errorf("weights is an empty slice")
+	}
+
+	var sum float64
+	for i, w := range weights {
+		if err := checkWeight(w); err != nil {
+			return &FenwickSampler{}, errors.Wrapf(err, "cannot initialize the Fenwick sampler: at index %d", i)
+		}
+		sum += w
+	}
+	if sum == 0 {
+		return &FenwickSampler{}, ErrZeroTotalWeight
+	}
+
+	t := FenwickSampler{Source: source}
+	t.weights = append([]float64{}, weights...)
+	t.build()
+
+	return &t, nil
+}
+
+// Len returns the number of weights.
+func (t *FenwickSampler) Len() int {
+	return len(t.weights)
+}
+
+// Weight returns the weight at index i.
+func (t *FenwickSampler) Weight(i int) float64 {
+	return t.weights[i]
+}
+
+// Weights returns a copy of the weights.
+func (t *FenwickSampler) Weights() []float64 {
+	return append([]float64{}, t.weights...)
+}
+
+// UpdateWeight replaces the weight at index i.
+func (t *FenwickSampler) UpdateWeight(i int, w float64) error {
+	if i < 0 || i >= len(t.weights) {
+		return fmt.Errorf("index %d is out of range", i)
+	}
+	if err := checkWeight(w); err != nil {
+		return err
+	}
+
+	delta := w - t.weights[i]
+	t.weights[i] = w
+	for k := i + 1; k < len(t.tree); k += k & -k {
+		t.tree[k] += delta
+	}
+
+	return nil
+}
+
+// Append adds a weight at the end.
+func (t *FenwickSampler) Append(w float64) error {
+	if err := checkWeight(w); err != nil {
+		return err
+	}
+
+	t.weights = append(t.weights, w)
+	if len(t.tree) == 0 {
+		t.tree = append(t.tree, 0)
+	}
+	k := len(t.tree)
+	t.tree = append(t.tree, w+t.prefix(k-1)-t.prefix(k-(k&-k)))
+
+	return nil
+}
+
+// Remove removes the weight at index i; the following weights move down by
+// one index.
+func (t *FenwickSampler) Remove(i int) error {
+	if i < 0 || i >= len(t.weights) {
+		return fmt.Errorf("index %d is out of range", i)
+	}
+
+	t.weights = append(t.weights[:i], t.weights[i+1:]...)
+	t.build()
+
+	return nil
+}
+
+// Sample generates a slice of items obtained by sampling the distribution.
+func (t *FenwickSampler) Sample(numSamples int) []int {
+	if len(t.weights) == 0 {
+		return []int{}
+	}
+
+	samples := make([]int, numSamples)
+	for i := range samples {
+		samples[i] = t.SampleWith(t.Source)
+	}
+
+	return samples
+}
+
+// Sample1 draws a single item from the sampler's own random source.
+func (t *FenwickSampler) Sample1() int {
+	return t.SampleWith(t.Source)
+}
+
+// SampleWith draws a single item using source instead of the sampler's own
+// random source. The sampler must not be empty and the sum of its weights
+// must be positive.
+func (t *FenwickSampler) SampleWith(source Rand) int {
+	x := source.Float64() * t.prefix(len(t.weights))
+
+	// Descend the tree to find the first index whose prefix sum exceeds x.
+	pos := 0
+	for step := highestPowerOfTwo(len(t.weights)); step > 0; step >>= 1 {
+		if next := pos + step; next < len(t.tree) && t.tree[next] <= x {
+			pos = next
+			x -= t.tree[next]
+		}
+	}
+
+	// Rounding errors can push the descent past the last positive weight.
+	for pos > 0 && (pos >= len(t.weights) || t.weights[pos] == 0) {
+		pos--
+	}
+
+	return pos
+}
+
+// build rebuilds the tree from the weights in O(n).
+func (t *FenwickSampler) build() {
+	t.tree = make([]float64, len(t.weights)+1)
+	for i, w := range t.weights {
+		k := i + 1
+		t.tree[k] += w
+		if parent := k + (k & -k); parent < len(t.tree) {
+			t.tree[parent] += t.tree[k]
+		}
+	}
+}
+
+// prefix returns the sum of the first n weights.
+func (t *FenwickSampler) prefix(n int) float64 {
+	var sum float64
+	for k := n; k > 0; k -= k & -k {
+		sum += t.tree[k]
+	}
+
+	return sum
+}
+
+func highestPowerOfTwo(n int) int {
+	p := 1
+	for p*2 <= n {
+		p *= 2
+	}
+
+	return p
+}
@@ -0,0 +1,101 @@
+package sampler
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// checkFrequencies draws from the sampler and checks that each item is drawn
+// with a frequency close to its share of the weights.
+func checkFrequencies(t *testing.T, name string, s *FenwickSampler, weights []float64) {
+	const numSamples = 100000
+	counts := make([]int, len(weights))
+	for _, sample := range s.Sample(numSamples) {
+		counts[sample]++
+	}
+
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+	for i, w := range weights {
+		expected := w / sum
+		got := float64(counts[i]) / numSamples
+		if math.Abs(got-expected) > 0.01 {
+			t.Errorf("fenwick sampler: %s: item %d drawn with frequency %v, expected %v", name, i, got, expected)
+		}
+	}
+}
+
+func TestFenwickSampler(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	weights := []float64{1, 0, 3, 2, 4}
+	s, err := NewFenwickSampler(r, weights)
+	if err != nil {
+		t.Fatalf("fenwick sampler: init should not have raised an error, raised %v instead", err)
+	}
+	checkFrequencies(t, "Initial weights", s, weights)
+
+	if err := s.UpdateWeight(1, 5); err != nil {
+		t.Fatalf("fenwick sampler: UpdateWeight should not have raised an error, raised %v instead", err)
+	}
+	if err := s.UpdateWeight(4, 0); err != nil {
+		t.Fatalf("fenwick sampler: UpdateWeight should not have raised an error, raised %v instead", err)
+	}
+	checkFrequencies(t, "Updated weights", s, []float64{1, 5, 3, 2, 0})
+
+	for _, w := range []float64{6, 1, 2} {
+		if err := s.Append(w); err != nil {
+			t.Fatalf("fenwick sampler: Append should not have raised an error, raised %v instead", err)
+		}
+	}
+	checkFrequencies(t, "Appended weights", s, []float64{1, 5, 3, 2, 0, 6, 1, 2})
+
+	if err := s.Remove(1); err != nil {
+		t.Fatalf("fenwick sampler: Remove should not have raised an error, raised %v instead", err)
+	}
+	checkFrequencies(t, "Removed weight", s, []float64{1, 3, 2, 0, 6, 1, 2})
+	if s.Len() != 7 || s.Weight(3) != 0 {
+		t.Errorf("fenwick sampler: expected 7 weights with a zero at index 3, got %v", s.Weights())
+	}
+
+	invalid := [][]float64{{}, {0, 0}, {1, -1}, {math.NaN()}}
+	for _, weights := range invalid {
+		if _, err := NewFenwickSampler(r, weights); err == nil {
+			t.Errorf("fenwick sampler: init with weights %v should have raised an error, got none instead", weights)
+		}
+	}
+	if err := s.UpdateWeight(7, 1); err == nil {
+		t.Errorf("fenwick sampler: UpdateWeight out of range should have raised an error, got none instead")
+	}
+	if err := s.Append(-1); err == nil {
+		t.Errorf("fenwick sampler: Append with a negative weight should have raised an error, got none instead")
+	}
+}
+
+func benchmarkFenwickSamplerUpdate(numWeights int, b *testing.B) {
+	weights := initWeightsForAliasBenchmarks(numWeights)
+	r := rand.New(rand.NewSource(42))
+	s, _ := NewFenwickSampler(r, weights)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = s.UpdateWeight(i%numWeights, float64(i%7))
+	}
+}
+
+func BenchmarkFenwickSamplerUpdate100000(b *testing.B) { benchmarkFenwickSamplerUpdate(100000, b) }
+
+func BenchmarkFenwickSamplerSample1(b *testing.B) {
+	weights := initWeightsForAliasBenchmarks(100000)
+	r := rand.New(rand.NewSource(42))
+	s, _ := NewFenwickSampler(r, weights)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = s.Sample1()
+	}
+}
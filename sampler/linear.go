@@ -0,0 +1,78 @@
+package sampler
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// LinearSampler draws an item by scanning the cumulative sums of the
+// weights. It takes O(n) to sample, but a single random number and one
+// slice, so it is faster to build and to sample from than an AliasSampler
+// for a handful of items, and takes half its memory.
+type LinearSampler struct {
+	Cumulative []float64 // cumulative sums of the weights
+	Source     Rand
+	last       int // last item with a positive weight
+}
+
+// NewLinearSampler builds a sampler from non-negative, finite weights. It
+// returns an error naming the first invalid weight, and ErrZeroTotalWeight if
+// all the weights are zero.
+func NewLinearSampler(source Rand, weights []float64) (*LinearSampler, error) {
+	if len(weights) == 0 {
+		return &LinearSampler{}, fmt.Errorf("weights is an empty slice")
+	}
+
+	t := LinearSampler{Cumulative: make([]float64, len(weights)), Source: source}
+	var sum float64
+	for i, w := range weights {
+		if err := checkWeight(w); err != nil {
+			return &LinearSampler{}, errors.Wrapf(err, "at index %d", i)
+		}
+		if w > 0 {
+			t.last = i
+		}
+		sum += w
+		t.Cumulative[i] = sum
+	}
+	if sum == 0 {
+		return &LinearSampler{}, ErrZeroTotalWeight
+	}
+
+	return &t, nil
+}
+
+// Sample generates a slice of items obtained by sampling the original distribution.
+func (t *LinearSampler) Sample(numSamples int) []int {
+	if len(t.Cumulative) == 0 {
+		return []int{}
+	}
+
+	samples := make([]int, numSamples)
+	for i := range samples {
+		samples[i] = t.SampleWith(t.Source)
+	}
+
+	return samples
+}
+
+// Sample1 draws a single item from the sampler's own random source. The
+// sampler must not be empty.
+func (t *LinearSampler) Sample1() int {
+	return t.SampleWith(t.Source)
+}
+
+// SampleWith draws a single item using source instead of the sampler's own
+// random source. The sampler must not be empty.
+func (t *LinearSampler) SampleWith(source Rand) int {
+	x := source.Float64() * t.Cumulative[len(t.Cumulative)-1]
+	for i, c := range t.Cumulative {
+		if c > x {
+			return i
+		}
+	}
+
+	// Rounding can make x equal to the total weight.
+	return t.last
+}
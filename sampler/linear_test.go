@@ -0,0 +1,69 @@
+package sampler
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/rlouf/birdland/sampler/samplertest"
+)
+
+func TestLinearSampler(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	weights := []float64{0, 1, 2, 0, 3}
+	s, err := NewLinearSampler(r, weights)
+	if err != nil {
+		t.Fatalf("linear sampler: init should not have raised an error, raised %v instead", err)
+	}
+	samplertest.AssertDistribution(t, s, weights, 100000, 0.001)
+
+	if got := len(s.Sample(10)); got != 10 {
+		t.Errorf("linear sampler: expected 10 samples, got %d", got)
+	}
+
+	invalid := map[string][]float64{
+		"Empty":           {},
+		"Zero weights":    {0, 0},
+		"Negative weight": {1, -1},
+	}
+	for name, weights := range invalid {
+		if _, err := NewLinearSampler(r, weights); err == nil {
+			t.Errorf("linear sampler: %s should have raised an error, got none instead", name)
+		}
+	}
+}
+
+// The linear and alias samplers are compared on collections of increasing
+// size to choose the largest one for which the linear sampler is used.
+func BenchmarkSmallSamplers(b *testing.B) {
+	for _, n := range []int{2, 4, 8, 16, 32} {
+		weights := initWeightsForAliasBenchmarks(n)
+		r := rand.New(rand.NewSource(42))
+
+		b.Run(fmt.Sprintf("LinearInit%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = NewLinearSampler(r, weights)
+			}
+		})
+		b.Run(fmt.Sprintf("AliasInit%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = NewAliasSampler(r, weights)
+			}
+		})
+
+		linear, _ := NewLinearSampler(r, weights)
+		alias, _ := NewAliasSampler(r, weights)
+		b.Run(fmt.Sprintf("LinearSample%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = linear.SampleWith(r)
+			}
+		})
+		b.Run(fmt.Sprintf("AliasSample%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = alias.SampleWith(r)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package sampler
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Multinomial draws the number of times each index would be drawn in n
+// independent draws with a probability proportional to the weights, without
+// performing the draws. The counts are drawn one index after the other from
+// binomial distributions conditioned on the previous counts, which takes
+// O(len(weights)) expected time whatever n.
+func Multinomial(source Rand, n int, weights []float64) ([]int, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("cannot perform %d draws", n)
+	}
+
+	var total float64
+	for i, w := range weights {
+		if err := checkWeight(w); err != nil {
+			return nil, errors.Wrapf(err, "at index %d", i)
+		}
+		total += w
+	}
+	if total == 0 {
+		return nil, ErrZeroTotalWeight
+	}
+
+	counts := make([]int, len(weights))
+	multinomialInto(source, n, weights, total, counts)
+
+	return counts, nil
+}
+
+// multinomialInto writes the counts of n draws from the weights, whose sum is
+// total, to counts.
+func multinomialInto(source Rand, n int, weights []float64, total float64, counts []int) {
+	last := len(weights) - 1
+	for last > 0 && weights[last] == 0 {
+		last--
+	}
+
+	remaining := n
+	for i := 0; i < last && remaining > 0; i++ {
+		if weights[i] == 0 {
+			continue
+		}
+		// The remaining draws are spread over indices i and above.
+		p := weights[i] / total
+		if p > 1 {
+			p = 1
+		}
+		counts[i] = binomial(source, remaining, p)
+		remaining -= counts[i]
+		total -= weights[i]
+	}
+	counts[last] += remaining
+}
+
+// SampleCounts returns the number of times each item would be drawn by
+// Sample(numSamples), without performing the draws; see Multinomial. The
+// probabilities of the items are recovered from the tables in O(n).
+func (t *AliasSampler) SampleCounts(numSamples int) []int {
+	n := len(t.AliasTable)
+	counts := make([]int, n)
+	if n == 0 {
+		return counts
+	}
+
+	multinomialInto(t.Source, numSamples, t.Probabilities(), 1, counts)
+
+	return counts
+}
+
+// binomial draws the number of successes in n trials of probability p.
+func binomial(source Rand, n int, p float64) int {
+	if n == 0 || p == 0 {
+		return 0
+	}
+	if p == 1 {
+		return n
+	}
+	if p > 0.5 {
+		return n - binomial(source, n, 1-p)
+	}
+	if float64(n)*p < 10 {
+		return binomialInversion(source, n, p)
+	}
+
+	return binomialBTRS(source, n, p)
+}
+
+// binomialInversion draws from the binomial distribution by inversion, in
+// O(np) expected time. p must be at most 0.5.
+func binomialInversion(source Rand, n int, p float64) int {
+	q := 1 - p
+	s := p / q
+	a := float64(n+1) * s
+	for {
+		r := math.Pow(q, float64(n))
+		u := source.Float64()
+		k := 0
+		for u > r {
+			u -= r
+			k++
+			r *= a/float64(k) - s
+			if k > n || r <= 0 {
+				break
+			}
+		}
+		if k <= n && u <= r {
+			return k
+		}
+	}
+}
+
+// binomialBTRS draws from the binomial distribution with the transformed
+// rejection with squeeze method of Hörmann, "The generation of binomial
+// random variates" (1993), in constant expected time. p must be at most 0.5
+// and np at least 10.
+func binomialBTRS(source Rand, n int, p float64) int {
+	fn := float64(n)
+	spq := math.Sqrt(fn * p * (1 - p))
+	b := 1.15 + 2.53*spq
+	a := -0.0873 + 0.0248*b + 0.01*p
+	c := fn*p + 0.5
+	vr := 0.92 - 4.2/b
+	r := p / (1 - p)
+	alpha := (2.83 + 5.1/b) * spq
+	m := math.Floor((fn + 1) * p)
+
+	for {
+		u := source.Float64() - 0.5
+		v := source.Float64()
+		us := 0.5 - math.Abs(u)
+		k := math.Floor((2*a/us+b)*u + c)
+		if k < 0 || k > fn {
+			continue
+		}
+		if us >= 0.07 && v <= vr {
+			return int(k)
+		}
+
+		v = math.Log(v * alpha / (a/(us*us) + b))
+		bound := (m+0.5)*math.Log((m+1)/(r*(fn-m+1))) +
+			(fn+1)*math.Log((fn-m+1)/(fn-k+1)) +
+			(k+0.5)*math.Log(r*(fn-k+1)/(k+1)) +
+			stirlingTail(m) + stirlingTail(fn-m) - stirlingTail(k) - stirlingTail(fn-k)
+		if v <= bound {
+			return int(k)
+		}
+	}
+}
+
+// stirlingTail returns log(k!) minus its Stirling approximation
+// (k+1/2)log(k+1) - (k+1) + log(2π)/2.
+func stirlingTail(k float64) float64 {
+	if k < 10 {
+		lgamma, _ := math.Lgamma(k + 1)
+		return lgamma - (k+0.5)*math.Log(k+1) + (k + 1) - 0.5*math.Log(2*math.Pi)
+	}
+	k1 := (k + 1) * (k + 1)
+
+	return (1.0/12 - (1.0/360-1.0/1260/k1)/k1) / (k + 1)
+}
@@ -0,0 +1,104 @@
+package sampler
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestBinomial(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	cases := []struct {
+		Name string
+		N    int
+		P    float64
+	}{
+		{"Inversion", 20, 0.3},
+		{"Transformed rejection", 10000, 0.2},
+		{"Probability above one half", 10000, 0.7},
+		{"Small probability", 1000000, 1e-6},
+	}
+
+	const repetitions = 20000
+	for _, ex := range cases {
+		var sum, sumSquares float64
+		for i := 0; i < repetitions; i++ {
+			k := binomial(r, ex.N, ex.P)
+			if k < 0 || k > ex.N {
+				t.Fatalf("binomial: %s: drew %d successes out of %d trials", ex.Name, k, ex.N)
+			}
+			sum += float64(k)
+			sumSquares += float64(k) * float64(k)
+		}
+		mean := sum / repetitions
+		variance := sumSquares/repetitions - mean*mean
+
+		expectedMean := float64(ex.N) * ex.P
+		expectedVariance := expectedMean * (1 - ex.P)
+		// 5 standard errors of the mean, and 5% on the variance.
+		if math.Abs(mean-expectedMean) > 5*math.Sqrt(expectedVariance/repetitions) {
+			t.Errorf("binomial: %s: expected a mean of %.3f, got %.3f", ex.Name, expectedMean, mean)
+		}
+		if math.Abs(variance-expectedVariance) > 0.05*expectedVariance {
+			t.Errorf("binomial: %s: expected a variance of %.3f, got %.3f", ex.Name, expectedVariance, variance)
+		}
+	}
+
+	if binomial(r, 10, 0) != 0 || binomial(r, 10, 1) != 10 || binomial(r, 0, 0.5) != 0 {
+		t.Errorf("binomial: degenerate distributions should be drawn exactly")
+	}
+}
+
+func TestAliasSampleCounts(t *testing.T) {
+	weights := []float64{1, 0, 2, 3, 4}
+	r := rand.New(rand.NewSource(42))
+	ts, err := NewAliasSampler(r, weights)
+	if err != nil {
+		t.Fatalf("alias sampler: SampleCounts: init should not have raised an error, raised %v instead", err)
+	}
+
+	// The mean count of each index over many repetitions, from SampleCounts
+	// and from counting the samples, must both match n times the
+	// probability of the index.
+	const n, repetitions = 1000, 2000
+	counted := make([]float64, len(weights))
+	sampled := make([]float64, len(weights))
+	samples := make([]int, n)
+	for i := 0; i < repetitions; i++ {
+		counts := ts.SampleCounts(n)
+		total := 0
+		for k, c := range counts {
+			counted[k] += float64(c)
+			total += c
+		}
+		if total != n {
+			t.Fatalf("alias sampler: SampleCounts: expected the counts to sum to %d, got %v", n, counts)
+		}
+
+		ts.SampleInto(samples)
+		for _, s := range samples {
+			sampled[s]++
+		}
+	}
+	if counted[1] != 0 {
+		t.Errorf("alias sampler: SampleCounts: the item of null weight was counted %v times", counted[1])
+	}
+	for k, w := range weights {
+		p := w / 10
+		expected := n * p
+		tolerance := 5 * math.Sqrt(n*p*(1-p)/repetitions)
+		if mean := counted[k] / repetitions; math.Abs(mean-expected) > tolerance {
+			t.Errorf("alias sampler: SampleCounts: expected a mean count of %.2f for index %d, got %.2f", expected, k, mean)
+		}
+		if mean := sampled[k] / repetitions; math.Abs(mean-expected) > tolerance {
+			t.Errorf("alias sampler: SampleCounts: expected Sample to draw index %d %.2f times on average, got %.2f", k, expected, mean)
+		}
+	}
+
+	if _, err := Multinomial(r, 10, []float64{0, 0}); err != ErrZeroTotalWeight {
+		t.Errorf("multinomial: expected all-zero weights to raise ErrZeroTotalWeight, got %v", err)
+	}
+	if counts, err := Multinomial(r, 10, []float64{0, 5, 0}); err != nil || counts[1] != 10 {
+		t.Errorf("multinomial: expected all the draws to go to the only positive weight, got %v (error: %v)", counts, err)
+	}
+}
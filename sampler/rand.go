@@ -0,0 +1,9 @@
+package sampler
+
+// Rand is the source of randomness the samplers draw from. *rand.Rand
+// satisfies it, so does any generator that can draw integers in [0, n) and
+// floats in [0, 1).
+type Rand interface {
+	Intn(n int) int
+	Float64() float64
+}
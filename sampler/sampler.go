@@ -0,0 +1,35 @@
+package sampler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrZeroTotalWeight is returned when a sampler is built from weights that
+// are all zero, from which no item can be drawn.
+var ErrZeroTotalWeight = errors.New("all weights are zero")
+
+// Sampler draws items, i.e. indices into the weights it was built from, from
+// a discrete probability distribution.
+type Sampler interface {
+	Sample(numSamples int) []int
+	Sample1() int
+	SampleWith(source Rand) int
+}
+
+var (
+	_ Sampler = (*AliasSampler)(nil)
+	_ Sampler = (*FenwickSampler)(nil)
+	_ Sampler = (*CompactAliasSampler)(nil)
+	_ Sampler = (*LinearSampler)(nil)
+)
+
+// checkWeight returns an error if w cannot be used as a sampling weight.
+func checkWeight(w float64) error {
+	if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+		return fmt.Errorf("invalid weight %v", w)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+// Package samplertest provides statistical helpers to check in tests that
+// samplers draw items from the right distribution. It lives apart from
+// sampler so that programs using the samplers do not import testing.
+package samplertest
+
+import (
+	"math"
+	"testing"
+)
+
+// Sampler is the part of sampler.Sampler that AssertDistribution uses.
+type Sampler interface {
+	Sample1() int
+}
+
+// ChiSquare returns Pearson's chi-square statistic of the observed counts
+// against the expected probabilities, which are scaled to the total count
+// and need not sum to 1. An item observed although its expected probability
+// is zero makes the statistic infinite; items of zero probability that are
+// not observed do not contribute.
+func ChiSquare(observed []int, expected []float64) float64 {
+	var total, sum float64
+	for i, c := range observed {
+		total += float64(c)
+		sum += expected[i]
+	}
+
+	var chi2 float64
+	for i, c := range observed {
+		e := total * expected[i] / sum
+		if e == 0 {
+			if c > 0 {
+				return math.Inf(1)
+			}
+			continue
+		}
+		chi2 += (float64(c) - e) * (float64(c) - e) / e
+	}
+
+	return chi2
+}
+
+// ChiSquareQuantile returns the value that the chi-square statistic with df
+// degrees of freedom exceeds with probability alpha, using the Wilson-Hilferty
+// approximation, which is within a few percent of the exact value.
+func ChiSquareQuantile(df int, alpha float64) float64 {
+	z := math.Sqrt2 * math.Erfinv(1-2*alpha)
+	k := float64(df)
+	x := 1 - 2/(9*k) + z*math.Sqrt(2/(9*k))
+
+	return k * x * x * x
+}
+
+// AssertCounts fails t if the counts of the items do not follow their
+// weights: the chi-square goodness of fit test rejects them at the alpha
+// level, or an item of zero weight was counted.
+func AssertCounts(t testing.TB, counts []int, weights []float64, alpha float64) {
+	t.Helper()
+
+	var df int
+	for i, w := range weights {
+		if w > 0 {
+			df++
+		} else if counts[i] > 0 {
+			t.Errorf("the item %d of zero weight was drawn %d times", i, counts[i])
+		}
+	}
+	df--
+	if df == 0 {
+		return
+	}
+
+	chi2 := ChiSquare(counts, weights)
+	if critical := ChiSquareQuantile(df, alpha); chi2 > critical {
+		t.Errorf("the counts do not follow the weights: the chi-square statistic is %.2f, above %.2f "+
+			"with %d degrees of freedom: %v", chi2, critical, df, counts)
+	}
+}
+
+// AssertDistribution draws draws items from s and fails t if they do not
+// follow weights, as checked by AssertCounts at the alpha level.
+func AssertDistribution(t testing.TB, s Sampler, weights []float64, draws int, alpha float64) {
+	t.Helper()
+
+	counts := make([]int, len(weights))
+	for i := 0; i < draws; i++ {
+		item := s.Sample1()
+		if item < 0 || item >= len(weights) {
+			t.Fatalf("drew the item %d, out of the %d weights", item, len(weights))
+		}
+		counts[item]++
+	}
+	AssertCounts(t, counts, weights, alpha)
+}
@@ -0,0 +1,41 @@
+package samplertest
+
+import (
+	"math"
+	"testing"
+)
+
+func TestChiSquare(t *testing.T) {
+	if chi2 := ChiSquare([]int{10, 20, 30}, []float64{1, 2, 3}); chi2 != 0 {
+		t.Errorf("ChiSquare: expected counts that match the weights to give 0, got %v", chi2)
+	}
+	// The expected counts are 20 and 20.
+	if chi2 := ChiSquare([]int{10, 30, 0}, []float64{1, 1, 0}); math.Abs(chi2-10) > 1e-12 {
+		t.Errorf("ChiSquare: expected 10, got %v", chi2)
+	}
+	if chi2 := ChiSquare([]int{10, 30, 1}, []float64{1, 1, 0}); !math.IsInf(chi2, 1) {
+		t.Errorf("ChiSquare: expected an item of zero weight to make the statistic infinite, got %v", chi2)
+	}
+}
+
+func TestChiSquareQuantile(t *testing.T) {
+	// Critical values at the 0.001 and 0.05 levels from the tables.
+	cases := []struct {
+		DF       int
+		Alpha    float64
+		Expected float64
+	}{
+		{1, 0.001, 10.83},
+		{4, 0.001, 18.47},
+		{9, 0.001, 27.88},
+		{4, 0.05, 9.49},
+		{99, 0.001, 148.23},
+	}
+	for _, ex := range cases {
+		q := ChiSquareQuantile(ex.DF, ex.Alpha)
+		if math.Abs(q-ex.Expected) > 0.05*ex.Expected {
+			t.Errorf("ChiSquareQuantile: expected about %v for %d degrees of freedom at the %v level, got %v",
+				ex.Expected, ex.DF, ex.Alpha, q)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package sampler
 
 import (
 	"fmt"
-	"math/rand"
 	"sort"
 
 	"github.com/pkg/errors"
@@ -12,10 +11,10 @@ import (
 // probability distribution.
 type TowerSampler struct {
 	CumulativeSum []float64
-	Source        *rand.Rand
+	Source        Rand
 }
 
-func NewTowerSampler(source *rand.Rand, weights []float64) (*TowerSampler, error) {
+func NewTowerSampler(source Rand, weights []float64) (*TowerSampler, error) {
 
 	if len(weights) == 0 {
 		return &TowerSampler{}, fmt.Errorf("weights is an empty slice")
@@ -58,7 +57,7 @@ func accumulate(weights []float64) ([]float64, error) {
 	}
 
 	if sum == 0 {
-		return nil, fmt.Errorf("all weights are null")
+		return nil, ErrZeroTotalWeight
 	}
 
 	for i, cumSum := range cumulativeSum {
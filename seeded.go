@@ -0,0 +1,179 @@
+package birdland
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rlouf/birdland/sampler"
+)
+
+// ProcessSeeded performs the same random walks as Process, spread over
+// workers goroutines, in a way that makes the result reproducible. Each of
+// the Cfg.Draws walks gets its own random source, seeded from seed and the
+// index of the walk, so the result only depends on seed and not on the number
+// of workers or on how the walks are scheduled. The Bird's RandSource is left
+// untouched.
+//
+// Items and referrers are ordered as in Process: first the items visited at
+// the first step of every walk, then those visited at the second step, and so
+// on. Walks that start from an item no one has interacted with are dropped,
+// and those that reach a dead end later on are handled according to
+// Cfg.Dangling.
+//
+// Unlike Process, which moves all the walks one step at a time, each walk is
+// performed on its own. With Cfg.LazyItemsToUsers, a Bird whose lists have
+// not been built yet thus pays a pass over the graph for every new item a
+// walk reaches, instead of one per step; Warmup avoids it.
+func (b *Bird) ProcessSeeded(query []QueryItem, seed int64, workers int) ([]int, []int, error) {
+	return b.processSeeded(query, seed, workers, nil)
+}
+
+// processSeeded is ProcessSeeded that takes the starts of the query from
+// cache, which may be nil.
+func (b *Bird) processSeeded(query []QueryItem, seed int64, workers int, cache *querySamplerCache) ([]int, []int, error) {
+	if len(query) == 0 {
+		return nil, nil, &EmptyQueryError{}
+	}
+	if workers < 1 {
+		return nil, nil, errors.New("the number of workers must be at least 1")
+	}
+
+	start := time.Now()
+	fixed, s, err := b.cachedQueryStarts(query, NewSplitMix64(subSeed(seed, b.Cfg.Draws+1)), cache)
+	if err != nil {
+		return nil, nil, wrap(err, "cannot sample items")
+	}
+	b.loadQueryItemUsers(query)
+
+	draws := b.Cfg.Draws
+
+	// The walks that start from a given item are spread at random, from a
+	// source of their own.
+	var fixedStarts []int
+	if len(fixed) > 0 {
+		fixedStarts = make([]int, draws)
+		for i := range fixedStarts {
+			fixedStarts[i] = -1
+		}
+		copy(fixedStarts, fixed)
+		shuffle(fixedStarts, NewSplitMix64(subSeed(seed, draws)))
+	}
+
+	// The starts are drawn before the walks, so that the number of steps
+	// can be computed from the walks that are actually performed. Each walk
+	// then goes on drawing from the source its start was drawn from.
+	rngs := make([]SplitMix64, draws)
+	walkStarts := make([]int, draws)
+	dropped := make([]bool, draws)
+	live := 0
+	for i := range walkStarts {
+		rngs[i].Seed(subSeed(seed, i))
+		if fixedStarts != nil && fixedStarts[i] >= 0 {
+			walkStarts[i] = fixedStarts[i]
+		} else {
+			walkStarts[i] = s.sample(&rngs[i])
+		}
+	}
+	b.loadItemUsers(walkStarts)
+	for i := range walkStarts {
+		if len(b.itemUsers(walkStarts[i])) == 0 {
+			dropped[i] = true
+			continue
+		}
+		live++
+	}
+	if live == 0 {
+		return nil, nil, wrap(ErrNoItemsSampled, "cannot sample items")
+	}
+	depth := b.walkDepth(live)
+
+	// The steps of walk i are stored at [i*depth, (i+1)*depth).
+	walkItems := make([]int, draws*depth)
+	walkReferrers := make([]int, draws*depth)
+
+	// Each worker takes the walks whose index is equal to its own modulo the
+	// number of workers, in increasing order, and stops at its first error.
+	// The error of the walk with the lowest index is then the first one in
+	// the errors of the workers, whatever the scheduling.
+	errs := make([]error, workers)
+	errIndex := make([]int, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			walker := b.newWalker(nil, s)
+			start := make([]int, 1)
+			var steps, referrers []int
+			visit := func(d int, items, stepReferrers []int) bool {
+				steps[d], referrers[d] = items[0], stepReferrers[0]
+				return true
+			}
+			for i := w; i < draws; i += workers {
+				if dropped[i] {
+					continue
+				}
+				start[0] = walkStarts[i]
+				steps = walkItems[i*depth : (i+1)*depth]
+				referrers = walkReferrers[i*depth : (i+1)*depth]
+				for d := range steps {
+					steps[d], referrers[d] = deadEnd, deadEnd
+				}
+				walker.rng = &rngs[i]
+				if err := walker.run(start, depth, visit); err != nil {
+					errs[w], errIndex[w] = err, i
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	firstErr := -1
+	for w, err := range errs {
+		if err != nil && (firstErr == -1 || errIndex[w] < errIndex[firstErr]) {
+			firstErr = w
+		}
+	}
+	if firstErr != -1 {
+		return nil, nil, wrapf(errs[firstErr], "cannot perform walk %d", errIndex[firstErr])
+	}
+	var items, referrers []int
+	for d := 0; d < depth; d++ {
+		for i := 0; i < draws; i++ {
+			if dropped[i] || walkItems[i*depth+d] == deadEnd {
+				continue
+			}
+			items = append(items, walkItems[i*depth+d])
+			referrers = append(referrers, walkReferrers[i*depth+d])
+		}
+	}
+	capVisits(b.Cfg.MaxVisits, &items, &referrers)
+	b.observeProcess(start, items)
+
+	return items, referrers, nil
+}
+
+// drawSeed draws a seed for ProcessSeeded from RandSource.
+func (b *Bird) drawSeed() int64 {
+	return int64(b.RandSource.Intn(1<<30))<<30 | int64(b.RandSource.Intn(1<<30))
+}
+
+// walkStep moves a single walk from item to one of the users who interacted
+// with it, then to one of this user's items. It returns the new item and the
+// user.
+func (b *Bird) walkStep(item int, rng sampler.Rand) (int, int, error) {
+	relatedUsers := b.itemUsers(item)
+	if len(relatedUsers) == 0 {
+		return 0, 0, &DeadEndError{Item: item, User: -1}
+	}
+	user := b.sampleReferrer(item, relatedUsers, rng)
+
+	newItem, err := b.sampleNextItem(user, item, rng)
+	if err != nil {
+		return 0, 0, &DeadEndError{Item: item, User: user}
+	}
+
+	return newItem, user, nil
+}
@@ -0,0 +1,91 @@
+package birdland
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestBirdProcessSeeded(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	numItems, numUsers := 50, 30
+	itemWeights := make([]float64, numItems)
+	for i := range itemWeights {
+		itemWeights[i] = r.Float64() + 0.1
+	}
+	usersToItems := make([][]int, numUsers)
+	for u := range usersToItems {
+		usersToItems[u] = r.Perm(numItems)[:1+r.Intn(10)]
+	}
+
+	cfg := NewBirdCfg()
+	cfg.Draws = 200
+	cfg.Depth = 3
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("ProcessSeeded: Bird initialization should not have raised an error but did: %v", err)
+	}
+	query := []QueryItem{{Item: usersToItems[0][0], Weight: 1}, {Item: usersToItems[1][0], Weight: 2}}
+
+	expectedItems, expectedReferrers, err := bird.ProcessSeeded(query, 7, 1)
+	if err != nil {
+		t.Fatalf("ProcessSeeded: 1 worker: should not have raised an error but did: %v", err)
+	}
+	if len(expectedItems) != cfg.Draws*cfg.Depth {
+		t.Errorf("ProcessSeeded: expected %d visits, got %d", cfg.Draws*cfg.Depth, len(expectedItems))
+	}
+	for _, workers := range []int{2, 4} {
+		items, referrers, err := bird.ProcessSeeded(query, 7, workers)
+		if err != nil {
+			t.Fatalf("ProcessSeeded: %d workers: should not have raised an error but did: %v", workers, err)
+		}
+		if !reflect.DeepEqual(items, expectedItems) || !reflect.DeepEqual(referrers, expectedReferrers) {
+			t.Errorf("ProcessSeeded: %d workers: the result differs from the one with 1 worker", workers)
+		}
+	}
+
+	items, _, err := bird.ProcessSeeded(query, 8, 4)
+	if err != nil {
+		t.Fatalf("ProcessSeeded: seed 8: should not have raised an error but did: %v", err)
+	}
+	if reflect.DeepEqual(items, expectedItems) {
+		t.Errorf("ProcessSeeded: different seeds should give different walks")
+	}
+
+	if _, _, err := bird.ProcessSeeded(query, 7, 0); err == nil {
+		t.Errorf("ProcessSeeded: 0 workers should have raised an error but did not")
+	}
+}
+
+func TestBirdProcessParallelism(t *testing.T) {
+	itemWeights := []float64{1, 2, 1, 3}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 2}, []int{2, 3}, []int{3, 0}}
+
+	var expected []int
+	for _, parallelism := range []int{2, 4} {
+		cfg := NewBirdCfg()
+		cfg.Depth = 2
+		cfg.Parallelism = parallelism
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("Parallelism: Bird initialization should not have raised an error but did: %v", err)
+		}
+		if err := bird.SetRandState([]byte{1, 2, 3, 4, 5, 6, 7, 8}); err != nil {
+			t.Fatalf("Parallelism: SetRandState should not have raised an error but did: %v", err)
+		}
+
+		items, referrers, err := bird.Process([]QueryItem{{Item: 0, Weight: 1}})
+		if err != nil {
+			t.Fatalf("Parallelism: %d: Process should not have raised an error but did: %v", parallelism, err)
+		}
+		if len(items) != cfg.Draws*cfg.Depth || len(referrers) != len(items) {
+			t.Errorf("Parallelism: %d: expected %d visits, got %d items and %d referrers",
+				parallelism, cfg.Draws*cfg.Depth, len(items), len(referrers))
+		}
+		if expected == nil {
+			expected = items
+		} else if !reflect.DeepEqual(items, expected) {
+			t.Errorf("Parallelism: %d: the result depends on the parallelism", parallelism)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package birdland
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ErrNotEnoughItems is returned along with the recommended items when fewer
+// than the requested number of them are left once the items already seen are
+// excluded. The items that are left are still returned.
+var ErrNotEnoughItems = errors.New("not enough unseen items to recommend")
+
+// SeenSet is a set of items a user has already seen, which ScoreOptions.Seen
+// excludes from the recommendations.
+type SeenSet interface {
+	Seen(item int) bool
+}
+
+// SeenBitset is a SeenSet where item i is seen if bit i%64 of word i/64 is
+// set. It is the most compact set when the seen items are a sizable part of
+// the catalog.
+type SeenBitset []uint64
+
+// NewSeenBitset returns a SeenBitset of the items, which must not be
+// negative.
+func NewSeenBitset(items []int) SeenBitset {
+	var bits SeenBitset
+	for _, item := range items {
+		for len(bits) <= item/64 {
+			bits = append(bits, 0)
+		}
+		bits[item/64] |= 1 << uint(item%64)
+	}
+
+	return bits
+}
+
+// Seen tells whether the bit of item is set.
+func (s SeenBitset) Seen(item int) bool {
+	if item < 0 || item/64 >= len(s) {
+		return false
+	}
+
+	return s[item/64]&(1<<uint(item%64)) != 0
+}
+
+// seenItems is the SeenSet returned by SeenItems.
+type seenItems map[int]bool
+
+func (s seenItems) Seen(item int) bool { return s[item] }
+
+// SeenItems returns a SeenSet of the items.
+func SeenItems(items ...int) SeenSet {
+	s := make(seenItems, len(items))
+	for _, item := range items {
+		s[item] = true
+	}
+
+	return s
+}
+
+// SeenByUser returns a SeenSet of the items in the collection of user, along
+// with the items of session, for instance those the user consumed since the
+// Bird was built. A user out of range only has the items of session.
+func (b *Bird) SeenByUser(user int, session ...int) SeenSet {
+	var items []int
+	if user >= 0 && user < len(b.UsersToItems) {
+		items = b.UsersToItems[user]
+	}
+	s := make(seenItems, len(items)+len(session))
+	for _, item := range items {
+		s[item] = true
+	}
+	for _, item := range session {
+		s[item] = true
+	}
+
+	return s
+}
+
+// dropSeen removes, in place, the items of ranked that are in seen.
+func dropSeen(ranked []ScoredItem, seen SeenSet) []ScoredItem {
+	kept := ranked[:0]
+	for _, s := range ranked {
+		if !seen.Seen(s.Item) {
+			kept = append(kept, s)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,77 @@
+package birdland
+
+import (
+	"testing"
+)
+
+func TestSeenSets(t *testing.T) {
+	bird, err := NewBird(NewBirdCfg(), []float64{1, 1, 1, 1}, [][]int{[]int{0, 1}, []int{1, 2, 3}})
+	if err != nil {
+		t.Fatalf("SeenSets: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	cases := []struct {
+		Name string
+		Set  SeenSet
+		Seen []int
+	}{
+		{"Bitset", NewSeenBitset([]int{1, 70}), []int{1, 70}},
+		{"Items", SeenItems(1, 70), []int{1, 70}},
+		{"User", bird.SeenByUser(0), []int{0, 1}},
+		{"User and session", bird.SeenByUser(0, 3), []int{0, 1, 3}},
+		{"Unknown user", bird.SeenByUser(7, 2), []int{2}},
+	}
+	for _, ex := range cases {
+		seen := make(map[int]bool)
+		for _, item := range ex.Seen {
+			seen[item] = true
+		}
+		for _, item := range []int{-1, 0, 1, 2, 3, 63, 64, 70, 200} {
+			if ex.Set.Seen(item) != seen[item] {
+				t.Errorf("SeenSets: %s: expected Seen(%d) to be %v", ex.Name, item, seen[item])
+			}
+		}
+	}
+}
+
+func TestBirdRecommendItemsScoredSeen(t *testing.T) {
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 2, 3}}
+	query := []QueryItem{{Item: 0, Weight: 1}}
+	cfg := NewBirdCfg()
+	cfg.Depth = 3
+
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("Seen: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	bird.ReSeed(42)
+	all, err := bird.RecommendItemsScored(query, 4, ScoreOptions{})
+	if err != nil || len(all) != 4 {
+		t.Fatalf("Seen: expected 4 items without error, got %v and %v", all, err)
+	}
+
+	bird.ReSeed(42)
+	unseen, err := bird.RecommendItemsScored(query, 2, ScoreOptions{Seen: SeenItems(all[0].Item)})
+	if err != nil {
+		t.Fatalf("Seen: should not have raised an error but did: %v", err)
+	}
+	if len(unseen) != 2 || unseen[0] != all[1] || unseen[1] != all[2] {
+		t.Errorf("Seen: expected the items after the seen one, %v, got %v", all[1:3], unseen)
+	}
+
+	bird.ReSeed(42)
+	unseen, err = bird.RecommendItemsScored(query, 3, ScoreOptions{Seen: bird.SeenByUser(0)})
+	if err != ErrNotEnoughItems {
+		t.Errorf("Seen: expected ErrNotEnoughItems, got %v", err)
+	}
+	if len(unseen) != 2 {
+		t.Errorf("Seen: expected the 2 items left, got %v", unseen)
+	}
+	for _, s := range unseen {
+		if s.Item < 2 {
+			t.Errorf("Seen: the item %d seen by user 0 was recommended", s.Item)
+		}
+	}
+}
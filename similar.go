@@ -0,0 +1,71 @@
+package birdland
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// SimilarItems returns the n items that are most often visited by random
+// walks starting from item, i.e. the result of processing a query that only
+// contains item. The item itself is not part of the results.
+func (b *Bird) SimilarItems(item int, n int) ([]ScoredItem, error) {
+	if n < 1 {
+		return nil, errors.New("the number of similar items must be greater than or equal to 1")
+	}
+	if item < 0 || item >= len(b.ItemWeights) {
+		return nil, fmt.Errorf("item %d does not belong to the graph", item)
+	}
+	if len(b.itemUsers(item)) == 0 {
+		return nil, fmt.Errorf("no one has interacted with item %d", item)
+	}
+
+	items, _, err := b.Process([]QueryItem{{Item: item, Weight: 1}})
+	if err != nil {
+		return nil, wrapf(err, "cannot process item %d", item)
+	}
+
+	scores := make(map[int]float64)
+	for _, visited := range items {
+		if visited != item {
+			scores[visited]++
+		}
+	}
+
+	return rankItems(scores, n), nil
+}
+
+// CoOccurrence returns, for each item that shares at least one user with
+// item, the number of users they share. Unlike SimilarItems the counts are
+// exact, which makes them useful to validate the random walks on small
+// catalogs. The item itself is not part of the result, and an item that does
+// not belong to the graph has no co-occurrences.
+func (b *Bird) CoOccurrence(item int) map[int]int {
+	counts := make(map[int]int)
+	if item < 0 || item >= len(b.ItemWeights) {
+		return counts
+	}
+
+	// countedFor remembers the last user an item was counted for, so that
+	// items or users that appear twice in a collection are only counted once.
+	countedFor := make(map[int]int)
+	users := make(map[int]bool)
+	for _, user := range b.itemUsers(item) {
+		if users[user] {
+			continue
+		}
+		users[user] = true
+		for _, other := range b.UsersToItems[user] {
+			if other == item {
+				continue
+			}
+			if last, ok := countedFor[other]; ok && last == user {
+				continue
+			}
+			countedFor[other] = user
+			counts[other]++
+		}
+	}
+
+	return counts
+}
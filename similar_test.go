@@ -0,0 +1,76 @@
+package birdland
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBirdSimilarItems(t *testing.T) {
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{
+		[]int{0, 1},
+		[]int{0, 1},
+		[]int{0, 1, 2},
+		[]int{3},
+	}
+	cfg := NewBirdCfg()
+	cfg.Draws = 1000
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("SimilarItems: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	similar, err := bird.SimilarItems(0, 10)
+	if err != nil {
+		t.Fatalf("SimilarItems: should not have raised an error but did: %v", err)
+	}
+	if len(similar) != 2 {
+		t.Fatalf("SimilarItems: expected 2 similar items, got %v", similar)
+	}
+	if similar[0].Item != 1 || similar[1].Item != 2 {
+		t.Errorf("SimilarItems: expected items [1 2] in this order, got %v", similar)
+	}
+	if similar[0].Score < similar[1].Score {
+		t.Errorf("SimilarItems: items are not sorted by descending score: %v", similar)
+	}
+
+	similar, err = bird.SimilarItems(0, 1)
+	if err != nil || len(similar) != 1 {
+		t.Errorf("SimilarItems: expected exactly 1 similar item, got %v (error: %v)", similar, err)
+	}
+
+	for _, item := range []int{-1, 4} {
+		if _, err := bird.SimilarItems(item, 1); err == nil {
+			t.Errorf("SimilarItems: item %d is out of range but no error was raised", item)
+		}
+	}
+	if _, err := bird.SimilarItems(0, 0); err == nil {
+		t.Errorf("SimilarItems: asking for 0 items should have raised an error but did not")
+	}
+}
+
+func TestBirdCoOccurrence(t *testing.T) {
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{
+		[]int{0, 1},
+		[]int{0, 1, 1},
+		[]int{0, 2},
+		[]int{3},
+	}
+	bird, err := NewBird(NewBirdCfg(), itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("CoOccurrence: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	counts := bird.CoOccurrence(0)
+	expected := map[int]int{1: 2, 2: 1}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("CoOccurrence: expected %v, got %v", expected, counts)
+	}
+	if counts := bird.CoOccurrence(3); len(counts) != 0 {
+		t.Errorf("CoOccurrence: item 3 shares no user, got %v", counts)
+	}
+	if counts := bird.CoOccurrence(4); len(counts) != 0 {
+		t.Errorf("CoOccurrence: item 4 does not exist, got %v", counts)
+	}
+}
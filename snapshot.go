@@ -0,0 +1,53 @@
+package birdland
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// SaveSnapshot writes the Bird to the file at path in the format of
+// SaveMapped, which holds everything needed to serve recommendations and is
+// versioned. The file is written next to path under a temporary name and
+// renamed once complete, so that a process reloading the snapshot never sees
+// a partial file, and a Bird that still maps the previous snapshot keeps
+// working until it is closed.
+func (b *Bird) SaveSnapshot(path string) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "cannot create snapshot")
+	}
+	tmp := f.Name()
+
+	// TempFile creates the file readable by its owner only.
+	err = f.Chmod(0644)
+	if err == nil {
+		err = b.SaveMapped(f)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return errors.Wrapf(err, "cannot write snapshot %s", path)
+	}
+
+	return nil
+}
+
+// OpenSnapshot opens a snapshot written by SaveSnapshot. The adjacency lists
+// and the samplers' tables are memory-mapped as with OpenMapped, so loading
+// a snapshot takes the same time whatever the size of the graph, and Close
+// must be called once the Bird is no longer used. Snapshots written with
+// another version of the format are rejected.
+func OpenSnapshot(path string) (*Bird, error) {
+	return OpenMapped(path)
+}
@@ -0,0 +1,64 @@
+package birdland
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBirdSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "birdland")
+	if err != nil {
+		t.Fatalf("Snapshot: cannot create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bird.snapshot")
+
+	bird := newPersistTestBird(t)
+	if err := bird.SaveSnapshot(path); err != nil {
+		t.Fatalf("Snapshot: SaveSnapshot should not have raised an error but did: %v", err)
+	}
+	opened, err := OpenSnapshot(path)
+	if err != nil {
+		t.Fatalf("Snapshot: OpenSnapshot should not have raised an error but did: %v", err)
+	}
+	defer opened.Close()
+
+	// Overwriting the snapshot must not disturb the Bird that maps it.
+	if err := bird.AddInteraction(0, 1); err != nil {
+		t.Fatalf("Snapshot: AddInteraction should not have raised an error but did: %v", err)
+	}
+	if err := bird.SaveSnapshot(path); err != nil {
+		t.Fatalf("Snapshot: SaveSnapshot should not have raised an error but did: %v", err)
+	}
+	if !reflect.DeepEqual(opened.ItemWeights, bird.ItemWeights) {
+		t.Errorf("Snapshot: expected item weights %v, got %v", bird.ItemWeights, opened.ItemWeights)
+	}
+	reopened, err := OpenSnapshot(path)
+	if err != nil {
+		t.Fatalf("Snapshot: OpenSnapshot should not have raised an error but did: %v", err)
+	}
+	defer reopened.Close()
+	if len(reopened.UsersToItems[0]) != len(opened.UsersToItems[0])+1 {
+		t.Errorf("Snapshot: expected the new snapshot to hold the new interaction")
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil || len(files) != 1 {
+		t.Errorf("Snapshot: expected the directory to only hold the snapshot, got %d files (error: %v)", len(files), err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Snapshot: cannot read the snapshot: %v", err)
+	}
+	data[len(mappedMagic)] = mappedFormatVersion + 1
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Snapshot: cannot write the snapshot: %v", err)
+	}
+	if _, err := OpenSnapshot(path); err == nil {
+		t.Errorf("Snapshot: OpenSnapshot should have rejected another version of the format")
+	}
+}
@@ -0,0 +1,69 @@
+package birdland
+
+import (
+	"github.com/pkg/errors"
+)
+
+// stabilityTopK is the number of recommended items compared by Stability.
+const stabilityTopK = 10
+
+// Stability estimates how reproducible the recommendations for query are. It
+// processes the query runs times, each time with a new seed drawn from
+// RandSource, and returns the average Jaccard index between the top 10 items
+// recommended by RecommendItems in every pair of runs. A value close to 1
+// means that the recommendations barely depend on the random walks; a low
+// value suggests increasing Cfg.Draws.
+func (b *Bird) Stability(query []QueryItem, runs int) (float64, error) {
+	if runs < 2 {
+		return 0, errors.New("the number of runs must be greater than or equal to 2")
+	}
+
+	workers := b.Cfg.Parallelism
+	if workers < 1 {
+		workers = 1
+	}
+
+	tops := make([]map[int]bool, runs)
+	for r := range tops {
+		items, referrers, err := b.ProcessSeeded(query, b.drawSeed(), workers)
+		if err != nil {
+			return 0, wrapf(err, "cannot process run %d", r)
+		}
+		recommended := RecommendItems(items, referrers)
+		if len(recommended) > stabilityTopK {
+			recommended = recommended[:stabilityTopK]
+		}
+		tops[r] = make(map[int]bool, len(recommended))
+		for _, item := range recommended {
+			tops[r][item] = true
+		}
+	}
+
+	var total float64
+	var pairs int
+	for i := range tops {
+		for j := i + 1; j < runs; j++ {
+			total += jaccard(tops[i], tops[j])
+			pairs++
+		}
+	}
+
+	return total / float64(pairs), nil
+}
+
+// jaccard returns the size of the intersection of two sets divided by the
+// size of their union. Two empty sets are identical.
+func jaccard(a, b map[int]bool) float64 {
+	var intersection int
+	for item := range a {
+		if b[item] {
+			intersection++
+		}
+	}
+	union := len(a) + len(b) - intersection
+	if union == 0 {
+		return 1
+	}
+
+	return float64(intersection) / float64(union)
+}
@@ -0,0 +1,39 @@
+package birdland
+
+import "testing"
+
+func TestBirdStability(t *testing.T) {
+	itemWeights := []float64{1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{0, 1}, []int{2}}
+	bird, err := NewBird(NewBirdCfg(), itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("Stability: Bird initialization should not have raised an error but did: %v", err)
+	}
+	query := []QueryItem{{Item: 0, Weight: 1}}
+
+	stability, err := bird.Stability(query, 5)
+	if err != nil {
+		t.Fatalf("Stability: should not have raised an error but did: %v", err)
+	}
+	if stability != 1 {
+		t.Errorf("Stability: walks from item 0 can only reach items 0 and 1, expected a stability of 1, got %v", stability)
+	}
+
+	if _, err := bird.Stability(query, 1); err == nil {
+		t.Errorf("Stability: a single run should have raised an error but did not")
+	}
+	if _, err := bird.Stability(nil, 3); err == nil {
+		t.Errorf("Stability: an empty query should have raised an error but did not")
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	a := map[int]bool{1: true, 2: true, 3: true}
+	b := map[int]bool{2: true, 3: true, 4: true}
+	if j := jaccard(a, b); j != 0.5 {
+		t.Errorf("Jaccard: expected 0.5, got %v", j)
+	}
+	if j := jaccard(map[int]bool{}, map[int]bool{}); j != 1 {
+		t.Errorf("Jaccard: two empty sets should have an index of 1, got %v", j)
+	}
+}
@@ -0,0 +1,187 @@
+package birdland
+
+import (
+	"math"
+	"sort"
+
+	"github.com/rlouf/birdland/sampler"
+)
+
+// Distribution summarizes a distribution of integer values with a few
+// percentiles rather than a full histogram.
+type Distribution struct {
+	Min int
+	P50 int
+	P90 int
+	P99 int
+	Max int
+}
+
+// GraphStats describes the shape of the user-item bipartite graph. It is
+// useful to get a feel for the data before tuning Depth and Draws.
+type GraphStats struct {
+	NumUsers    int
+	NumItems    int
+	NumEdges    int          // number of user-item interactions
+	Density     float64      // fraction of the user-item matrix that is filled
+	UserDegrees Distribution // number of items in each user's collection
+	ItemDegrees Distribution // number of users who interacted with each item
+	OrphanItems int          // items no one has interacted with
+	EmptyUsers  int          // users with an empty collection
+}
+
+// Stats returns statistics about the user-item graph. They are computed on
+// the first call and cached afterwards.
+func (b *Bird) Stats() GraphStats {
+	c := b.caches
+	c.statsOnce.Do(func() {
+		c.stats = computeGraphStats(b.UsersToItems, b.allItemsToUsers())
+	})
+
+	return c.stats
+}
+
+// ItemDegrees returns the number of users who interacted with each item. The
+// slice is the caller's to modify.
+func (b *Bird) ItemDegrees() []int {
+	degrees := make([]int, len(b.ItemWeights))
+	if b.lazyItemsToUsers != nil {
+		// Counting the interactions spares building the lists of users.
+		for _, userItems := range b.UsersToItems {
+			for _, item := range userItems {
+				degrees[item]++
+			}
+		}
+		return degrees
+	}
+
+	for i := range degrees {
+		degrees[i] = len(b.itemUsers(i))
+	}
+
+	return degrees
+}
+
+// UserDegrees returns the number of items in each user's collection. The
+// slice is the caller's to modify.
+func (b *Bird) UserDegrees() []int {
+	degrees := make([]int, len(b.UsersToItems))
+	for u, userItems := range b.UsersToItems {
+		degrees[u] = len(userItems)
+	}
+
+	return degrees
+}
+
+// UserDistribution returns the topK items most likely to be drawn from the
+// collection of user, in decreasing order of probability, along with their
+// probabilities, as encoded by the user's sampler. It is meant to debug
+// unexpected recommendations. Items the user interacted with several times
+// appear once per interaction. A user out of range or with an empty
+// collection gets nil slices, and a negative topK is treated as 0.
+func (b *Bird) UserDistribution(user int, topK int) ([]int, []float64) {
+	if user < 0 || user >= len(b.UsersToItems) || len(b.UsersToItems[user]) == 0 {
+		return nil, nil
+	}
+
+	var probabilities []float64
+	switch f := b.fenwickSampler(user); {
+	case b.Cfg.UniformUserSampling:
+		probabilities = make([]float64, len(b.UsersToItems[user]))
+		for i := range probabilities {
+			probabilities[i] = 1 / float64(len(probabilities))
+		}
+	case b.customSamplers != nil:
+		// Samplers built by a factory cannot be inspected; they encode the
+		// weights they were built from.
+		var edgeWeights []float64
+		if b.EdgeWeights != nil {
+			edgeWeights = b.EdgeWeights[user]
+		}
+		probabilities = normalizedWeights(userSamplingWeights(b.ItemWeights, b.UsersToItems[user], edgeWeights))
+	case f != nil:
+		probabilities = normalizedWeights(f.Weights())
+	default:
+		probabilities = b.userItemsSamplers[user].Probabilities()
+	}
+
+	indices, top := sampler.TopProbabilities(probabilities, topK)
+	items := make([]int, len(indices))
+	for i, j := range indices {
+		items[i] = b.UsersToItems[user][j]
+	}
+
+	return items, top
+}
+
+// normalizedWeights divides the weights by their sum, in place.
+func normalizedWeights(weights []float64) []float64 {
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+	for i := range weights {
+		weights[i] /= sum
+	}
+
+	return weights
+}
+
+// computeGraphStats computes the statistics of the graph from its two
+// complementary adjacency lists.
+func computeGraphStats(usersToItems, itemsToUsers [][]int) GraphStats {
+	stats := GraphStats{
+		NumUsers: len(usersToItems),
+		NumItems: len(itemsToUsers),
+	}
+
+	userDegrees := make([]int, len(usersToItems))
+	for u, userItems := range usersToItems {
+		userDegrees[u] = len(userItems)
+		stats.NumEdges += len(userItems)
+		if len(userItems) == 0 {
+			stats.EmptyUsers++
+		}
+	}
+
+	itemDegrees := make([]int, len(itemsToUsers))
+	for i, itemUsers := range itemsToUsers {
+		itemDegrees[i] = len(itemUsers)
+		if len(itemUsers) == 0 {
+			stats.OrphanItems++
+		}
+	}
+
+	if stats.NumUsers > 0 && stats.NumItems > 0 {
+		stats.Density = float64(stats.NumEdges) / (float64(stats.NumUsers) * float64(stats.NumItems))
+	}
+	stats.UserDegrees = summarize(userDegrees)
+	stats.ItemDegrees = summarize(itemDegrees)
+
+	return stats
+}
+
+// summarize sorts the values in place and returns their distribution. The
+// percentiles are computed with the nearest-rank method.
+func summarize(values []int) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sort.Ints(values)
+
+	percentile := func(p float64) int {
+		rank := int(math.Ceil(p / 100 * float64(len(values))))
+		if rank < 1 {
+			rank = 1
+		}
+		return values[rank-1]
+	}
+
+	return Distribution{
+		Min: values[0],
+		P50: percentile(50),
+		P90: percentile(90),
+		P99: percentile(99),
+		Max: values[len(values)-1],
+	}
+}
@@ -0,0 +1,125 @@
+package birdland
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+type SummarizeCase struct {
+	Name     string
+	Values   []int
+	Expected Distribution
+}
+
+var summarizeTable = []SummarizeCase{
+	{
+		Name:     "Empty slice",
+		Values:   []int{},
+		Expected: Distribution{},
+	},
+	{
+		Name:     "Single value",
+		Values:   []int{3},
+		Expected: Distribution{Min: 3, P50: 3, P90: 3, P99: 3, Max: 3},
+	},
+	{
+		Name:     "Unsorted values",
+		Values:   []int{10, 1, 9, 2, 8, 3, 7, 4, 6, 5},
+		Expected: Distribution{Min: 1, P50: 5, P90: 9, P99: 10, Max: 10},
+	},
+}
+
+func TestSummarize(t *testing.T) {
+	for _, ex := range summarizeTable {
+		d := summarize(ex.Values)
+		if d != ex.Expected {
+			t.Errorf("Summarize: %s: expected %+v, got %+v", ex.Name, ex.Expected, d)
+		}
+	}
+}
+
+func TestBirdStats(t *testing.T) {
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{}, []int{1, 2}, []int{1}}
+
+	bird, err := NewBird(NewBirdCfg(), itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("Stats: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	stats := bird.Stats()
+	expected := GraphStats{
+		NumUsers:    4,
+		NumItems:    4,
+		NumEdges:    5,
+		Density:     5. / 16.,
+		UserDegrees: Distribution{Min: 0, P50: 1, P90: 2, P99: 2, Max: 2},
+		ItemDegrees: Distribution{Min: 0, P50: 1, P90: 3, P99: 3, Max: 3},
+		OrphanItems: 1,
+		EmptyUsers:  1,
+	}
+	if stats != expected {
+		t.Errorf("Stats: expected %+v, got %+v", expected, stats)
+	}
+
+	if bird.Stats() != stats {
+		t.Errorf("Stats: the cached statistics differ from the first computation")
+	}
+}
+
+func TestBirdDegrees(t *testing.T) {
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{}, []int{1, 2}, []int{1}}
+
+	for _, lazy := range []bool{false, true} {
+		cfg := NewBirdCfg()
+		cfg.LazyItemsToUsers = lazy
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("Degrees: Bird initialization should not have raised an error but did: %v", err)
+		}
+
+		itemDegrees := bird.ItemDegrees()
+		if expected := []int{1, 3, 1, 0}; !reflect.DeepEqual(itemDegrees, expected) {
+			t.Errorf("Degrees: lazy=%v: expected item degrees %v, got %v", lazy, expected, itemDegrees)
+		}
+		userDegrees := bird.UserDegrees()
+		if expected := []int{2, 0, 2, 1}; !reflect.DeepEqual(userDegrees, expected) {
+			t.Errorf("Degrees: lazy=%v: expected user degrees %v, got %v", lazy, expected, userDegrees)
+		}
+
+		itemDegrees[1] = 0
+		if bird.ItemDegrees()[1] != 3 {
+			t.Errorf("Degrees: lazy=%v: modifying the returned degrees should not modify the Bird", lazy)
+		}
+	}
+}
+
+func TestBirdUserDistribution(t *testing.T) {
+	itemWeights := []float64{1, 2, 3, 4}
+	usersToItems := [][]int{[]int{3, 0, 2}, []int{}}
+
+	bird, err := NewBird(NewBirdCfg(), itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("UserDistribution: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	items, probabilities := bird.UserDistribution(0, 2)
+	if !reflect.DeepEqual(items, []int{3, 2}) {
+		t.Errorf("UserDistribution: expected items [3 2], got %v", items)
+	}
+	if len(probabilities) != 2 || math.Abs(probabilities[0]-0.5) > 1e-12 || math.Abs(probabilities[1]-0.375) > 1e-12 {
+		t.Errorf("UserDistribution: expected probabilities [0.5 0.375], got %v", probabilities)
+	}
+
+	if items, _ := bird.UserDistribution(1, 2); items != nil {
+		t.Errorf("UserDistribution: expected no items for an empty collection, got %v", items)
+	}
+	if items, _ := bird.UserDistribution(2, 2); items != nil {
+		t.Errorf("UserDistribution: expected no items for a user out of range, got %v", items)
+	}
+	if items, _ := bird.UserDistribution(0, -1); len(items) != 0 {
+		t.Errorf("UserDistribution: expected no items for a negative topK, got %v", items)
+	}
+}
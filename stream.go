@@ -0,0 +1,158 @@
+package birdland
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// defaultStreamTopN is the number of items returned for a query read by
+// ProcessReader that does not set "n".
+const defaultStreamTopN = 10
+
+// streamQuery is a line read by ProcessReader:
+//
+//	{"id": "user-42", "query": [{"item": 3, "weight": 2.0}, {"item": 7, "weight": 1.0}], "n": 20}
+//
+// "id" is optional and copied as is to the result, "query" holds the query
+// items, with the fields of QueryItem, and "n" is the number of items to
+// return, defaultStreamTopN if it is missing.
+type streamQuery struct {
+	ID    json.RawMessage `json:"id,omitempty"`
+	Query []QueryItem     `json:"query"`
+	N     int             `json:"n"`
+}
+
+// streamResult is a line written by ProcessReader:
+//
+//	{"line": 1, "id": "user-42", "items": [{"item": 5, "score": 412}, ...]}
+//	{"line": 2, "error": "cannot process the query: empty query"}
+//
+// "line" is the number of the line the query was read from, starting at 1,
+// and "items" the recommended items in descending order of their number of
+// visits.
+type streamResult struct {
+	Line  int             `json:"line"`
+	ID    json.RawMessage `json:"id,omitempty"`
+	Items []streamItem    `json:"items,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+type streamItem struct {
+	Item  int     `json:"item"`
+	Score float64 `json:"score"`
+}
+
+// ProcessReader reads newline-delimited JSON queries from r, in the format of
+// streamQuery, and writes a newline-delimited JSON result for each of them
+// to w, in the format of streamResult and in the order of the queries. Empty
+// lines are skipped.
+//
+// At most Cfg.Parallelism queries, or one if it is not set, are processed at
+// once, each with ProcessSeeded and a seed drawn from RandSource in the
+// order of the queries, so that the results only depend on the state of
+// RandSource. A query that cannot be read or processed gets a result with an
+// error and the next ones are processed; an error is only returned if r
+// cannot be read or w written to. The Bird must not be modified meanwhile.
+// Identical queries share their query sampler when Cfg.QuerySamplerCache is
+// set.
+func (b *Bird) ProcessReader(r io.Reader, w io.Writer) error {
+	workers := b.Cfg.Parallelism
+	if workers < 1 {
+		workers = 1
+	}
+	cache := newQuerySamplerCache(b.Cfg.QuerySamplerCache)
+
+	// The results are written in the order in which their channels are
+	// queued, whatever the order in which the queries are processed.
+	pending := make(chan chan streamResult, workers)
+	slots := make(chan struct{}, workers)
+	done := make(chan struct{})
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(pending)
+		br := bufio.NewReader(r)
+		for line := 1; ; line++ {
+			data, err := br.ReadBytes('\n')
+			if len(bytes.TrimSpace(data)) > 0 {
+				res := make(chan streamResult, 1)
+				select {
+				case slots <- struct{}{}:
+				case <-done:
+					readErr <- nil
+					return
+				}
+				pending <- res
+				go func(line int, data []byte, seed int64) {
+					res <- b.processStreamLine(line, data, seed, cache)
+					<-slots
+				}(line, data, b.drawSeed())
+			}
+			if err == io.EOF {
+				readErr <- nil
+				return
+			}
+			if err != nil {
+				readErr <- errors.Wrapf(err, "cannot read line %d", line)
+				return
+			}
+		}
+	}()
+
+	enc := json.NewEncoder(w)
+	var writeErr error
+	for res := range pending {
+		result := <-res
+		if writeErr != nil {
+			continue
+		}
+		if err := enc.Encode(result); err != nil {
+			writeErr = errors.Wrapf(err, "cannot write the result of line %d", result.Line)
+			close(done)
+		}
+	}
+
+	if err := <-readErr; err != nil {
+		return err
+	}
+
+	return writeErr
+}
+
+// processStreamLine processes the query read from line of ProcessReader,
+// taking its starts from cache.
+func (b *Bird) processStreamLine(line int, data []byte, seed int64, cache *querySamplerCache) streamResult {
+	var q streamQuery
+	if err := json.Unmarshal(data, &q); err != nil {
+		return streamResult{Line: line, Error: fmt.Sprintf("invalid query: %v", err)}
+	}
+	result := streamResult{Line: line, ID: q.ID}
+
+	n := q.N
+	if n == 0 {
+		n = defaultStreamTopN
+	}
+	if n < 0 {
+		result.Error = fmt.Sprintf("invalid number of items %d", n)
+		return result
+	}
+
+	items, _, err := b.processSeeded(q.Query, seed, 1, cache)
+	if err != nil {
+		result.Error = errors.Wrap(err, "cannot process the query").Error()
+		return result
+	}
+	var counter Counter
+	counter.Add(items)
+	ids, scores := counter.TopN(n)
+	result.Items = make([]streamItem, len(ids))
+	for i, item := range ids {
+		result.Items[i] = streamItem{Item: item, Score: scores[i]}
+	}
+
+	return result
+}
@@ -0,0 +1,157 @@
+package birdland
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestBirdProcessReader(t *testing.T) {
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 2, 3}}
+	input := strings.Join([]string{
+		`{"id": "first", "query": [{"item": 0, "weight": 1}], "n": 2}`,
+		`not json`,
+		``,
+		`{"id": 4, "query": [{"item": 7, "weight": 1}]}`,
+		`{"query": [{"item": 3, "weight": 1}, {"item": 1, "weight": 2}]}`,
+		`{"query": [{"item": 3, "weight": 1}, {"item": 1, "weight": 2}]}`,
+	}, "\n")
+
+	// The last run shares the query sampler of the repeated query.
+	var outputs []string
+	for r, parallelism := range []int{1, 4, 4} {
+		cfg := NewBirdCfg()
+		cfg.Depth = 2
+		cfg.Parallelism = parallelism
+		if r == 2 {
+			cfg.QuerySamplerCache = 1
+		}
+		bird, err := NewBird(cfg, itemWeights, usersToItems)
+		if err != nil {
+			t.Fatalf("ProcessReader: Bird initialization should not have raised an error but did: %v", err)
+		}
+		bird.ReSeed(42)
+
+		var w bytes.Buffer
+		if err := bird.ProcessReader(strings.NewReader(input), &w); err != nil {
+			t.Fatalf("ProcessReader: %d: should not have raised an error but did: %v", parallelism, err)
+		}
+		outputs = append(outputs, w.String())
+
+		var results []streamResult
+		dec := json.NewDecoder(&w)
+		for dec.More() {
+			var r streamResult
+			if err := dec.Decode(&r); err != nil {
+				t.Fatalf("ProcessReader: %d: cannot decode the results: %v", parallelism, err)
+			}
+			results = append(results, r)
+		}
+		if len(results) != 5 {
+			t.Fatalf("ProcessReader: %d: expected 5 results, got %d", parallelism, len(results))
+		}
+
+		for i, line := range []int{1, 2, 4, 5, 6} {
+			if results[i].Line != line {
+				t.Errorf("ProcessReader: %d: expected result %d to be that of line %d, got %d", parallelism, i, line, results[i].Line)
+			}
+		}
+		if string(results[0].ID) != `"first"` || len(results[0].Items) != 2 || results[0].Error != "" {
+			t.Errorf("ProcessReader: %d: expected 2 items for the first query, got %+v", parallelism, results[0])
+		}
+		if results[1].Error == "" || results[2].Error == "" || string(results[2].ID) != "4" {
+			t.Errorf("ProcessReader: %d: expected the invalid queries to be reported, got %+v and %+v", parallelism, results[1], results[2])
+		}
+		if len(results[3].Items) != 4 {
+			t.Errorf("ProcessReader: %d: expected 4 items for the last query, got %+v", parallelism, results[3])
+		}
+	}
+	if outputs[0] != outputs[1] {
+		t.Errorf("ProcessReader: the results should not depend on the parallelism")
+	}
+	if outputs[1] != outputs[2] {
+		t.Errorf("ProcessReader: the results should not depend on the query sampler cache")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestBirdProcessReaderWriteError(t *testing.T) {
+	bird, err := NewBird(NewBirdCfg(), []float64{1, 1}, [][]int{[]int{0, 1}})
+	if err != nil {
+		t.Fatalf("ProcessReader: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	input := strings.Repeat(`{"query": [{"item": 0, "weight": 1}]}`+"\n", 100)
+	if err := bird.ProcessReader(strings.NewReader(input), failingWriter{}); err == nil {
+		t.Errorf("ProcessReader: a failing writer should have raised an error")
+	}
+}
+
+// benchmarkBirdProcessReader processes a batch of 100 queries of querySize
+// items drawn from numDistinct distinct queries, so that the queries are
+// repeated in the batch when numDistinct is below 100, as in re-ranking. The
+// repeated queries share their query sampler when cacheSize is positive.
+func benchmarkBirdProcessReader(querySize, numDistinct, cacheSize int, b *testing.B) {
+	numItems, numUsers := 100000, 10000
+	usersToItems := make([][]int, numUsers)
+	for i := 0; i < numUsers; i++ {
+		num := 1 + rand.Intn(100) // +1 so that num != 0
+		items := make([]int, num)
+		for j := 0; j < num; j++ {
+			items[j] = rand.Intn(numItems)
+		}
+		usersToItems[i] = items
+	}
+
+	itemWeights := make([]float64, numItems)
+	for i := 0; i < numItems; i++ {
+		itemWeights[i] = 10 * rand.Float64()
+	}
+
+	cfg := NewBirdCfg()
+	cfg.Depth = 2
+	cfg.QuerySamplerCache = cacheSize
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		b.Fatalf("BenchmarkBirdProcessReader: Bird initialization raised an error: %v", err)
+	}
+
+	lines := make([][]byte, numDistinct)
+	for k := range lines {
+		query := make([]QueryItem, querySize)
+		for i := range query {
+			query[i] = QueryItem{Item: rand.Intn(numItems), Weight: rand.Float64()}
+		}
+		lines[k], _ = json.Marshal(streamQuery{Query: query})
+	}
+	var batch bytes.Buffer
+	for l := 0; l < 100; l++ {
+		batch.Write(lines[l%numDistinct])
+		batch.WriteByte('\n')
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = bird.ProcessReader(bytes.NewReader(batch.Bytes()), ioutil.Discard)
+	}
+}
+
+func BenchmarkBirdProcessReader50Query10Distinct(b *testing.B) {
+	benchmarkBirdProcessReader(50, 10, 0, b)
+}
+
+func BenchmarkBirdProcessReader50Query10DistinctCached(b *testing.B) {
+	benchmarkBirdProcessReader(50, 10, 10, b)
+}
+
+func BenchmarkBirdProcessReader50Query100Distinct(b *testing.B) {
+	benchmarkBirdProcessReader(50, 100, 0, b)
+}
@@ -0,0 +1,156 @@
+package birdland
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/rlouf/birdland/sampler"
+)
+
+// Binary format of the sampler tables saved by SaveSamplers, with the same
+// conventions as the format of Save:
+//
+//	magic     "BIRS"
+//	version   1 byte
+//	samplers  number of users, then for each user the probability table and
+//	          the alias table, each preceded by its length
+//	checksum  CRC-32 (IEEE) of everything above, 4 bytes
+var samplersMagic = []byte("BIRS")
+
+const samplersFormatVersion byte = 1
+
+// SamplersRebuiltError is returned by NewBirdWithSamplers, along with a Bird
+// that is ready to use, when the saved sampler tables could not be used and
+// the samplers were rebuilt from the weights instead. It should be treated as
+// a warning.
+type SamplersRebuiltError struct {
+	Err error // why the saved tables were rejected
+}
+
+func (e *SamplersRebuiltError) Error() string {
+	return "the samplers were rebuilt: " + e.Err.Error()
+}
+
+// SaveSamplers writes the probability and alias tables of the Bird's samplers
+// to w. Together with the graph saved in any other format, for instance with
+// SaveJSON, they let NewBirdWithSamplers skip the construction of the
+// samplers, which dominates the cost of NewBird on large graphs.
+func (b *Bird) SaveSamplers(w io.Writer) error {
+	samplers, err := b.aliasSamplers()
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	crc := crc32.NewIEEE()
+	enc := &encoder{w: io.MultiWriter(bw, crc)}
+
+	enc.writeBytes(samplersMagic)
+	enc.writeBytes([]byte{samplersFormatVersion})
+	enc.writeInt(len(samplers))
+	for _, s := range samplers {
+		enc.writeFloats(s.ProbabilityTable)
+		enc.writeInts(s.AliasTable)
+	}
+	if enc.err != nil {
+		return errors.Wrap(enc.err, "cannot write samplers")
+	}
+
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc.Sum32())
+	if _, err := bw.Write(sum[:]); err != nil {
+		return errors.Wrap(err, "cannot write checksum")
+	}
+
+	return bw.Flush()
+}
+
+// NewBirdWithSamplers creates a recommender like NewBirdWithEdgeWeights, but
+// restores the samplers from tables written by SaveSamplers instead of
+// building them. The tables must match the graph: one pair per user, as long
+// as the user's collection.
+//
+// If the tables cannot be read or do not match the graph, the samplers are
+// rebuilt and the Bird is returned along with a *SamplersRebuiltError. Any
+// other error means the graph itself is invalid and no Bird is returned.
+func NewBirdWithSamplers(cfg *BirdCfg, itemWeights []float64, usersToItems [][]int,
+	edgeWeights [][]float64, samplers io.Reader) (*Bird, error) {
+
+	tables, err := readSamplers(samplers)
+	if err == nil {
+		err = checkSamplers(usersToItems, tables)
+	}
+	if err != nil {
+		b, buildErr := NewBirdWithEdgeWeights(cfg, itemWeights, usersToItems, edgeWeights)
+		if buildErr != nil {
+			return nil, buildErr
+		}
+		return b, &SamplersRebuiltError{Err: err}
+	}
+
+	return restoreBird(cfg, itemWeights, usersToItems, edgeWeights, tables)
+}
+
+// readSamplers reads tables written by SaveSamplers.
+func readSamplers(r io.Reader) ([]sampler.AliasSampler, error) {
+	crc := crc32.NewIEEE()
+	dec := &decoder{r: io.TeeReader(bufio.NewReader(r), crc)}
+
+	header := dec.readBytes(len(samplersMagic) + 1)
+	if dec.err != nil {
+		return nil, errors.Wrap(dec.err, "cannot read header")
+	}
+	if !bytes.Equal(header[:len(samplersMagic)], samplersMagic) {
+		return nil, errors.New("not a samplers file")
+	}
+	if header[len(samplersMagic)] != samplersFormatVersion {
+		return nil, errors.Errorf("unsupported format version %d", header[len(samplersMagic)])
+	}
+
+	numUsers := dec.readLength()
+	tables := make([]sampler.AliasSampler, 0, minInt(numUsers, chunkSize))
+	for u := 0; u < numUsers && dec.err == nil; u++ {
+		var s sampler.AliasSampler
+		s.ProbabilityTable = dec.readFloats()
+		s.AliasTable = dec.readInts()
+		tables = append(tables, s)
+	}
+	if dec.err != nil {
+		return nil, errors.Wrap(dec.err, "cannot read samplers")
+	}
+
+	expected := crc.Sum32()
+	sum := dec.readBytes(4)
+	if dec.err != nil {
+		return nil, errors.Wrap(dec.err, "cannot read checksum")
+	}
+	if binary.LittleEndian.Uint32(sum) != expected {
+		return nil, errors.New("checksum mismatch, the file is corrupted")
+	}
+
+	return tables, nil
+}
+
+// checkSamplers checks that there is one sampler per user, with tables as
+// long as the user's collection and aliases within its range.
+func checkSamplers(usersToItems [][]int, tables []sampler.AliasSampler) error {
+	if len(tables) != len(usersToItems) {
+		return errors.Errorf("there are %d samplers for %d users", len(tables), len(usersToItems))
+	}
+	for u, userItems := range usersToItems {
+		if len(tables[u].ProbabilityTable) != len(userItems) || len(tables[u].AliasTable) != len(userItems) {
+			return errors.Errorf("the sampler of user %d does not match their collection", u)
+		}
+		for _, alias := range tables[u].AliasTable {
+			if alias < 0 || alias >= len(userItems) {
+				return errors.Errorf("the alias table of user %d is out of range", u)
+			}
+		}
+	}
+
+	return nil
+}
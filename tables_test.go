@@ -0,0 +1,103 @@
+package birdland
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestBirdSamplersRoundTrip(t *testing.T) {
+	bird := newPersistTestBird(t)
+
+	var buf bytes.Buffer
+	if err := bird.SaveSamplers(&buf); err != nil {
+		t.Fatalf("Samplers: SaveSamplers should not have raised an error but did: %v", err)
+	}
+
+	loaded, err := NewBirdWithSamplers(bird.Cfg, bird.ItemWeights, bird.UsersToItems, nil, &buf)
+	if err != nil {
+		t.Fatalf("Samplers: NewBirdWithSamplers should not have raised an error but did: %v", err)
+	}
+	for u := range bird.userItemsSamplers {
+		expected, got := bird.userItemsSamplers[u], loaded.userItemsSamplers[u]
+		if !reflect.DeepEqual(expected.ProbabilityTable, got.ProbabilityTable) ||
+			!reflect.DeepEqual(expected.AliasTable, got.AliasTable) {
+			t.Errorf("Samplers: the sampler of user %d was not restored", u)
+		}
+	}
+}
+
+func TestBirdSamplersMismatch(t *testing.T) {
+	bird := newPersistTestBird(t)
+
+	var buf bytes.Buffer
+	if err := bird.SaveSamplers(&buf); err != nil {
+		t.Fatalf("Samplers: SaveSamplers should not have raised an error but did: %v", err)
+	}
+	saved := buf.Bytes()
+
+	// The first user now has one item more than their saved sampler.
+	usersToItems := [][]int{[]int{0, 1, 2}, []int{}, []int{1, 2, 3}, []int{3}}
+	inputs := map[string][]byte{
+		"Mismatched graph":    saved,
+		"Truncated tables":    saved[:len(saved)-10],
+		"Not a samplers file": []byte("BIRD"),
+	}
+	for name, data := range inputs {
+		graph := bird.UsersToItems
+		if name == "Mismatched graph" {
+			graph = usersToItems
+		}
+		loaded, err := NewBirdWithSamplers(bird.Cfg, bird.ItemWeights, graph, nil, bytes.NewReader(data))
+		if _, ok := err.(*SamplersRebuiltError); !ok {
+			t.Errorf("Samplers: %s: expected a SamplersRebuiltError, got %v", name, err)
+			continue
+		}
+		if _, _, err := loaded.Process([]QueryItem{{Item: 1, Weight: 1}}); err != nil {
+			t.Errorf("Samplers: %s: Process on the rebuilt Bird should not have raised an error but did: %v", name, err)
+		}
+	}
+
+	_, err := NewBirdWithSamplers(bird.Cfg, []float64{1}, bird.UsersToItems, nil, bytes.NewReader(saved))
+	if err == nil {
+		t.Errorf("Samplers: an invalid graph should have raised an error but did not")
+	}
+	if _, ok := err.(*SamplersRebuiltError); ok {
+		t.Errorf("Samplers: an invalid graph should not be reported as rebuilt samplers")
+	}
+}
+
+func TestBirdSamplersInvalidInput(t *testing.T) {
+	bird := newPersistTestBird(t)
+
+	var buf bytes.Buffer
+	if err := bird.SaveSamplers(&buf); err != nil {
+		t.Fatalf("Samplers: SaveSamplers should not have raised an error but did: %v", err)
+	}
+	saved := buf.Bytes()
+
+	for name, change := range map[string]func(*BirdCfg){
+		"Negative parallelism": func(cfg *BirdCfg) { cfg.Parallelism = -3 },
+		"Unknown depth mode":   func(cfg *BirdCfg) { cfg.DepthMode = DepthMode(42) },
+	} {
+		cfg := *bird.Cfg
+		change(&cfg)
+		loaded, err := NewBirdWithSamplers(&cfg, bird.ItemWeights, bird.UsersToItems, nil, bytes.NewReader(saved))
+		if _, ok := err.(*InvalidInputError); !ok || loaded != nil {
+			t.Errorf("Samplers: %s: expected a nil Bird and an *InvalidInputError, got %v", name, err)
+		}
+	}
+
+	cfg := *bird.Cfg
+	cfg.LazyItemsToUsers = true
+	loaded, err := NewBirdWithSamplers(&cfg, bird.ItemWeights, bird.UsersToItems, nil, bytes.NewReader(saved))
+	if err != nil {
+		t.Fatalf("Samplers: NewBirdWithSamplers should not have raised an error but did: %v", err)
+	}
+	if loaded.ItemsToUsers != nil {
+		t.Errorf("Samplers: expected the users of the items to be built lazily")
+	}
+	if _, _, err := loaded.Process([]QueryItem{{Item: 1, Weight: 1}}); err != nil {
+		t.Errorf("Samplers: Process on the lazy Bird should not have raised an error but did: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package birdland
+
+import "github.com/rlouf/birdland/sampler"
+
+// walker performs the random walks of Process and of the methods that
+// aggregate them differently. The walks advance in lockstep: at each step,
+// every walk moves from its item to one of the users who interacted with it,
+// its referrer, then to branching items of the referrer's collection, from
+// which it continues. Walks that reach a dead end are handled according to
+// Cfg.Dangling, and their length is chosen according to Cfg.DepthMode. The
+// buffers of a walker are reused from one run to the next.
+type walker struct {
+	b         *Bird
+	rng       sampler.Rand  // source of all the draws of the walks
+	restart   *querySampler // sampler the walks restart from with DanglingRestart, nil if there is none
+	branching int           // number of items each walk continues from at each step
+	distinct  bool          // whether the items a walk continues from are distinct
+
+	items, next, referrers []int
+}
+
+// newWalker returns a walker without branching that draws from rng.
+func (b *Bird) newWalker(rng sampler.Rand, restart *querySampler) *walker {
+	return &walker{b: b, rng: rng, restart: restart, branching: 1}
+}
+
+// run performs at most depth steps of the walks that start from the items of
+// starts, which it does not modify, and calls visit after step d, starting
+// at 0, with the items the walks visited and their referrers. Each walk of a
+// step continues into branching walks of the next one, which follow each
+// other, so that there are len(starts) times branching^(d+1) walks at step d;
+// the walks that were dropped or that have ended hold deadEnd. visit must not
+// keep the slices, and returns false to stop the walks, which also stop once
+// they have all been dropped.
+func (w *walker) run(starts []int, depth int, visit func(d int, items, referrers []int) bool) error {
+	items := starts
+	for d := 0; d < depth; d++ {
+		if d > 0 && w.endWalks(d, items) == 0 {
+			break
+		}
+		n := len(items) * w.branching
+		w.next = resize(w.next, n)
+		w.referrers = resize(w.referrers, n)
+		if err := w.step(items, w.next, w.referrers); err != nil {
+			return err
+		}
+		if !visit(d, w.next, w.referrers) {
+			break
+		}
+		w.items, w.next = w.next, w.items
+		items = w.items
+	}
+
+	return nil
+}
+
+// endWalks ends the walks of items that do not perform step d, drawing from
+// rng with DepthGeometric, and returns the number of walks that do.
+func (w *walker) endWalks(d int, items []int) int {
+	live := 0
+	for i, item := range items {
+		if item == deadEnd {
+			continue
+		}
+		if !w.b.continueWalk(d, w.rng) {
+			items[i] = deadEnd
+			continue
+		}
+		live++
+	}
+
+	return live
+}
+
+// step moves every walk of items one step further and writes the items the
+// walks visit and their referrers in newItems and referrers, which must be
+// branching times as long as items. The walks that reach a dead end are
+// handled according to Cfg.Dangling; the visits of dropped walks are set to
+// deadEnd.
+func (w *walker) step(items, newItems, referrers []int) error {
+	b, k := w.b, w.branching
+
+	// The referrer of walk i is kept in referrers[i*k] until the items it
+	// leads to are drawn.
+	b.loadItemUsers(items)
+	for i, item := range items {
+		if item == deadEnd {
+			referrers[i*k] = deadEnd
+			continue
+		}
+		relatedUsers := b.itemUsers(item)
+		if len(relatedUsers) == 0 {
+			if b.Cfg.Dangling == DanglingFail {
+				return wrap(&DeadEndError{Item: item, User: -1}, "cannot perform step")
+			}
+			referrers[i*k] = deadEnd
+			continue
+		}
+		referrers[i*k] = b.sampleReferrer(item, relatedUsers, w.rng)
+	}
+
+	// The visits are drawn in the order of the walks. Drawing them in the
+	// order of the referrers, so that walks through the same user read its
+	// sampler one after the other, did not make a measurable difference on
+	// BenchmarkBirdProcess100KDraws3Depth10MEdges: the referrers of a step
+	// rarely repeat and each user's tables live in their own allocation.
+	for i, item := range items {
+		branches, branchReferrers := newItems[i*k:(i+1)*k], referrers[i*k:(i+1)*k]
+		user := branchReferrers[0]
+		for j := range branches {
+			branches[j], branchReferrers[j] = deadEnd, deadEnd
+		}
+		if item == deadEnd {
+			continue
+		}
+		if user != deadEnd {
+			n, err := w.sampleBranches(branches, user, item)
+			if err == nil {
+				for j := 0; j < n; j++ {
+					branchReferrers[j] = user
+				}
+				continue
+			}
+			if b.Cfg.Dangling == DanglingFail {
+				return wrap(&DeadEndError{Item: item, User: user}, "cannot perform step")
+			}
+		}
+
+		b.metrics().IncDeadEnd()
+		if b.Cfg.Dangling == DanglingRestart {
+			if next, user, err := b.restartWalk(w.restart, w.rng); err == nil {
+				branches[0], branchReferrers[0] = next, user
+			}
+		}
+	}
+
+	return nil
+}
+
+// sampleBranches draws from the collection of user the items that the walk
+// coming from item continues from, writes them at the start of branches and
+// returns their number. Fewer than branching items are drawn when they must
+// be distinct and the collection does not have enough of them.
+func (w *walker) sampleBranches(branches []int, user, item int) (int, error) {
+	if w.branching == 1 {
+		next, err := w.b.sampleNextItem(user, item, w.rng)
+		if err != nil {
+			return 0, err
+		}
+		branches[0] = next
+		return 1, nil
+	}
+
+	drawn, err := w.b.sampleItems(branches[:0:len(branches)], user, w.branching, w.distinct, w.rng)
+	if err != nil {
+		for j := range branches {
+			branches[j] = deadEnd
+		}
+		return 0, err
+	}
+
+	return len(drawn), nil
+}
+
+// resize returns a slice of length n, reusing the array of s if it is large
+// enough.
+func resize(s []int, n int) []int {
+	if cap(s) < n {
+		return make([]int, n)
+	}
+
+	return s[:n]
+}
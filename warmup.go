@@ -0,0 +1,68 @@
+package birdland
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Warmup pays at once the costs that would otherwise fall on the first
+// requests served by the Bird. It reads the table of every user's sampler,
+// which checks that they match the collections and, for a Bird opened with
+// OpenMapped, brings them into memory; it builds the users of every item when
+// Cfg.LazyItemsToUsers is set, which gives up the memory lazy mode saves; and
+// it computes the Stats.
+//
+// Warmup does not modify the graph and can be called several times, including
+// concurrently with Process or with another Warmup.
+func (b *Bird) Warmup() error {
+	if len(b.userItemsSamplers) != len(b.UsersToItems) {
+		return errors.Errorf("expected %d samplers, got %d", len(b.UsersToItems), len(b.userItemsSamplers))
+	}
+
+	if b.customSamplers != nil && len(b.customSamplers) != len(b.UsersToItems) {
+		return errors.Errorf("expected %d samplers, got %d", len(b.UsersToItems), len(b.customSamplers))
+	}
+
+	for u, userItems := range b.UsersToItems {
+		if b.Cfg.UniformUserSampling {
+			continue
+		}
+		if b.customSamplers != nil {
+			if len(userItems) > 0 && b.customSamplers[u] == nil {
+				return errors.Errorf("user %d has no sampler", u)
+			}
+			continue
+		}
+		if f := b.fenwickSampler(u); f != nil {
+			if f.Len() != len(userItems) {
+				return errors.Errorf("the sampler of user %d does not match their collection", u)
+			}
+			continue
+		}
+		s := &b.userItemsSamplers[u]
+		if len(s.ProbabilityTable) != len(userItems) || len(s.AliasTable) != len(userItems) {
+			return errors.Errorf("the sampler of user %d does not match their collection", u)
+		}
+		for _, alias := range s.AliasTable {
+			if alias < 0 || alias >= len(userItems) {
+				return errors.Errorf("the alias table of user %d is out of range", u)
+			}
+		}
+		for _, item := range userItems {
+			if item < 0 || item >= len(b.ItemWeights) {
+				return errors.Errorf("user %d refers to item %d which does not belong to the graph", u, item)
+			}
+		}
+	}
+
+	if b.lazyItemsToUsers != nil {
+		items := make([]int, len(b.ItemWeights))
+		for i := range items {
+			items[i] = i
+		}
+		b.loadItemUsers(items)
+	}
+
+	b.Stats()
+
+	return nil
+}
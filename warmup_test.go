@@ -0,0 +1,47 @@
+package birdland
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestBirdWarmup(t *testing.T) {
+	itemWeights := []float64{1, 2, 3, 4}
+	usersToItems := [][]int{[]int{0, 1, 2}, []int{1, 3}, []int{}, []int{0, 2, 3}}
+	cfg := NewBirdCfg()
+	cfg.LazyItemsToUsers = true
+	bird, err := NewBird(cfg, itemWeights, usersToItems)
+	if err != nil {
+		t.Fatalf("Warmup: Bird initialization should not have raised an error but did: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := bird.Warmup(); err != nil {
+				t.Errorf("Warmup: should not have raised an error but did: %v", err)
+			}
+			if _, _, err := bird.ProcessSeeded([]QueryItem{{Item: i, Weight: 1}}, int64(i), 2); err != nil {
+				t.Errorf("Warmup: ProcessSeeded should not have raised an error but did: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for item := range itemWeights {
+		if _, ok := bird.lazyItemsToUsers.get(item); !ok {
+			t.Errorf("Warmup: the users of item %d should have been built", item)
+		}
+	}
+
+	bird.userItemsSamplers[1].AliasTable = []int{0, 2}
+	if err := bird.Warmup(); err == nil {
+		t.Errorf("Warmup: an out of range alias table should have raised an error but did not")
+	}
+	bird.userItemsSamplers = bird.userItemsSamplers[:2]
+	if err := bird.Warmup(); err == nil {
+		t.Errorf("Warmup: missing samplers should have raised an error but did not")
+	}
+}
@@ -40,12 +40,12 @@ func NewWeaver(cfg *WeaverCfg, itemWeights []float64, usersToItems [][]int,
 
 	err := validateWeaverInputs(itemWeights, usersToItems, socialGraph)
 	if err != nil {
-		return &Weaver{}, errors.Wrap(err, "invalid input")
+		return &Weaver{}, wrap(err, "invalid input")
 	}
 
 	bird, err := NewBird(cfg.BirdCfg, itemWeights, usersToItems)
 	if err != nil {
-		return &Weaver{}, errors.Wrap(err, "couldn't create new bird")
+		return &Weaver{}, wrap(err, "couldn't create new bird")
 	}
 
 	b := Weaver{
@@ -66,19 +66,25 @@ func (b *Weaver) Process(query []QueryItem, user int) ([]int, []int, error) {
 
 	stepItems, err := b.sampleItemsFromQuery(query)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "cannot sample items from the query")
-	}
-
-	var items []int
-	var referrers []int
-	for d := 0; d < b.Cfg.Depth; d++ {
-		stepItems, stepReferrers, err := b.step(stepItems, user)
+		return nil, nil, wrap(err, "cannot sample items from the query")
+	}
+
+	// The items visited at each step are written in place, as in
+	// Bird.Process, and the samplers of the users related to an item are
+	// built once for the whole walk since they only depend on the user.
+	draws, depth := len(stepItems), b.walkDepth(len(stepItems))
+	items := make([]int, draws*depth)
+	referrers := make([]int, draws*depth)
+	samplers := make(map[int]*sampler.AliasSampler)
+	for d := 0; d < depth; d++ {
+		newItems := items[d*draws : (d+1)*draws]
+		err = b.stepInto(stepItems, newItems, referrers[d*draws:(d+1)*draws], user, samplers)
 		if err != nil {
-			return nil, nil, errors.Wrap(err, "cannot step through items")
+			return nil, nil, wrap(err, "cannot step through items")
 		}
-		items = append(items, stepItems...)
-		referrers = append(referrers, stepReferrers...)
+		stepItems = newItems
 	}
+	capVisits(b.Cfg.MaxVisits, &items, &referrers)
 
 	return items, referrers, nil
 }
@@ -87,24 +93,36 @@ func (b *Weaver) Process(query []QueryItem, user int) ([]int, []int, error) {
 // it returns a slice of visited items along with the 'referrers', i.e. the
 // users that were visited to reach these items.
 func (b *Weaver) step(items []int, user int) ([]int, []int, error) {
+	newItems := make([]int, len(items))
+	referrers := make([]int, len(items))
+	err := b.stepInto(items, newItems, referrers, user, make(map[int]*sampler.AliasSampler))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newItems, referrers, nil
+}
 
+// stepInto is like step but writes the visited items and the referrers in
+// newItems and referrers, which must be as long as items. The samplers of
+// the users related to each item, weighted for user, are looked up in and
+// added to samplers.
+func (b *Weaver) stepInto(items, newItems, referrers []int, user int, samplers map[int]*sampler.AliasSampler) error {
 	if user >= len(b.SocialGraph) {
-		return nil, nil, fmt.Errorf("user %d does not belong to the social graph", user)
+		return fmt.Errorf("user %d does not belong to the social graph", user)
 	}
 
-	referrers := make([]int, len(items))
-	itemUserSamplers := make(map[int]*sampler.AliasSampler)
-
+	b.loadItemUsers(items)
 	for i, item := range items {
-		relatedUsers := b.ItemsToUsers[item]
+		relatedUsers := b.itemUsers(item)
 
 		if len(relatedUsers) == 0 {
-			return nil, nil, errors.New("the item refers to an item no one has interacted with")
+			return errors.New("the item refers to an item no one has interacted with")
 		}
 
 		// for each item, create a sampler of related users weighted by socialCoef
 		// (with default weight value 1)
-		if _, ok := itemUserSamplers[item]; !ok {
+		if _, ok := samplers[item]; !ok {
 			weightedRelatedUsers := make([]float64, len(relatedUsers))
 			for j, u := range relatedUsers {
 				if w, ok := b.SocialGraph[user][u]; ok {
@@ -114,20 +132,23 @@ func (b *Weaver) step(items []int, user int) ([]int, []int, error) {
 				}
 			}
 			itemUserSampler, err := sampler.NewAliasSampler(b.RandSource, weightedRelatedUsers)
-			itemUserSamplers[item] = itemUserSampler
 			if err != nil {
-				return nil, nil, errors.Wrapf(err, "could not initialize users' sampler for user %d and item %d", user, item)
+				return errors.Wrapf(err, "could not initialize users' sampler for user %d and item %d", user, item)
 			}
+			samplers[item] = itemUserSampler
 		}
-		referrers[i] = relatedUsers[itemUserSamplers[item].Sample(1)[0]]
+		referrers[i] = relatedUsers[samplers[item].Sample1()]
 	}
 
-	newItems := make([]int, len(items))
 	for j, user := range referrers {
-		newItems[j] = b.sampleItem(user)
+		item, err := b.sampleItem(user)
+		if err != nil {
+			return errors.Wrap(err, "cannot perform step")
+		}
+		newItems[j] = item
 	}
 
-	return newItems, referrers, nil
+	return nil
 }
 
 // validateWeaverInput checks the validity of the data fed to Weaver.  It returns
@@ -135,6 +135,32 @@ func TestWeaverInitialization(t *testing.T) {
 	}
 }
 
+func TestWeaverChainedSteps(t *testing.T) {
+	// Users link the items into a chain, so that item 2 can only be reached
+	// from item 0 in two steps.
+	itemWeights := []float64{1, 1, 1, 1}
+	usersToItems := [][]int{[]int{0, 1}, []int{1, 2}, []int{2, 3}}
+	socialGraph := []map[int]float64{{1: 1., 2: 1.}, {0: 1., 2: 1.}, {0: 1., 1: 1.}}
+	cfg := NewWeaverCfg()
+	cfg.Depth = 2
+	cfg.Draws = 100
+
+	weaver, err := NewWeaver(cfg, itemWeights, usersToItems, socialGraph)
+	if err != nil {
+		t.Fatalf("ChainedSteps: Weaver initialization should not have raised an error but did: %v", err)
+	}
+	items, _, err := weaver.Process([]QueryItem{{Item: 0, Weight: 1}}, 0)
+	if err != nil {
+		t.Fatalf("ChainedSteps: Process should not have raised an error but did: %v", err)
+	}
+	for _, item := range items[cfg.Draws:] {
+		if item == 2 {
+			return
+		}
+	}
+	t.Errorf("ChainedSteps: expected the second step to start from the items of the first one and reach item 2")
+}
+
 func benchmarkWeaverStep(querySize, numUsers, numItems int, b *testing.B) {
 	usersToItems := make([][]int, numUsers)
 	for i := 0; i < numUsers; i++ {
@@ -354,3 +380,20 @@ func BenchmarkWeaverProcess5Depth(b *testing.B) {
 func BenchmarkWeaverProcess10Depth(b *testing.B) {
 	benchmarkWeaverProcess(2000000, 1000000, 100, 10000, 10, b)
 }
+
+// Small graphs whose allocations are dominated by the walks rather than by
+// the samplers, to compare allocs/op as the depth grows.
+func BenchmarkWeaverProcessAllocs1Depth(b *testing.B) {
+	b.ReportAllocs()
+	benchmarkWeaverProcess(10000, 1000, 10, 1000, 1, b)
+}
+
+func BenchmarkWeaverProcessAllocs5Depth(b *testing.B) {
+	b.ReportAllocs()
+	benchmarkWeaverProcess(10000, 1000, 10, 1000, 5, b)
+}
+
+func BenchmarkWeaverProcessAllocs10Depth(b *testing.B) {
+	b.ReportAllocs()
+	benchmarkWeaverProcess(10000, 1000, 10, 1000, 10, b)
+}
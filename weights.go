@@ -0,0 +1,55 @@
+package birdland
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ClipWeights returns a copy of weights where the weights below the lowerPct
+// percentile are raised to it and those above the upperPct percentile are
+// lowered to it. Clipping the item weights, for instance at the 1st and 99th
+// percentiles, flattens their distribution and keeps a handful of very
+// popular items from being drawn from every collection.
+//
+// Percentiles are interpolated linearly between the closest ranks, so they
+// stay within the range of the weights: a single weight is never changed,
+// and ClipWeights(weights, 0, 100) returns an identical copy. It panics
+// unless 0 <= lowerPct <= upperPct <= 100.
+func ClipWeights(weights []float64, lowerPct, upperPct float64) []float64 {
+	if lowerPct < 0 || upperPct > 100 || lowerPct > upperPct {
+		panic(fmt.Sprintf("invalid percentiles %v and %v", lowerPct, upperPct))
+	}
+
+	clipped := make([]float64, len(weights))
+	copy(clipped, weights)
+	if len(weights) == 0 {
+		return clipped
+	}
+
+	sorted := make([]float64, len(weights))
+	copy(sorted, weights)
+	sort.Float64s(sorted)
+	lower, upper := percentile(sorted, lowerPct), percentile(sorted, upperPct)
+
+	for i, w := range clipped {
+		if w < lower {
+			clipped[i] = lower
+		} else if w > upper {
+			clipped[i] = upper
+		}
+	}
+
+	return clipped
+}
+
+// percentile returns the pct percentile of the sorted values, interpolating
+// between the two closest ranks.
+func percentile(sorted []float64, pct float64) float64 {
+	rank := pct / 100 * float64(len(sorted)-1)
+	lo := int(rank)
+	if lo >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+
+	return sorted[lo] + (rank-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
@@ -0,0 +1,70 @@
+package birdland
+
+import (
+	"reflect"
+	"testing"
+)
+
+var clipWeightsTable = []struct {
+	Name     string
+	Weights  []float64
+	Lower    float64
+	Upper    float64
+	Expected []float64
+}{
+	{
+		Name:     "Empty weights",
+		Weights:  []float64{},
+		Lower:    10,
+		Upper:    90,
+		Expected: []float64{},
+	},
+	{
+		Name:     "Single weight",
+		Weights:  []float64{7},
+		Lower:    10,
+		Upper:    90,
+		Expected: []float64{7},
+	},
+	{
+		Name:     "No clipping",
+		Weights:  []float64{3, 1, 2},
+		Lower:    0,
+		Upper:    100,
+		Expected: []float64{3, 1, 2},
+	},
+	{
+		Name:     "Typical weights",
+		Weights:  []float64{1000, 1, 2, 3, 4, 5, 6, 7, 8, 9, 0},
+		Lower:    10,
+		Upper:    90,
+		Expected: []float64{9, 1, 2, 3, 4, 5, 6, 7, 8, 9, 1},
+	},
+	{
+		Name:     "Interpolated percentiles",
+		Weights:  []float64{0, 10},
+		Lower:    25,
+		Upper:    75,
+		Expected: []float64{2.5, 7.5},
+	},
+}
+
+func TestClipWeights(t *testing.T) {
+	for _, c := range clipWeightsTable {
+		input := append([]float64{}, c.Weights...)
+		clipped := ClipWeights(input, c.Lower, c.Upper)
+		if !reflect.DeepEqual(clipped, c.Expected) {
+			t.Errorf("ClipWeights: %s: expected %v, got %v", c.Name, c.Expected, clipped)
+		}
+		if !reflect.DeepEqual(input, c.Weights) {
+			t.Errorf("ClipWeights: %s: the input was modified", c.Name)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("ClipWeights: inverted percentiles should have panicked but did not")
+		}
+	}()
+	ClipWeights([]float64{1, 2}, 90, 10)
+}